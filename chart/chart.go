@@ -0,0 +1,258 @@
+// Package chart renders time series as line charts with axes and a legend,
+// as SVG or PNG, for places without a browser such as chat messages, tickets
+// and email reports.
+package chart
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+)
+
+// Units of the value axis.
+const (
+	UnitNone    = ""
+	UnitPercent = "percent"
+	UnitBytes   = "bytes"
+)
+
+const (
+	marginLeft   = 64
+	marginRight  = 24
+	marginTop    = 32
+	marginBottom = 24
+	legendRow    = 16
+	legendSwatch = 12
+	// charWidth is the advance of the PNG font, used to lay out text in both
+	// formats.
+	charWidth = 7
+)
+
+// Palette holds the line colors, used in series order.
+var Palette = []color.RGBA{
+	{0x25, 0x63, 0xeb, 0xff},
+	{0xdc, 0x26, 0x26, 0xff},
+	{0x16, 0xa3, 0x4a, 0xff},
+	{0xd9, 0x77, 0x06, 0xff},
+	{0x7c, 0x3a, 0xed, 0xff},
+	{0x08, 0x91, 0xb2, 0xff},
+	{0xdb, 0x27, 0x77, 0xff},
+	{0x4b, 0x55, 0x63, 0xff},
+}
+
+var (
+	colorText = color.RGBA{0x1f, 0x29, 0x33, 0xff}
+	colorMute = color.RGBA{0x6b, 0x77, 0x85, 0xff}
+	colorGrid = color.RGBA{0xe4, 0xe7, 0xeb, 0xff}
+	colorAxis = color.RGBA{0x9a, 0xa5, 0xb1, 0xff}
+)
+
+// Series is one line of a chart.
+type Series struct {
+	Name   string
+	Points []models.Point
+}
+
+// Chart is a line chart of Series between Start and End. Lines are broken
+// where two points are more than two Steps apart.
+type Chart struct {
+	Title  string
+	Unit   string
+	Width  int
+	Height int
+	Start  time.Time
+	End    time.Time
+	Step   time.Duration
+	Series []Series
+}
+
+type tick struct {
+	pos   float64
+	label string
+}
+
+type legendEntry struct {
+	x, y  float64
+	color color.RGBA
+	label string
+}
+
+// layout is what both renderers draw: the plot area, the ticks of both axes,
+// the legend and the lines in pixel coordinates.
+type layout struct {
+	width, height            float64
+	left, right, top, bottom float64
+	xTicks, yTicks           []tick
+	legend                   []legendEntry
+	lines                    [][][][2]float64 // per series, per segment, points
+	empty                    bool
+}
+
+func (c *Chart) layout() layout {
+	l := layout{width: float64(c.Width), height: float64(c.Height)}
+
+	// The legend wraps below the plot.
+	x, rows := float64(marginLeft), 1
+	for i, s := range c.Series {
+		w := float64(legendSwatch + 6 + charWidth*len(s.Name) + 16)
+		if x+w > l.width-marginRight && x > marginLeft {
+			x, rows = marginLeft, rows+1
+		}
+		l.legend = append(l.legend, legendEntry{x: x, y: float64(rows), color: Palette[i%len(Palette)], label: s.Name})
+		x += w
+	}
+	if len(c.Series) == 0 {
+		rows = 0
+	}
+	l.left, l.right, l.top = marginLeft, l.width-marginRight, marginTop
+	l.bottom = l.height - marginBottom - float64(rows*legendRow)
+	for i := range l.legend {
+		l.legend[i].y = l.bottom + marginBottom + (l.legend[i].y-1)*legendRow + legendRow/2
+	}
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, s := range c.Series {
+		for _, p := range s.Points {
+			lo, hi = math.Min(lo, p.Value), math.Max(hi, p.Value)
+		}
+	}
+	if math.IsInf(lo, 1) {
+		l.empty = true
+		lo, hi = 0, 1
+	}
+	lo, hi = math.Min(lo, 0), math.Max(hi, 0)
+	if c.Unit == UnitPercent && hi <= 100 && lo >= 0 {
+		hi = 100
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+	step := niceStep((hi - lo) / 5)
+	lo, hi = math.Floor(lo/step)*step, math.Ceil(hi/step)*step
+
+	start, end := float64(c.Start.UnixNano()), float64(c.End.UnixNano())
+	if end <= start {
+		end = start + 1
+	}
+	px := func(t time.Time) float64 {
+		return l.left + (float64(t.UnixNano())-start)/(end-start)*(l.right-l.left)
+	}
+	py := func(v float64) float64 {
+		return l.bottom - (v-lo)/(hi-lo)*(l.bottom-l.top)
+	}
+
+	for i := 0; lo+float64(i)*step <= hi+step/2; i++ {
+		v := lo + float64(i)*step
+		l.yTicks = append(l.yTicks, tick{pos: py(v), label: FormatValue(c.Unit, v)})
+	}
+	span := c.End.Sub(c.Start)
+	tickStep := timeStep(span)
+	for t := c.Start.Truncate(tickStep); !t.After(c.End); t = t.Add(tickStep) {
+		if t.Before(c.Start) {
+			continue
+		}
+		l.xTicks = append(l.xTicks, tick{pos: px(t), label: formatTime(t, span)})
+	}
+
+	for _, s := range c.Series {
+		var segments [][][2]float64
+		var segment [][2]float64
+		for i, p := range s.Points {
+			if i > 0 && c.Step > 0 && p.Time.Sub(s.Points[i-1].Time) > 2*c.Step && len(segment) > 0 {
+				segments = append(segments, segment)
+				segment = nil
+			}
+			segment = append(segment, [2]float64{px(p.Time), py(p.Value)})
+		}
+		if len(segment) > 0 {
+			segments = append(segments, segment)
+		}
+		l.lines = append(l.lines, segments)
+	}
+	return l
+}
+
+// niceStep rounds a raw axis step up to 1, 2 or 5 times a power of ten.
+func niceStep(raw float64) float64 {
+	if raw <= 0 {
+		return 1
+	}
+	pow := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if raw <= m*pow {
+			return m * pow
+		}
+	}
+	return 10 * pow
+}
+
+// timeStep returns the tick interval giving at most about six ticks.
+func timeStep(span time.Duration) time.Duration {
+	steps := []time.Duration{
+		time.Second, 5 * time.Second, 15 * time.Second, 30 * time.Second,
+		time.Minute, 5 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+		time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour,
+		24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour,
+	}
+	for _, step := range steps {
+		if span/step <= 6 {
+			return step
+		}
+	}
+	return steps[len(steps)-1]
+}
+
+func formatTime(t time.Time, span time.Duration) string {
+	t = t.UTC()
+	switch {
+	case span > 2*24*time.Hour:
+		return t.Format("Jan 2")
+	case span > 24*time.Hour:
+		return t.Format("Jan 2 15:04")
+	case span <= 5*time.Minute:
+		return t.Format("15:04:05")
+	default:
+		return t.Format("15:04")
+	}
+}
+
+// FormatValue formats an axis value of unit compactly.
+func FormatValue(unit string, v float64) string {
+	switch unit {
+	case UnitPercent:
+		return trimFloat(v) + "%"
+	case UnitBytes:
+		units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+		i := 0
+		for math.Abs(v) >= 1024 && i < len(units)-1 {
+			v /= 1024
+			i++
+		}
+		return trimFloat(v) + " " + units[i]
+	}
+	suffixes := []string{"", "k", "M", "G", "T"}
+	i := 0
+	for math.Abs(v) >= 1000 && i < len(suffixes)-1 {
+		v /= 1000
+		i++
+	}
+	return trimFloat(v) + suffixes[i]
+}
+
+func trimFloat(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,105 @@
+package chart
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// PNG writes the chart as a PNG image. Text is drawn in a fixed 7x13 pixel
+// font.
+func (c *Chart) PNG(w io.Writer) error {
+	l := c.layout()
+	img := image.NewRGBA(image.Rect(0, 0, c.Width, c.Height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	if c.Title != "" {
+		drawText(img, c.Title, marginLeft, 20, colorText, alignLeft)
+	}
+	for _, t := range l.yTicks {
+		hLine(img, l.left, l.right, t.pos, colorGrid)
+		drawText(img, t.label, l.left-6, t.pos+4, colorMute, alignRight)
+	}
+	for _, t := range l.xTicks {
+		drawLine(img, t.pos, l.bottom, t.pos, l.bottom+4, colorAxis, 1)
+		drawText(img, t.label, t.pos, l.bottom+16, colorMute, alignCenter)
+	}
+	drawLine(img, l.left, l.top, l.left, l.bottom, colorAxis, 1)
+	hLine(img, l.left, l.right, l.bottom, colorAxis)
+
+	if l.empty {
+		drawText(img, "No data", (l.left+l.right)/2, (l.top+l.bottom)/2, colorMute, alignCenter)
+	}
+	for i, segments := range l.lines {
+		col := Palette[i%len(Palette)]
+		for _, segment := range segments {
+			if len(segment) == 1 {
+				drawLine(img, segment[0][0], segment[0][1], segment[0][0], segment[0][1], col, 2)
+			}
+			for j := 1; j < len(segment); j++ {
+				drawLine(img, segment[j-1][0], segment[j-1][1], segment[j][0], segment[j][1], col, 2)
+			}
+		}
+	}
+
+	for _, e := range l.legend {
+		draw.Draw(img, image.Rect(int(e.x), int(e.y)-1, int(e.x)+legendSwatch, int(e.y)+2), image.NewUniform(e.color), image.Point{}, draw.Src)
+		drawText(img, e.label, e.x+legendSwatch+6, e.y+4, colorText, alignLeft)
+	}
+
+	return png.Encode(w, img)
+}
+
+type align int
+
+const (
+	alignLeft align = iota
+	alignCenter
+	alignRight
+)
+
+// drawText draws s with its baseline at y.
+func drawText(img draw.Image, s string, x, y float64, col color.Color, a align) {
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(col), Face: basicfont.Face7x13}
+	width := float64(d.MeasureString(s).Round())
+	switch a {
+	case alignCenter:
+		x -= width / 2
+	case alignRight:
+		x -= width
+	}
+	d.Dot = fixed.P(int(math.Round(x)), int(math.Round(y)))
+	d.DrawString(s)
+}
+
+func hLine(img *image.RGBA, x0, x1, y float64, col color.RGBA) {
+	yy := int(math.Round(y))
+	for x := int(math.Round(x0)); x <= int(math.Round(x1)); x++ {
+		img.SetRGBA(x, yy, col)
+	}
+}
+
+// drawLine draws a line of the given width in pixels by stamping a square
+// brush along it.
+func drawLine(img *image.RGBA, x0, y0, x1, y1 float64, col color.RGBA, width int) {
+	steps := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))) + 1
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		x := int(math.Round(x0 + (x1-x0)*t))
+		y := int(math.Round(y0 + (y1-y0)*t))
+		for dx := 0; dx < width; dx++ {
+			for dy := 0; dy < width; dy++ {
+				if (image.Point{X: x + dx, Y: y + dy}).In(img.Rect) {
+					img.SetRGBA(x+dx, y+dy, col)
+				}
+			}
+		}
+	}
+}
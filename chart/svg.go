@@ -0,0 +1,59 @@
+package chart
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// SVG writes the chart as an SVG document.
+func (c *Chart) SVG(w io.Writer) error {
+	l := c.layout()
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n", c.Width, c.Height, c.Width, c.Height)
+	fmt.Fprintf(b, `<rect width="100%%" height="100%%" fill="#ffffff"/>`+"\n")
+	if c.Title != "" {
+		fmt.Fprintf(b, `<text x="%d" y="20" font-size="14" font-weight="bold" fill="%s">%s</text>`+"\n", marginLeft, hexColor(colorText), html.EscapeString(c.Title))
+	}
+
+	for _, t := range l.yTicks {
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`+"\n", l.left, t.pos, l.right, t.pos, hexColor(colorGrid))
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="end" fill="%s">%s</text>`+"\n", l.left-6, t.pos+4, hexColor(colorMute), html.EscapeString(t.label))
+	}
+	for _, t := range l.xTicks {
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`+"\n", t.pos, l.bottom, t.pos, l.bottom+4, hexColor(colorAxis))
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="middle" fill="%s">%s</text>`+"\n", t.pos, l.bottom+16, hexColor(colorMute), html.EscapeString(t.label))
+	}
+	fmt.Fprintf(b, `<path d="M%.1f,%.1fV%.1fH%.1f" fill="none" stroke="%s"/>`+"\n", l.left, l.top, l.bottom, l.right, hexColor(colorAxis))
+
+	if l.empty {
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="middle" font-size="14" fill="%s">No data</text>`+"\n", (l.left+l.right)/2, (l.top+l.bottom)/2, hexColor(colorMute))
+	}
+	for i, segments := range l.lines {
+		var d strings.Builder
+		for _, segment := range segments {
+			for j, p := range segment {
+				cmd := "L"
+				if j == 0 {
+					cmd = "M"
+				}
+				fmt.Fprintf(&d, "%s%.1f,%.1f", cmd, p[0], p[1])
+			}
+		}
+		if d.Len() == 0 {
+			continue
+		}
+		fmt.Fprintf(b, `<path d="%s" fill="none" stroke="%s" stroke-width="1.5" stroke-linejoin="round"/>`+"\n", d.String(), hexColor(Palette[i%len(Palette)]))
+	}
+
+	for _, e := range l.legend {
+		fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%d" height="3" fill="%s"/>`+"\n", e.x, e.y-1.5, legendSwatch, hexColor(e.color))
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f" fill="%s">%s</text>`+"\n", e.x+legendSwatch+6, e.y+4, hexColor(colorText), html.EscapeString(e.label))
+	}
+
+	b.WriteString("</svg>\n")
+	return b.Flush()
+}
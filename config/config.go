@@ -3,13 +3,38 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/utils"
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
 )
 
+const (
+	ModeStandalone = "standalone"
+	ModeAgent      = "agent"
+	ModeServer     = "server"
+)
+
+// Sink names accepted in SINKS.
+const (
+	SinkDatabase = "database"
+	SinkFile     = "file"
+	SinkStdout   = "stdout"
+	SinkWebhook  = "webhook"
+)
+
+// Version is reported by agents in their host inventory. Release builds set
+// it with -ldflags "-X github.com/ROHITHSAKTHIVEL/Metrics-Monitor/config.Version=<version>".
+var Version = "dev"
+
+// Cfg is the configuration loaded at startup, for code that has no other way
+// to reach it such as the HTTP handlers.
+var Cfg = &models.Config{}
+
 func LoadConfig() *models.Config {
 	err := godotenv.Load()
 	if err != nil {
@@ -18,14 +43,218 @@ func LoadConfig() *models.Config {
 
 	metricsInterval, _ := strconv.Atoi(os.Getenv("METRICS_INTERVAL_SECONDS"))
 
-	return &models.Config{
+	retentionPeriod := optionalDurationEnv("RETENTION_PERIOD", 0)
+	retentionOverrides, err := utils.ParseDurationMap(os.Getenv("RETENTION_OVERRIDES"))
+	if err != nil {
+		logger.Log.Fatal("Invalid RETENTION_OVERRIDES", zap.Error(err))
+	}
+	retentionBatchSize, _ := strconv.Atoi(os.Getenv("RETENTION_BATCH_SIZE"))
+	if retentionBatchSize <= 0 {
+		retentionBatchSize = 5000
+	}
+
+	rollupMaxPoints, _ := strconv.Atoi(os.Getenv("ROLLUP_MAX_POINTS"))
+	if rollupMaxPoints <= 0 {
+		rollupMaxPoints = 1000
+	}
+
+	timescaleEnabled, _ := strconv.ParseBool(os.Getenv("TIMESCALE_ENABLED"))
+
+	partitionPremake, err := strconv.Atoi(os.Getenv("PARTITION_PREMAKE"))
+	if err != nil || partitionPremake < 0 {
+		partitionPremake = 7
+	}
+
+	mode := os.Getenv("MODE")
+	if mode == "" {
+		mode = ModeStandalone
+	}
+	if mode != ModeStandalone && mode != ModeAgent && mode != ModeServer {
+		logger.Log.Fatal("Invalid MODE, expected standalone, agent or server", zap.String("mode", mode))
+	}
+
+	hostname := os.Getenv("HOST_NAME")
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+
+	var ingestTokens []string
+	for _, token := range strings.Split(os.Getenv("INGEST_TOKENS"), ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			ingestTokens = append(ingestTokens, token)
+		}
+	}
+	if mode == ModeServer && len(ingestTokens) == 0 {
+		logger.Log.Fatal("MODE=server requires INGEST_TOKENS")
+	}
+
+	// The database sink is the default everywhere but in agent mode, which
+	// has no database.
+	sinks := []string{SinkDatabase}
+	if value, ok := os.LookupEnv("SINKS"); ok || mode == ModeAgent {
+		sinks = nil
+		for _, sink := range strings.Split(value, ",") {
+			if sink = strings.TrimSpace(sink); sink == "" {
+				continue
+			}
+			if sink != SinkDatabase && sink != SinkFile && sink != SinkStdout && sink != SinkWebhook {
+				logger.Log.Fatal("Invalid SINKS, expected database, file, stdout or webhook", zap.String("sink", sink))
+			}
+			if sink == SinkDatabase && mode == ModeAgent {
+				logger.Log.Fatal("MODE=agent has no database, remove it from SINKS")
+			}
+			if sink == SinkWebhook && os.Getenv("SINK_WEBHOOK_URL") == "" {
+				logger.Log.Fatal("SINKS=webhook requires SINK_WEBHOOK_URL")
+			}
+			sinks = append(sinks, sink)
+		}
+	}
+	if mode == ModeAgent && os.Getenv("AGENT_SERVER_URL") == "" && os.Getenv("OTLP_ENDPOINT") == "" && os.Getenv("GRAPHITE_OUTPUT_ADDR") == "" && len(sinks) == 0 {
+		logger.Log.Fatal("MODE=agent requires AGENT_SERVER_URL, OTLP_ENDPOINT, GRAPHITE_OUTPUT_ADDR or SINKS")
+	}
+
+	hostLabels, err := utils.ParseLabels(os.Getenv("HOST_LABELS"))
+	if err != nil {
+		logger.Log.Fatal("Invalid HOST_LABELS", zap.Error(err))
+	}
+
+	agentBufferSize, _ := strconv.Atoi(os.Getenv("AGENT_BUFFER_SIZE"))
+	if agentBufferSize <= 0 {
+		agentBufferSize = 10000
+	}
+
+	// /metrics already serves the JSON API.
+	prometheusPath := os.Getenv("PROMETHEUS_PATH")
+	if prometheusPath == "" {
+		prometheusPath = "/prometheus/metrics"
+	}
+	if !strings.HasPrefix(prometheusPath, "/") || strings.TrimSuffix(prometheusPath, "/") == "/metrics" {
+		logger.Log.Fatal("Invalid PROMETHEUS_PATH, expected an absolute path other than /metrics", zap.String("path", prometheusPath))
+	}
+
+	otlpHeaders, err := utils.ParseLabels(os.Getenv("OTLP_HEADERS"))
+	if err != nil {
+		logger.Log.Fatal("Invalid OTLP_HEADERS", zap.Error(err))
+	}
+	otlpBatchSize, _ := strconv.Atoi(os.Getenv("OTLP_BATCH_SIZE"))
+	if otlpBatchSize <= 0 {
+		otlpBatchSize = 1000
+	}
+	otlpMaxRetries, err := strconv.Atoi(os.Getenv("OTLP_MAX_RETRIES"))
+	if err != nil || otlpMaxRetries < 0 {
+		otlpMaxRetries = 3
+	}
+	otlpBufferSize, _ := strconv.Atoi(os.Getenv("OTLP_BUFFER_SIZE"))
+	if otlpBufferSize <= 0 {
+		otlpBufferSize = 10000
+	}
+
+	sinkQueueSize, _ := strconv.Atoi(os.Getenv("SINK_QUEUE_SIZE"))
+	if sinkQueueSize <= 0 {
+		sinkQueueSize = 1000
+	}
+	sinkFilePath := os.Getenv("SINK_FILE_PATH")
+	if sinkFilePath == "" {
+		sinkFilePath = "metrics.ndjson"
+	}
+	sinkFileMaxSizeMB, _ := strconv.Atoi(os.Getenv("SINK_FILE_MAX_SIZE_MB"))
+	if sinkFileMaxSizeMB <= 0 {
+		sinkFileMaxSizeMB = 100
+	}
+	sinkFileMaxBackups, err := strconv.Atoi(os.Getenv("SINK_FILE_MAX_BACKUPS"))
+	if err != nil || sinkFileMaxBackups < 0 {
+		sinkFileMaxBackups = 5
+	}
+	sinkWebhookHeaders, err := utils.ParseLabels(os.Getenv("SINK_WEBHOOK_HEADERS"))
+	if err != nil {
+		logger.Log.Fatal("Invalid SINK_WEBHOOK_HEADERS", zap.Error(err))
+	}
+
+	Cfg = &models.Config{
 		DBHost:          os.Getenv("DB_HOST"),
 		DBUser:          os.Getenv("DB_USER"),
 		DBPass:          os.Getenv("DB_PASS"),
 		DBName:          os.Getenv("DB_NAME"),
 		Port:            os.Getenv("PORT"),
-		DBPort:         os.Getenv("DB_PORT"),
+		DBPort:          os.Getenv("DB_PORT"),
 		MetricsInterval: metricsInterval,
+
+		RetentionPeriod:    retentionPeriod,
+		RetentionOverrides: retentionOverrides,
+		RetentionInterval:  durationEnv("RETENTION_INTERVAL", time.Hour),
+		RetentionBatchSize: retentionBatchSize,
+
+		RollupInterval:  durationEnv("ROLLUP_INTERVAL", time.Minute),
+		RollupMaxPoints: rollupMaxPoints,
+
+		TimescaleEnabled:       timescaleEnabled,
+		TimescaleChunkInterval: durationEnv("TIMESCALE_CHUNK_INTERVAL", 24*time.Hour),
+		TimescaleCompressAfter: optionalDurationEnv("TIMESCALE_COMPRESS_AFTER", 7*24*time.Hour),
+
+		PartitionInterval: os.Getenv("PARTITION_INTERVAL"),
+		PartitionPremake:  partitionPremake,
+
+		Mode:              mode,
+		Hostname:          hostname,
+		AgentServerURL:    strings.TrimSuffix(os.Getenv("AGENT_SERVER_URL"), "/"),
+		AgentToken:        os.Getenv("AGENT_TOKEN"),
+		AgentPushInterval: durationEnv("AGENT_PUSH_INTERVAL", 10*time.Second),
+		AgentBufferSize:   agentBufferSize,
+		IngestTokens:      ingestTokens,
+		HostStaleAfter:    durationEnv("HOST_STALE_AFTER", 2*time.Minute),
+		HostLabels:        hostLabels,
+		PrometheusPath:    prometheusPath,
+
+		OTLPEndpoint:       os.Getenv("OTLP_ENDPOINT"),
+		OTLPHeaders:        otlpHeaders,
+		OTLPExportInterval: durationEnv("OTLP_EXPORT_INTERVAL", 10*time.Second),
+		OTLPBatchSize:      otlpBatchSize,
+		OTLPMaxRetries:     otlpMaxRetries,
+		OTLPBufferSize:     otlpBufferSize,
+
+		StatsDUDPAddr: os.Getenv("STATSD_UDP_ADDR"),
+		StatsDTCPAddr: os.Getenv("STATSD_TCP_ADDR"),
+
+		GraphiteAddr:         os.Getenv("GRAPHITE_ADDR"),
+		GraphiteTemplates:    os.Getenv("GRAPHITE_TEMPLATES"),
+		GraphiteOutputAddr:   os.Getenv("GRAPHITE_OUTPUT_ADDR"),
+		GraphiteOutputPrefix: os.Getenv("GRAPHITE_OUTPUT_PREFIX"),
+
+		Sinks:              sinks,
+		SinkQueueSize:      sinkQueueSize,
+		SinkFilePath:       sinkFilePath,
+		SinkFileMaxSize:    int64(sinkFileMaxSizeMB) << 20,
+		SinkFileMaxBackups: sinkFileMaxBackups,
+		SinkWebhookURL:     os.Getenv("SINK_WEBHOOK_URL"),
+		SinkWebhookHeaders: sinkWebhookHeaders,
+	}
+
+	return Cfg
+
+}
+
+// durationEnv reads a positive duration such as "90s", "12h" or "30d" from
+// the environment, falling back to def when the variable is unset.
+func durationEnv(key string, def time.Duration) time.Duration {
+	d := optionalDurationEnv(key, def)
+	if d == 0 {
+		logger.Log.Fatal("Duration in config must be positive", zap.String("key", key))
 	}
+	return d
+}
 
+// optionalDurationEnv is durationEnv for settings that 0 turns off.
+func optionalDurationEnv(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	d, err := utils.ParseDuration(value)
+	if err != nil {
+		logger.Log.Fatal("Invalid duration in config", zap.String("key", key), zap.Error(err))
+	}
+	if d < 0 {
+		logger.Log.Fatal("Duration in config must not be negative", zap.String("key", key), zap.Duration("value", d))
+	}
+	return d
 }
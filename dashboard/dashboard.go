@@ -0,0 +1,21 @@
+// Package dashboard embeds the single-page web dashboard. It is plain HTML,
+// CSS and JavaScript reading the JSON APIs, so it needs no build step.
+package dashboard
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var static embed.FS
+
+// FS returns the dashboard files, index.html at the root.
+func FS() http.FileSystem {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	return http.FS(files)
+}
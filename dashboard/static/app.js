@@ -0,0 +1,424 @@
+// Metrics Monitor dashboard. Reads the JSON APIs relative to /dashboard/:
+// /hosts for the host selector, the Prometheus API for series names and
+// history, and /metrics/stream for live samples.
+"use strict";
+
+const API = "..";
+const DEFAULT_CHARTS = ["cpu_percent", "mem_percent"];
+const LIVE_WINDOW = 15 * 60; // seconds of history shown in live mode
+const MAX_POINTS = 300; // points per series requested from query_range
+const COLORS = ["#2563eb", "#dc2626", "#16a34a", "#d97706", "#7c3aed", "#0891b2", "#db2777", "#4b5563"];
+
+const $ = (id) => document.getElementById(id);
+
+const state = {
+  host: "",
+  range: "3600",
+  refresh: "30",
+  start: "",
+  end: "",
+  charts: [...DEFAULT_CHARTS],
+};
+
+// chart name -> { el, series: Map(fingerprint -> { labels, points: [[ms, value]], last }) }
+const charts = new Map();
+let refreshTimer = null;
+let stream = null;
+let renderPending = false;
+
+// ---- state in the URL, so a view can be bookmarked and shared ----
+
+function loadState() {
+  const params = new URLSearchParams(location.hash.slice(1));
+  for (const key of ["host", "range", "refresh", "start", "end"]) {
+    if (params.has(key)) state[key] = params.get(key);
+  }
+  if (params.has("charts")) state.charts = params.get("charts").split(",").filter(Boolean);
+}
+
+function saveState() {
+  const params = new URLSearchParams();
+  params.set("host", state.host);
+  params.set("range", state.range);
+  params.set("refresh", state.refresh);
+  if (state.range === "custom") {
+    params.set("start", state.start);
+    params.set("end", state.end);
+  }
+  params.set("charts", state.charts.join(","));
+  history.replaceState(null, "", "#" + params.toString());
+}
+
+// ---- API ----
+
+async function getJSON(path) {
+  const resp = await fetch(API + path, { headers: { Accept: "application/json" } });
+  const body = await resp.json().catch(() => ({}));
+  if (!resp.ok) {
+    throw new Error(body.error || body.Message || body.message || resp.statusText);
+  }
+  return body;
+}
+
+async function loadHosts() {
+  const select = $("host");
+  try {
+    const body = await getJSON("/hosts");
+    for (const host of body.data || []) {
+      const option = new Option(host.hostname + (host.stale ? " (stale)" : ""), host.hostname);
+      select.add(option);
+    }
+  } catch (err) {
+    setStatus("Could not load hosts: " + err.message, true);
+  }
+  select.value = state.host;
+}
+
+async function loadSeriesNames() {
+  const select = $("add-series");
+  try {
+    const body = await getJSON("/api/v1/label/__name__/values");
+    for (const name of body.data || []) {
+      select.add(new Option(name, name));
+    }
+  } catch (err) {
+    setStatus("Could not load series names: " + err.message, true);
+  }
+}
+
+function isCounter(name) {
+  return name.endsWith("_total");
+}
+
+function selector(name) {
+  if (!state.host) return name;
+  return `${name}{host="${state.host.replace(/\\/g, "\\\\").replace(/"/g, '\\"')}"}`;
+}
+
+// Counters are shown as per-second rates.
+function promQuery(name, step) {
+  if (!isCounter(name)) return selector(name);
+  return `rate(${selector(name)}[${Math.max(4 * step, 60)}s])`;
+}
+
+function timeRange() {
+  const now = Date.now() / 1000;
+  if (state.range === "live") return [now - LIVE_WINDOW, now];
+  if (state.range === "custom") {
+    const start = Date.parse(state.start) / 1000;
+    const end = state.end ? Date.parse(state.end) / 1000 : now;
+    if (!isNaN(start) && !isNaN(end) && start < end) return [start, end];
+    return [now - 3600, now];
+  }
+  return [now - Number(state.range), now];
+}
+
+async function loadHistory(name) {
+  const [start, end] = timeRange();
+  const step = Math.max(Math.ceil((end - start) / MAX_POINTS), 10);
+  const params = new URLSearchParams({
+    query: promQuery(name, step),
+    start: start.toFixed(3),
+    end: end.toFixed(3),
+    step: step + "s",
+  });
+  const body = await getJSON("/api/v1/query_range?" + params);
+  const series = new Map();
+  for (const result of body.data.result || []) {
+    const labels = { ...result.metric };
+    delete labels.__name__;
+    series.set(fingerprint(labels), {
+      labels,
+      points: result.values.map(([t, v]) => [t * 1000, parseFloat(v)]),
+    });
+  }
+  return series;
+}
+
+// ---- live samples ----
+
+function startStream() {
+  stopStream();
+  if (state.range !== "live" || state.charts.length === 0) return;
+
+  const params = new URLSearchParams();
+  for (const name of state.charts) params.append("name", name);
+  if (state.host) params.append("host", state.host);
+
+  stream = new EventSource(API + "/metrics/stream?" + params);
+  stream.addEventListener("sample", (event) => addSample(JSON.parse(event.data)));
+  stream.onopen = () => setStatus("Live");
+  stream.onerror = () => setStatus("Live stream disconnected, reconnecting…", true);
+}
+
+function stopStream() {
+  if (stream) {
+    stream.close();
+    stream = null;
+  }
+}
+
+function addSample(sample) {
+  const chart = charts.get(sample.name);
+  if (!chart) return;
+
+  const labels = { ...sample.labels };
+  const key = fingerprint(labels);
+  let series = chart.series.get(key);
+  if (!series) {
+    series = { labels, points: [] };
+    chart.series.set(key, series);
+  }
+
+  const t = Date.parse(sample.ts);
+  let value = sample.value;
+  if (isCounter(sample.name)) {
+    const last = series.last;
+    series.last = [t, value];
+    if (!last || t <= last[0]) return;
+    // A counter that went down was reset and counts from zero again.
+    value = (value >= last[1] ? value - last[1] : value) / ((t - last[0]) / 1000);
+  }
+  series.points.push([t, value]);
+
+  const cutoff = Date.now() - LIVE_WINDOW * 1000;
+  while (series.points.length && series.points[0][0] < cutoff) series.points.shift();
+  scheduleRender();
+}
+
+// ---- charts ----
+
+function fingerprint(labels) {
+  return Object.keys(labels)
+    .sort()
+    .map((key) => `${key}="${labels[key]}"`)
+    .join(",");
+}
+
+function createCharts() {
+  const container = $("charts");
+  container.replaceChildren();
+  charts.clear();
+  for (const name of state.charts) {
+    const el = $("chart-template").content.firstElementChild.cloneNode(true);
+    el.querySelector("h2").textContent = isCounter(name) ? `rate(${name})` : name;
+    el.querySelector(".remove").addEventListener("click", () => removeChart(name));
+    container.append(el);
+    charts.set(name, { el, series: new Map() });
+  }
+  if (state.charts.length === 0) {
+    setStatus("Add a chart to get started.");
+  }
+}
+
+async function refresh() {
+  saveState();
+  const results = await Promise.allSettled(
+    state.charts.map(async (name) => {
+      const series = await loadHistory(name);
+      const chart = charts.get(name);
+      if (!chart) return;
+      if (isCounter(name)) {
+        // Live rates continue from the last raw value seen on the stream.
+        for (const [key, s] of chart.series) {
+          if (series.has(key)) series.get(key).last = s.last;
+        }
+      }
+      chart.series = series;
+    })
+  );
+  const failed = results.find((r) => r.status === "rejected");
+  if (failed) {
+    setStatus("Could not load data: " + failed.reason.message, true);
+  } else if (state.range !== "live") {
+    setStatus("Updated " + new Date().toLocaleTimeString());
+  }
+  scheduleRender();
+}
+
+function scheduleRender() {
+  if (renderPending) return;
+  renderPending = true;
+  requestAnimationFrame(() => {
+    renderPending = false;
+    for (const [name, chart] of charts) renderChart(name, chart);
+  });
+}
+
+function renderChart(name, chart) {
+  const svg = chart.el.querySelector("svg");
+  const width = svg.clientWidth || 600;
+  const height = svg.clientHeight || 220;
+  const pad = { left: 56, right: 8, top: 8, bottom: 20 };
+  svg.setAttribute("viewBox", `0 0 ${width} ${height}`);
+  svg.replaceChildren();
+
+  const [start, end] = timeRange().map((s) => s * 1000);
+  const series = [...chart.series.values()].filter((s) => s.points.length);
+  const legend = chart.el.querySelector(".legend");
+  legend.replaceChildren();
+  chart.el.querySelector(".latest").textContent = "";
+
+  if (series.length === 0) {
+    svg.append(svgEl("text", { x: width / 2, y: height / 2, "text-anchor": "middle", class: "empty" }, "No data"));
+    return;
+  }
+
+  let min = Infinity;
+  let max = -Infinity;
+  for (const s of series) {
+    for (const [, v] of s.points) {
+      if (v < min) min = v;
+      if (v > max) max = v;
+    }
+  }
+  if (min > 0) min = 0;
+  if (name.endsWith("_percent") && max <= 100) max = 100;
+  if (max === min) max = min + 1;
+
+  const x = (t) => pad.left + ((t - start) / (end - start)) * (width - pad.left - pad.right);
+  const y = (v) => pad.top + (1 - (v - min) / (max - min)) * (height - pad.top - pad.bottom);
+
+  for (let i = 0; i <= 4; i++) {
+    const v = min + ((max - min) * i) / 4;
+    svg.append(svgEl("line", { x1: pad.left, x2: width - pad.right, y1: y(v), y2: y(v), class: "grid" }));
+    svg.append(svgEl("text", { x: pad.left - 6, y: y(v) + 4, "text-anchor": "end" }, formatValue(name, v)));
+  }
+  for (const [t, anchor] of [[start, "start"], [(start + end) / 2, "middle"], [end, "end"]]) {
+    svg.append(svgEl("text", { x: x(t), y: height - 4, "text-anchor": anchor }, formatTime(t, end - start)));
+  }
+
+  series.forEach((s, i) => {
+    const color = COLORS[i % COLORS.length];
+    const d = s.points.map(([t, v], j) => `${j ? "L" : "M"}${x(t).toFixed(1)},${y(v).toFixed(1)}`).join("");
+    svg.append(svgEl("path", { d, class: "line", stroke: color }));
+
+    const item = document.createElement("li");
+    item.style.setProperty("--color", color);
+    item.textContent = fingerprint(s.labels) || name;
+    legend.append(item);
+  });
+
+  if (series.length === 1) {
+    const points = series[0].points;
+    chart.el.querySelector(".latest").textContent = formatValue(name, points[points.length - 1][1]);
+  }
+}
+
+function svgEl(tag, attrs, text) {
+  const el = document.createElementNS("http://www.w3.org/2000/svg", tag);
+  for (const [key, value] of Object.entries(attrs)) el.setAttribute(key, value);
+  if (text !== undefined) el.textContent = text;
+  return el;
+}
+
+function formatValue(name, v) {
+  if (name.endsWith("_percent")) return v.toFixed(1) + "%";
+  const suffix = isCounter(name) ? "/s" : "";
+  if (name.includes("_bytes")) return formatBytes(v) + suffix;
+  return Intl.NumberFormat(undefined, { maximumFractionDigits: 2, notation: "compact" }).format(v) + suffix;
+}
+
+function formatBytes(v) {
+  const units = ["B", "KiB", "MiB", "GiB", "TiB"];
+  let i = 0;
+  while (Math.abs(v) >= 1024 && i < units.length - 1) {
+    v /= 1024;
+    i++;
+  }
+  return v.toFixed(i ? 1 : 0) + " " + units[i];
+}
+
+function formatTime(ms, span) {
+  const date = new Date(ms);
+  if (span > 86400 * 1000) {
+    return date.toLocaleDateString(undefined, { month: "short", day: "numeric" }) + " " +
+      date.toLocaleTimeString(undefined, { hour: "2-digit", minute: "2-digit" });
+  }
+  return date.toLocaleTimeString(undefined, { hour: "2-digit", minute: "2-digit" });
+}
+
+function setStatus(text, error = false) {
+  const status = $("status");
+  status.textContent = text;
+  status.classList.toggle("error", error);
+}
+
+// ---- controls ----
+
+function schedule() {
+  clearInterval(refreshTimer);
+  refreshTimer = null;
+  // Custom ranges are fixed, live mode is kept current by the stream.
+  if (state.range !== "live" && state.range !== "custom" && Number(state.refresh) > 0) {
+    refreshTimer = setInterval(refresh, Number(state.refresh) * 1000);
+  }
+}
+
+async function reload() {
+  $("custom-range").hidden = state.range !== "custom";
+  $("refresh").disabled = state.range === "live" || state.range === "custom";
+  stopStream();
+  createCharts();
+  schedule();
+  await refresh();
+  startStream();
+}
+
+function removeChart(name) {
+  state.charts = state.charts.filter((n) => n !== name);
+  reload();
+}
+
+function bindControls() {
+  $("host").addEventListener("change", (e) => {
+    state.host = e.target.value;
+    reload();
+  });
+  $("range").addEventListener("change", (e) => {
+    state.range = e.target.value;
+    if (state.range === "custom" && !state.start) {
+      const toLocal = (d) => new Date(d.getTime() - d.getTimezoneOffset() * 60000).toISOString().slice(0, 16);
+      state.start = toLocal(new Date(Date.now() - 3600 * 1000));
+      state.end = toLocal(new Date());
+      $("start").value = state.start;
+      $("end").value = state.end;
+    }
+    reload();
+  });
+  $("start").addEventListener("change", (e) => {
+    state.start = e.target.value;
+    reload();
+  });
+  $("end").addEventListener("change", (e) => {
+    state.end = e.target.value;
+    reload();
+  });
+  $("refresh").addEventListener("change", (e) => {
+    state.refresh = e.target.value;
+    saveState();
+    schedule();
+  });
+  $("add-series").addEventListener("change", (e) => {
+    const name = e.target.value;
+    e.target.value = "";
+    if (name && !state.charts.includes(name)) {
+      state.charts.push(name);
+      reload();
+    }
+  });
+  window.addEventListener("resize", scheduleRender);
+}
+
+async function main() {
+  loadState();
+  $("range").value = state.range;
+  $("refresh").value = state.refresh;
+  $("start").value = state.start;
+  $("end").value = state.end;
+  bindControls();
+  await Promise.all([loadHosts(), loadSeriesNames()]);
+  await reload();
+}
+
+main();
@@ -16,7 +16,29 @@ import (
 var DB *gorm.DB
 
 func InitDB(cfg *models.Config) {
+	Connect(cfg)
 
+	ctx := context.Background()
+	if err := CheckSchemaVersion(ctx); err != nil {
+		logger.Log.Fatal("Refusing to start", zap.Error(err))
+	}
+	applied, err := MigrateUp(ctx)
+	if err != nil {
+		logger.Log.Fatal("error in migrating DB", zap.Error(err))
+	}
+	logger.Log.Info("Database schema is up to date", zap.Int("applied", applied))
+
+	if cfg.TimescaleEnabled {
+		setupTimescale(cfg)
+	}
+	if !Timescale && cfg.PartitionInterval != "" {
+		setupPartitioning(cfg)
+	}
+}
+
+// Connect opens DB, creating the database first if needed, without touching
+// the schema.
+func Connect(cfg *models.Config) {
 	dbURL := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
 		cfg.DBHost, cfg.DBUser, cfg.DBPass, cfg.DBName, cfg.DBPort)
 
@@ -28,9 +50,6 @@ func InitDB(cfg *models.Config) {
 		return
 	}
 	DB = db
-	DB.AutoMigrate(
-		&models.Metrics{},
-	)
 }
 
 func CeateDbNotExist(dburl string, dbName string) {
@@ -0,0 +1,225 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is one versioned schema change read from
+// migrations/<version>_<name>.(up|down).sql.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState is a migration together with when it was applied, nil
+// while it is pending.
+type MigrationState struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+type schemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// LoadMigrations returns the embedded migrations ordered by version.
+func LoadMigrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		file := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(file, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %q", file)
+		}
+		versionText, name, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(versionText, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q", file)
+		}
+
+		body, err := migrationFiles.ReadFile(path.Join("migrations", file))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up script", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrateUp applies every pending migration, each in its own transaction,
+// and returns how many ran.
+func MigrateUp(ctx context.Context) (int, error) {
+	migrations, applied, err := migrationState(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(m.Up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		logger.Log.Info("Applied migration", zap.Int64("version", m.Version), zap.String("name", m.Name))
+		count++
+	}
+	return count, nil
+}
+
+// MigrateDown reverts the latest steps applied migrations.
+func MigrateDown(ctx context.Context, steps int) (int, error) {
+	migrations, applied, err := migrationState(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == "" {
+			return count, fmt.Errorf("migration %d_%s cannot be reverted", m.Version, m.Name)
+		}
+		err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(m.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{Version: m.Version}).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		logger.Log.Info("Reverted migration", zap.Int64("version", m.Version), zap.String("name", m.Name))
+		count++
+	}
+	return count, nil
+}
+
+// MigrationStatus lists every known migration and whether it was applied.
+// Versions recorded in the database but unknown to this binary are included
+// with an empty name.
+func MigrationStatus(ctx context.Context) ([]MigrationState, error) {
+	migrations, applied, err := migrationState(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var states []MigrationState
+	for _, m := range migrations {
+		state := MigrationState{Version: m.Version, Name: m.Name}
+		if row, ok := applied[m.Version]; ok {
+			state.AppliedAt = &row.AppliedAt
+			delete(applied, m.Version)
+		}
+		states = append(states, state)
+	}
+	for _, row := range applied {
+		states = append(states, MigrationState{Version: row.Version, Name: row.Name, AppliedAt: &row.AppliedAt})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+	return states, nil
+}
+
+// CheckSchemaVersion fails when the database was migrated by a newer binary,
+// since this one would not know how to read it.
+func CheckSchemaVersion(ctx context.Context) error {
+	migrations, applied, err := migrationState(ctx)
+	if err != nil {
+		return err
+	}
+
+	var latest int64
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("database schema version %d is newer than the latest version %d known to this binary", version, latest)
+		}
+	}
+	return nil
+}
+
+func migrationState(ctx context.Context) ([]Migration, map[int64]schemaMigration, error) {
+	if DB == nil {
+		return nil, nil, gorm.ErrInvalidDB
+	}
+
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := DB.WithContext(ctx).Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    bigint PRIMARY KEY,
+		name       text NOT NULL,
+		applied_at timestamptz NOT NULL
+	)`).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var rows []schemaMigration
+	if err := DB.WithContext(ctx).Order("version").Find(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	applied := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return migrations, applied, nil
+}
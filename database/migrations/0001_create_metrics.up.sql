@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS metrics (
+    id          uuid PRIMARY KEY,
+    cpu_percent double precision NOT NULL,
+    mem_percent double precision NOT NULL,
+    created_at  timestamptz
+);
@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS metrics_1m (
+    bucket       timestamptz PRIMARY KEY,
+    cpu_min      double precision NOT NULL,
+    cpu_max      double precision NOT NULL,
+    cpu_avg      double precision NOT NULL,
+    cpu_last     double precision NOT NULL,
+    mem_min      double precision NOT NULL,
+    mem_max      double precision NOT NULL,
+    mem_avg      double precision NOT NULL,
+    mem_last     double precision NOT NULL,
+    sample_count bigint NOT NULL,
+    last_at      timestamptz NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS metrics_1h (
+    bucket       timestamptz PRIMARY KEY,
+    cpu_min      double precision NOT NULL,
+    cpu_max      double precision NOT NULL,
+    cpu_avg      double precision NOT NULL,
+    cpu_last     double precision NOT NULL,
+    mem_min      double precision NOT NULL,
+    mem_max      double precision NOT NULL,
+    mem_avg      double precision NOT NULL,
+    mem_last     double precision NOT NULL,
+    sample_count bigint NOT NULL,
+    last_at      timestamptz NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS metrics_1d (
+    bucket       timestamptz PRIMARY KEY,
+    cpu_min      double precision NOT NULL,
+    cpu_max      double precision NOT NULL,
+    cpu_avg      double precision NOT NULL,
+    cpu_last     double precision NOT NULL,
+    mem_min      double precision NOT NULL,
+    mem_max      double precision NOT NULL,
+    mem_avg      double precision NOT NULL,
+    mem_last     double precision NOT NULL,
+    sample_count bigint NOT NULL,
+    last_at      timestamptz NOT NULL
+);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_metrics_created_at;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_metrics_created_at ON metrics (created_at);
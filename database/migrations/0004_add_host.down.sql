@@ -0,0 +1,17 @@
+ALTER TABLE metrics_1d DROP CONSTRAINT IF EXISTS metrics_1d_pkey;
+DELETE FROM metrics_1d WHERE host <> '';
+ALTER TABLE metrics_1d DROP COLUMN IF EXISTS host;
+ALTER TABLE metrics_1d ADD PRIMARY KEY (bucket);
+
+ALTER TABLE metrics_1h DROP CONSTRAINT IF EXISTS metrics_1h_pkey;
+DELETE FROM metrics_1h WHERE host <> '';
+ALTER TABLE metrics_1h DROP COLUMN IF EXISTS host;
+ALTER TABLE metrics_1h ADD PRIMARY KEY (bucket);
+
+ALTER TABLE metrics_1m DROP CONSTRAINT IF EXISTS metrics_1m_pkey;
+DELETE FROM metrics_1m WHERE host <> '';
+ALTER TABLE metrics_1m DROP COLUMN IF EXISTS host;
+ALTER TABLE metrics_1m ADD PRIMARY KEY (bucket);
+
+DROP INDEX IF EXISTS idx_metrics_host_created_at;
+ALTER TABLE metrics DROP COLUMN IF EXISTS host;
@@ -0,0 +1,14 @@
+ALTER TABLE metrics ADD COLUMN IF NOT EXISTS host text NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_metrics_host_created_at ON metrics (host, created_at);
+
+ALTER TABLE metrics_1m ADD COLUMN IF NOT EXISTS host text NOT NULL DEFAULT '';
+ALTER TABLE metrics_1m DROP CONSTRAINT IF EXISTS metrics_1m_pkey;
+ALTER TABLE metrics_1m ADD PRIMARY KEY (host, bucket);
+
+ALTER TABLE metrics_1h ADD COLUMN IF NOT EXISTS host text NOT NULL DEFAULT '';
+ALTER TABLE metrics_1h DROP CONSTRAINT IF EXISTS metrics_1h_pkey;
+ALTER TABLE metrics_1h ADD PRIMARY KEY (host, bucket);
+
+ALTER TABLE metrics_1d ADD COLUMN IF NOT EXISTS host text NOT NULL DEFAULT '';
+ALTER TABLE metrics_1d DROP CONSTRAINT IF EXISTS metrics_1d_pkey;
+ALTER TABLE metrics_1d ADD PRIMARY KEY (host, bucket);
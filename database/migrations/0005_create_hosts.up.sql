@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS hosts (
+    id               uuid PRIMARY KEY,
+    hostname         text NOT NULL,
+    os               text NOT NULL DEFAULT '',
+    platform         text NOT NULL DEFAULT '',
+    platform_version text NOT NULL DEFAULT '',
+    kernel           text NOT NULL DEFAULT '',
+    arch             text NOT NULL DEFAULT '',
+    cpu_model        text NOT NULL DEFAULT '',
+    cpu_count        integer NOT NULL DEFAULT 0,
+    total_memory     bigint NOT NULL DEFAULT 0,
+    agent_version    text NOT NULL DEFAULT '',
+    first_seen       timestamptz NOT NULL,
+    last_seen        timestamptz NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_hosts_hostname ON hosts (hostname);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS host_labels (
+    host_id uuid NOT NULL REFERENCES hosts (id) ON DELETE CASCADE,
+    key     text NOT NULL,
+    value   text NOT NULL,
+    source  text NOT NULL,
+    PRIMARY KEY (host_id, key)
+);
+CREATE INDEX IF NOT EXISTS idx_host_labels_key_value ON host_labels (key, value);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS samples;
+DROP TABLE IF EXISTS series_labels;
+DROP TABLE IF EXISTS series;
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS series (
+    id          bigserial PRIMARY KEY,
+    name        text NOT NULL,
+    host        text NOT NULL DEFAULT '',
+    fingerprint text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_series_fingerprint ON series (fingerprint);
+CREATE INDEX IF NOT EXISTS idx_series_name ON series (name);
+CREATE INDEX IF NOT EXISTS idx_series_host ON series (host);
+
+CREATE TABLE IF NOT EXISTS series_labels (
+    series_id bigint NOT NULL REFERENCES series (id) ON DELETE CASCADE,
+    key       text NOT NULL,
+    value     text NOT NULL,
+    PRIMARY KEY (series_id, key)
+);
+CREATE INDEX IF NOT EXISTS idx_series_labels_key_value ON series_labels (key, value);
+
+CREATE TABLE IF NOT EXISTS samples (
+    series_id bigint NOT NULL,
+    ts        timestamptz NOT NULL,
+    value     double precision NOT NULL,
+    PRIMARY KEY (series_id, ts)
+);
+CREATE INDEX IF NOT EXISTS idx_samples_ts ON samples (ts);
@@ -0,0 +1,237 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"go.uber.org/zap"
+)
+
+const (
+	PartitionDaily  = "day"
+	PartitionWeekly = "week"
+)
+
+// Partitioned is true once the metrics table is range partitioned on
+// created_at.
+var Partitioned bool
+
+var partitionUpperBound = regexp.MustCompile(`TO \('([^']+)'\)`)
+
+// setupPartitioning turns the metrics table into a table partitioned by
+// cfg.PartitionInterval, copying existing rows into their partitions, and
+// pre-creates the upcoming partitions.
+func setupPartitioning(cfg *models.Config) {
+	if cfg.PartitionInterval != PartitionDaily && cfg.PartitionInterval != PartitionWeekly {
+		logger.Log.Error("Unsupported PARTITION_INTERVAL, using a plain table", zap.String("interval", cfg.PartitionInterval))
+		return
+	}
+
+	var partitioned bool
+	if err := DB.Raw("SELECT EXISTS(SELECT 1 FROM pg_partitioned_table WHERE partrelid = 'metrics'::regclass)").
+		Scan(&partitioned).Error; err != nil {
+		logger.Log.Error("Error checking metrics partitioning, using a plain table", zap.Error(err))
+		return
+	}
+
+	if !partitioned {
+		if err := convertToPartitioned(cfg); err != nil {
+			logger.Log.Error("Error partitioning metrics table, using a plain table", zap.Error(err))
+			return
+		}
+	}
+
+	Partitioned = true
+	if err := MaintainPartitions(context.Background(), cfg, time.Now()); err != nil {
+		logger.Log.Error("Error creating upcoming partitions", zap.Error(err))
+	}
+	logger.Log.Info("Metrics table partitioning enabled", zap.String("interval", cfg.PartitionInterval))
+}
+
+func convertToPartitioned(cfg *models.Config) error {
+	var bounds struct {
+		First *time.Time
+		Last  *time.Time
+	}
+	if err := DB.Raw("SELECT MIN(created_at) AS first, MAX(created_at) AS last FROM metrics").Scan(&bounds).Error; err != nil {
+		return err
+	}
+
+	// Indexes stay with the old table, so their definitions are recreated on
+	// the partitioned table once the old one is gone.
+	var indexes []string
+	if err := DB.Raw(`SELECT indexdef FROM pg_indexes
+			WHERE schemaname = current_schema() AND tablename = 'metrics' AND indexname <> 'metrics_pkey'
+			ORDER BY indexname`).Scan(&indexes).Error; err != nil {
+		return err
+	}
+
+	tx := DB.Begin()
+	defer tx.Rollback()
+
+	steps := []setupStep{
+		{"rename table", "ALTER TABLE metrics RENAME TO metrics_unpartitioned", nil},
+		{"rename primary key", "ALTER TABLE metrics_unpartitioned RENAME CONSTRAINT metrics_pkey TO metrics_unpartitioned_pkey", nil},
+		// The partition key has to be part of the primary key.
+		{"create partitioned table", `CREATE TABLE metrics (
+			LIKE metrics_unpartitioned INCLUDING DEFAULTS,
+			PRIMARY KEY (id, created_at)
+		) PARTITION BY RANGE (created_at)`, nil},
+		// Catches rows outside every partition, e.g. from a skewed clock.
+		{"create default partition", "CREATE TABLE metrics_default PARTITION OF metrics DEFAULT", nil},
+	}
+	for _, step := range steps {
+		if err := tx.Exec(step.sql, step.args...).Error; err != nil {
+			return fmt.Errorf("%s: %w", step.name, err)
+		}
+	}
+
+	if bounds.First != nil && bounds.Last != nil {
+		for start := partitionStart(*bounds.First, cfg.PartitionInterval); !start.After(*bounds.Last); start = partitionEnd(start, cfg.PartitionInterval) {
+			if err := tx.Exec(createPartitionSQL(start, cfg.PartitionInterval)).Error; err != nil {
+				return fmt.Errorf("create partition: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Exec("INSERT INTO metrics SELECT * FROM metrics_unpartitioned").Error; err != nil {
+		return fmt.Errorf("copy rows: %w", err)
+	}
+	if err := tx.Exec("DROP TABLE metrics_unpartitioned").Error; err != nil {
+		return fmt.Errorf("drop old table: %w", err)
+	}
+	for _, def := range indexes {
+		if err := tx.Exec(def).Error; err != nil {
+			return fmt.Errorf("create index: %w", err)
+		}
+	}
+	return tx.Commit().Error
+}
+
+// MaintainPartitions makes sure the partition holding now and the next
+// cfg.PartitionPremake partitions exist.
+func MaintainPartitions(ctx context.Context, cfg *models.Config, now time.Time) error {
+	start := partitionStart(now, cfg.PartitionInterval)
+	for i := 0; i <= cfg.PartitionPremake; i++ {
+		if err := addPartition(ctx, start, cfg.PartitionInterval); err != nil {
+			return err
+		}
+		start = partitionEnd(start, cfg.PartitionInterval)
+	}
+	return nil
+}
+
+// addPartition creates the partition starting at start unless it exists.
+func addPartition(ctx context.Context, start time.Time, interval string) error {
+	var exists bool
+	if err := DB.WithContext(ctx).Raw("SELECT to_regclass(?) IS NOT NULL", partitionName(start)).Scan(&exists).Error; err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	tx := DB.WithContext(ctx).Begin()
+	defer tx.Rollback()
+	for _, step := range addPartitionSteps(start, interval) {
+		if err := tx.Exec(step.sql, step.args...).Error; err != nil {
+			return fmt.Errorf("%s: %w", step.name, err)
+		}
+	}
+	return tx.Commit().Error
+}
+
+// addPartitionSteps builds the partition starting at start as a plain table
+// and attaches it. Postgres refuses to attach a partition while the default
+// partition holds rows in its range, so those rows are moved over first.
+func addPartitionSteps(start time.Time, interval string) []setupStep {
+	name := partitionName(start)
+	end := partitionEnd(start, interval)
+	return []setupStep{
+		{"create partition table", fmt.Sprintf("CREATE TABLE %s (LIKE metrics INCLUDING DEFAULTS)", name), nil},
+		{"move default partition rows", fmt.Sprintf(`WITH moved AS (
+			DELETE FROM metrics_default WHERE created_at >= ? AND created_at < ? RETURNING *
+		) INSERT INTO %s SELECT * FROM moved`, name), []interface{}{start, end}},
+		{"attach partition", fmt.Sprintf("ALTER TABLE metrics ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')",
+			name, start.Format(time.RFC3339), end.Format(time.RFC3339)), nil},
+	}
+}
+
+// DropExpiredPartitions drops every partition of table whose upper bound is
+// not after cutoff and returns how many were dropped.
+func DropExpiredPartitions(ctx context.Context, table string, cutoff time.Time) (int, error) {
+	var partitions []struct {
+		Name  string
+		Bound string
+	}
+	if err := DB.WithContext(ctx).Raw(`SELECT c.relname AS name, pg_get_expr(c.relpartbound, c.oid) AS bound
+			FROM pg_inherits i
+			JOIN pg_class c ON c.oid = i.inhrelid
+			JOIN pg_class p ON p.oid = i.inhparent
+			JOIN pg_namespace n ON n.oid = p.relnamespace
+			WHERE n.nspname = current_schema() AND p.relname = ?`, table).Scan(&partitions).Error; err != nil {
+		return 0, err
+	}
+
+	dropped := 0
+	for _, p := range partitions {
+		match := partitionUpperBound.FindStringSubmatch(p.Bound)
+		if match == nil {
+			continue // the default partition
+		}
+		upper, err := parsePartitionBound(match[1])
+		if err != nil {
+			logger.Log.Warn("Unrecognised partition bound", zap.String("partition", p.Name), zap.String("bound", p.Bound))
+			continue
+		}
+		if upper.After(cutoff) {
+			continue
+		}
+		if err := DB.WithContext(ctx).Exec(fmt.Sprintf("DROP TABLE IF EXISTS %q", p.Name)).Error; err != nil {
+			return dropped, err
+		}
+		logger.Log.Info("Dropped expired partition", zap.String("partition", p.Name), zap.Time("upper_bound", upper))
+		dropped++
+	}
+	return dropped, nil
+}
+
+func createPartitionSQL(start time.Time, interval string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF metrics FOR VALUES FROM ('%s') TO ('%s')",
+		partitionName(start), start.Format(time.RFC3339), partitionEnd(start, interval).Format(time.RFC3339))
+}
+
+func partitionName(start time.Time) string {
+	return "metrics_p" + start.Format("20060102")
+}
+
+// partitionStart returns the UTC midnight (day) or Monday midnight (week)
+// starting the partition that holds t.
+func partitionStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if interval == PartitionWeekly {
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+	return day
+}
+
+func partitionEnd(start time.Time, interval string) time.Time {
+	if interval == PartitionWeekly {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+func parsePartitionBound(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05-07", "2006-01-02 15:04:05-07:00", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid partition bound %q", value)
+}
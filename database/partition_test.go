@@ -0,0 +1,225 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// postgresTestDB points DB at a fresh schema of the database named by the
+// env variable, migrated to the latest version, and skips the test when the
+// variable is unset. The schema is dropped when the test ends.
+func postgresTestDB(t *testing.T, env string) {
+	dsn := os.Getenv(env)
+	if dsn == "" {
+		t.Skipf("%s is not set", env)
+	}
+	logger.Log = zap.NewNop()
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	schema := fmt.Sprintf("metrics_monitor_test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+
+	// Every connection of the pool has to use the schema.
+	if strings.Contains(dsn, "://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + "search_path=" + schema
+	} else {
+		dsn += " search_path=" + schema
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	prev := DB
+	DB = db
+	t.Cleanup(func() {
+		DB = prev
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if _, err := MigrateUp(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+}
+
+func TestPartitionBounds(t *testing.T) {
+	// Wednesday 23:30 in UTC-5 is Thursday 04:30 UTC
+	at := time.Date(2024, 3, 6, 23, 30, 0, 0, time.FixedZone("EST", -5*3600))
+
+	day := partitionStart(at, PartitionDaily)
+	assert.Equal(t, time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC), day)
+	assert.Equal(t, time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC), partitionEnd(day, PartitionDaily))
+
+	week := partitionStart(at, PartitionWeekly)
+	assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), week, "Expected weeks to start on Monday")
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), partitionEnd(week, PartitionWeekly))
+	assert.Equal(t, week, partitionStart(week, PartitionWeekly))
+	assert.Equal(t, week, partitionStart(week.Add(7*24*time.Hour-time.Nanosecond), PartitionWeekly))
+
+	// Sunday belongs to the week started by the previous Monday
+	assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), partitionStart(time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC), PartitionWeekly))
+}
+
+func TestParsePartitionBound(t *testing.T) {
+	want := time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC)
+	for _, value := range []string{"2024-03-07 00:00:00+00", "2024-03-07 00:00:00+00:00", "2024-03-07 00:00:00"} {
+		got, err := parsePartitionBound(value)
+		if assert.NoError(t, err, value) {
+			assert.True(t, want.Equal(got), value)
+		}
+	}
+	got, err := parsePartitionBound("2024-03-07 02:00:00+02")
+	assert.NoError(t, err)
+	assert.True(t, want.Equal(got), "Expected the offset to be applied")
+
+	_, err = parsePartitionBound("MAXVALUE")
+	assert.Error(t, err)
+
+	match := partitionUpperBound.FindStringSubmatch("FOR VALUES FROM ('2024-03-06 00:00:00+00') TO ('2024-03-07 00:00:00+00')")
+	if assert.NotNil(t, match) {
+		assert.Equal(t, "2024-03-07 00:00:00+00", match[1])
+	}
+	assert.Nil(t, partitionUpperBound.FindStringSubmatch("DEFAULT"))
+}
+
+func TestPartitionSQL(t *testing.T) {
+	start := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t,
+		"CREATE TABLE IF NOT EXISTS metrics_p20240304 PARTITION OF metrics FOR VALUES FROM ('2024-03-04T00:00:00Z') TO ('2024-03-11T00:00:00Z')",
+		createPartitionSQL(start, PartitionWeekly))
+
+	steps := addPartitionSteps(start, PartitionDaily)
+	if !assert.Len(t, steps, 3) {
+		return
+	}
+	assert.Equal(t, "CREATE TABLE metrics_p20240304 (LIKE metrics INCLUDING DEFAULTS)", steps[0].sql)
+	assert.Contains(t, steps[1].sql, "DELETE FROM metrics_default WHERE created_at >= ? AND created_at < ?")
+	assert.Contains(t, steps[1].sql, "INSERT INTO metrics_p20240304 SELECT * FROM moved")
+	assert.Equal(t, []interface{}{start, start.AddDate(0, 0, 1)}, steps[1].args, "Expected the rows of the new range to be moved")
+	assert.Equal(t,
+		"ALTER TABLE metrics ATTACH PARTITION metrics_p20240304 FOR VALUES FROM ('2024-03-04T00:00:00Z') TO ('2024-03-05T00:00:00Z')",
+		steps[2].sql, "Expected the partition to be attached after the rows were moved")
+}
+
+// TestConvertToPartitionedPostgres needs POSTGRES_DSN pointing at a
+// PostgreSQL database the tests may create schemas in.
+func TestConvertToPartitionedPostgres(t *testing.T) {
+	postgresTestDB(t, "POSTGRES_DSN")
+	defer func() { Partitioned = false }()
+
+	day := partitionStart(time.Now().AddDate(0, 0, -3), PartitionDaily)
+	assert.NoError(t, DB.Create(&[]models.Metrics{
+		{ID: uuid.New(), Host: "web-01", CPUPercent: 10, CreatedAt: day.Add(time.Hour)},
+		{ID: uuid.New(), Host: "web-01", CPUPercent: 20, CreatedAt: day.AddDate(0, 0, 1).Add(time.Hour)},
+	}).Error)
+
+	setupPartitioning(&models.Config{PartitionInterval: PartitionDaily, PartitionPremake: 1})
+	if !assert.True(t, Partitioned) {
+		return
+	}
+
+	var indexes []string
+	assert.NoError(t, DB.Raw("SELECT indexname FROM pg_indexes WHERE schemaname = current_schema() AND tablename = 'metrics' ORDER BY indexname").
+		Scan(&indexes).Error)
+	assert.Equal(t, []string{"idx_metrics_created_at", "idx_metrics_host_created_at", "metrics_pkey"}, indexes,
+		"Expected every index of the plain table on the partitioned table")
+
+	var partitions []string
+	assert.NoError(t, DB.Raw("SELECT tableoid::regclass::text FROM metrics ORDER BY created_at").Scan(&partitions).Error)
+	assert.Equal(t, []string{partitionName(day), partitionName(day.AddDate(0, 0, 1))}, partitions)
+}
+
+// TestPartitionMaintenancePostgres needs POSTGRES_DSN, see
+// TestConvertToPartitionedPostgres.
+func TestPartitionMaintenancePostgres(t *testing.T) {
+	postgresTestDB(t, "POSTGRES_DSN")
+	defer func() { Partitioned = false }()
+
+	ctx := context.Background()
+	cfg := &models.Config{PartitionInterval: PartitionDaily}
+	setupPartitioning(cfg)
+	if !assert.True(t, Partitioned) {
+		return
+	}
+
+	partitionOf := func(id uuid.UUID) string {
+		var name string
+		assert.NoError(t, DB.Raw("SELECT tableoid::regclass::text FROM metrics WHERE id = ?", id).Scan(&name).Error)
+		return name
+	}
+	partitions := func() []string {
+		var names []string
+		assert.NoError(t, DB.Raw(`SELECT c.relname FROM pg_inherits i
+			JOIN pg_class c ON c.oid = i.inhrelid
+			WHERE i.inhparent = 'metrics'::regclass
+			ORDER BY c.relname`).Scan(&names).Error)
+		return names
+	}
+
+	now := time.Now()
+	today := partitionStart(now, PartitionDaily)
+	current, ahead, old := uuid.New(), uuid.New(), uuid.New()
+	assert.NoError(t, DB.Create(&[]models.Metrics{
+		{ID: current, Host: "web-01", CreatedAt: now},
+		{ID: ahead, Host: "web-01", CreatedAt: today.AddDate(0, 0, 2).Add(time.Hour)},
+		{ID: old, Host: "web-01", CreatedAt: today.AddDate(0, 0, -100)},
+	}).Error)
+	assert.Equal(t, partitionName(today), partitionOf(current), "Expected inserts to be routed to their partition")
+	assert.Equal(t, "metrics_default", partitionOf(ahead))
+	assert.Equal(t, "metrics_default", partitionOf(old))
+
+	// Creating the partition of a row kept by the default partition moves it.
+	cfg.PartitionPremake = 2
+	assert.NoError(t, MaintainPartitions(ctx, cfg, now))
+	assert.Equal(t, partitionName(today.AddDate(0, 0, 2)), partitionOf(ahead))
+	assert.Equal(t, "metrics_default", partitionOf(old))
+
+	// Partitions in the past, the first of which expires.
+	expired := today.AddDate(0, 0, -10)
+	cfg.PartitionPremake = 1
+	assert.NoError(t, MaintainPartitions(ctx, cfg, expired))
+	assert.NoError(t, DB.Create(&models.Metrics{ID: uuid.New(), Host: "web-01", CreatedAt: expired.Add(time.Hour)}).Error)
+
+	dropped, err := DropExpiredPartitions(ctx, "metrics", partitionEnd(expired, PartitionDaily))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, dropped)
+	assert.Equal(t, []string{
+		"metrics_default",
+		partitionName(expired.AddDate(0, 0, 1)),
+		partitionName(today),
+		partitionName(today.AddDate(0, 0, 1)),
+		partitionName(today.AddDate(0, 0, 2)),
+	}, partitions(), "Expected only the expired partition to be dropped")
+
+	var count int64
+	assert.NoError(t, DB.Model(&models.Metrics{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count, "Expected the rows of the expired partition to be gone")
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"go.uber.org/zap"
+)
+
+// TimescaleCaggHourly is the continuous aggregate kept over the metrics
+// hypertable, one row of sums and counts per host and hour.
+const TimescaleCaggHourly = "metrics_cagg_1h"
+
+// Timescale is true once the metrics table has been converted into a
+// hypertable, letting queries use the continuous aggregate.
+var Timescale bool
+
+// setupStep is one idempotent statement of an optional storage setup.
+type setupStep struct {
+	name string
+	sql  string
+	args []interface{}
+}
+
+// timescaleAvailable reports whether the timescaledb extension can be
+// created. Tests replace it together with timescaleExec.
+var timescaleAvailable = func() (bool, error) {
+	var available bool
+	err := DB.Raw("SELECT EXISTS(SELECT 1 FROM pg_available_extensions WHERE name = 'timescaledb')").
+		Scan(&available).Error
+	return available, err
+}
+
+// timescaleExec runs one step of setupTimescale.
+var timescaleExec = func(step setupStep) error {
+	return DB.Exec(step.sql, step.args...).Error
+}
+
+// setupTimescale converts the metrics table into a hypertable with
+// compression and an hourly continuous aggregate. Any failure leaves the
+// plain table in place and is only logged.
+func setupTimescale(cfg *models.Config) {
+	if available, err := timescaleAvailable(); err != nil || !available {
+		logger.Log.Warn("TimescaleDB requested but the extension is not available, using a plain table", zap.Error(err))
+		return
+	}
+
+	for _, step := range timescaleSteps(cfg) {
+		if err := timescaleExec(step); err != nil {
+			logger.Log.Error("TimescaleDB setup failed, using a plain table", zap.String("step", step.name), zap.Error(err))
+			return
+		}
+	}
+
+	Timescale = true
+	logger.Log.Info("TimescaleDB hypertable enabled",
+		zap.Duration("chunk_interval", cfg.TimescaleChunkInterval),
+		zap.Duration("compress_after", cfg.TimescaleCompressAfter))
+}
+
+// timescaleSteps returns the statements of setupTimescale in order.
+func timescaleSteps(cfg *models.Config) []setupStep {
+	steps := []setupStep{
+		{"create extension", "CREATE EXTENSION IF NOT EXISTS timescaledb", nil},
+		// Unique constraints on a hypertable must contain the time column.
+		{"primary key", `DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM timescaledb_information.hypertables
+		WHERE hypertable_schema = current_schema() AND hypertable_name = 'metrics') THEN
+		ALTER TABLE metrics DROP CONSTRAINT IF EXISTS metrics_pkey;
+		ALTER TABLE metrics ADD PRIMARY KEY (id, created_at);
+	END IF;
+END $$`, nil},
+		{"create hypertable", "SELECT create_hypertable('metrics', 'created_at', chunk_time_interval => ?::interval, if_not_exists => TRUE, migrate_data => TRUE)",
+			[]interface{}{pgInterval(cfg.TimescaleChunkInterval)}},
+		{"chunk interval", "SELECT set_chunk_time_interval('metrics', ?::interval)",
+			[]interface{}{pgInterval(cfg.TimescaleChunkInterval)}},
+	}
+
+	if cfg.TimescaleCompressAfter > 0 {
+		steps = append(steps,
+			// Compression settings cannot be changed once chunks are compressed.
+			setupStep{"enable compression", `DO $$
+BEGIN
+	IF NOT (SELECT compression_enabled FROM timescaledb_information.hypertables
+		WHERE hypertable_schema = current_schema() AND hypertable_name = 'metrics') THEN
+		ALTER TABLE metrics SET (timescaledb.compress, timescaledb.compress_orderby = 'created_at DESC');
+	END IF;
+END $$`, nil},
+			setupStep{"compression policy", "SELECT add_compression_policy('metrics', ?::interval, if_not_exists => TRUE)",
+				[]interface{}{pgInterval(cfg.TimescaleCompressAfter)}},
+		)
+	}
+
+	steps = append(steps,
+		// Aggregates created before hosts were tracked cannot be filtered by host.
+		setupStep{"drop outdated continuous aggregate", fmt.Sprintf(`DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = '%[1]s')
+		AND NOT EXISTS (SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = '%[1]s' AND column_name = 'host') THEN
+		DROP MATERIALIZED VIEW %[1]s;
+	END IF;
+END $$`, TimescaleCaggHourly), nil},
+		setupStep{"continuous aggregate", fmt.Sprintf(`CREATE MATERIALIZED VIEW IF NOT EXISTS %s
+WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+SELECT time_bucket(INTERVAL '1 hour', created_at) AS bucket,
+	host,
+	SUM(cpu_percent) AS cpu_sum,
+	SUM(mem_percent) AS mem_sum,
+	COUNT(*) AS sample_count
+FROM metrics
+GROUP BY bucket, host
+WITH NO DATA`, TimescaleCaggHourly), nil},
+		setupStep{"continuous aggregate policy", fmt.Sprintf(`SELECT add_continuous_aggregate_policy('%s',
+	start_offset => INTERVAL '3 days',
+	end_offset => INTERVAL '1 hour',
+	schedule_interval => INTERVAL '30 minutes',
+	if_not_exists => TRUE)`, TimescaleCaggHourly), nil},
+	)
+
+	return steps
+}
+
+// DropTimescaleChunks drops the chunks of table that only hold rows older
+// than cutoff and returns how many were dropped.
+func DropTimescaleChunks(ctx context.Context, table string, cutoff time.Time) (int, error) {
+	var dropped []string
+	if err := DB.WithContext(ctx).
+		Raw("SELECT drop_chunks(?::regclass, older_than => ?::timestamptz)", table, cutoff).
+		Scan(&dropped).Error; err != nil {
+		return 0, err
+	}
+	return len(dropped), nil
+}
+
+// pgInterval formats d as a Postgres interval literal.
+func pgInterval(d time.Duration) string {
+	return fmt.Sprintf("%d seconds", int64(d.Seconds()))
+}
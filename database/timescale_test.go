@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestTimescaleSteps(t *testing.T) {
+	names := func(steps []setupStep) []string {
+		var res []string
+		for _, step := range steps {
+			res = append(res, step.name)
+		}
+		return res
+	}
+
+	steps := timescaleSteps(&models.Config{TimescaleChunkInterval: 24 * time.Hour})
+	assert.Equal(t, []string{
+		"create extension", "primary key", "create hypertable", "chunk interval",
+		"drop outdated continuous aggregate", "continuous aggregate", "continuous aggregate policy",
+	}, names(steps), "Expected no compression steps when compression is disabled")
+	assert.Equal(t, []interface{}{"86400 seconds"}, steps[2].args)
+	assert.Contains(t, steps[2].sql, "create_hypertable('metrics', 'created_at'")
+	assert.Contains(t, steps[5].sql, "CREATE MATERIALIZED VIEW IF NOT EXISTS "+TimescaleCaggHourly)
+	assert.Contains(t, steps[5].sql, "GROUP BY bucket, host")
+
+	steps = timescaleSteps(&models.Config{TimescaleChunkInterval: time.Hour, TimescaleCompressAfter: 7 * 24 * time.Hour})
+	assert.Equal(t, []string{
+		"create extension", "primary key", "create hypertable", "chunk interval",
+		"enable compression", "compression policy",
+		"drop outdated continuous aggregate", "continuous aggregate", "continuous aggregate policy",
+	}, names(steps))
+	assert.Equal(t, []interface{}{"3600 seconds"}, steps[3].args)
+	assert.Equal(t, []interface{}{"604800 seconds"}, steps[5].args)
+}
+
+func TestSetupTimescaleFallback(t *testing.T) {
+	logger.Log = zap.NewNop()
+	cfg := &models.Config{TimescaleChunkInterval: 24 * time.Hour}
+	defer func(available func() (bool, error), exec func(setupStep) error) {
+		timescaleAvailable, timescaleExec, Timescale = available, exec, false
+	}(timescaleAvailable, timescaleExec)
+
+	var ran []string
+	setup := func(available bool, availableErr error, failAt string) {
+		Timescale = false
+		ran = nil
+		timescaleAvailable = func() (bool, error) { return available, availableErr }
+		timescaleExec = func(step setupStep) error {
+			ran = append(ran, step.name)
+			if step.name == failAt {
+				return errors.New("permission denied")
+			}
+			return nil
+		}
+		setupTimescale(cfg)
+	}
+
+	setup(true, nil, "")
+	assert.True(t, Timescale)
+	assert.Len(t, ran, len(timescaleSteps(cfg)))
+
+	setup(false, nil, "")
+	assert.False(t, Timescale, "Expected a plain table without the extension")
+	assert.Empty(t, ran)
+
+	setup(true, errors.New("connection reset"), "")
+	assert.False(t, Timescale)
+	assert.Empty(t, ran)
+
+	setup(true, nil, "create hypertable")
+	assert.False(t, Timescale, "Expected a plain table when a step fails")
+	assert.Equal(t, []string{"create extension", "primary key", "create hypertable"}, ran, "Expected no steps after the failing one")
+}
+
+// TestTimescalePostgres needs TIMESCALE_DSN pointing at a PostgreSQL database
+// with the timescaledb extension available that the tests may create
+// schemas in.
+func TestTimescalePostgres(t *testing.T) {
+	postgresTestDB(t, "TIMESCALE_DSN")
+	defer func() { Timescale = false }()
+
+	setupTimescale(&models.Config{TimescaleChunkInterval: 24 * time.Hour, TimescaleCompressAfter: 7 * 24 * time.Hour})
+	if !assert.True(t, Timescale) {
+		return
+	}
+
+	var compressed []bool
+	assert.NoError(t, DB.Raw(`SELECT compression_enabled FROM timescaledb_information.hypertables
+		WHERE hypertable_schema = current_schema() AND hypertable_name = 'metrics'`).Scan(&compressed).Error)
+	assert.Equal(t, []bool{true}, compressed, "Expected a hypertable with compression")
+
+	var views []string
+	assert.NoError(t, DB.Raw(`SELECT view_name FROM timescaledb_information.continuous_aggregates
+		WHERE view_schema = current_schema()`).Scan(&views).Error)
+	assert.Equal(t, []string{TimescaleCaggHourly}, views)
+
+	now := time.Now().UTC()
+	assert.NoError(t, DB.Create(&[]models.Metrics{
+		{ID: uuid.New(), Host: "web-01", CPUPercent: 10, CreatedAt: now.AddDate(0, 0, -30)},
+		{ID: uuid.New(), Host: "web-01", CPUPercent: 20, CreatedAt: now.AddDate(0, 0, -20)},
+		{ID: uuid.New(), Host: "web-01", CPUPercent: 30, CreatedAt: now.Add(-time.Hour)},
+	}).Error)
+
+	// The aggregate is real-time, so it covers rows not materialized yet.
+	var samples int64
+	assert.NoError(t, DB.Raw("SELECT COALESCE(SUM(sample_count), 0) FROM "+TimescaleCaggHourly).Scan(&samples).Error)
+	assert.Equal(t, int64(3), samples)
+
+	dropped, err := DropTimescaleChunks(context.Background(), "metrics", now.AddDate(0, 0, -7))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, dropped, "Expected the chunks of both old rows to be dropped")
+	var remaining []float64
+	assert.NoError(t, DB.Raw("SELECT cpu_percent FROM metrics").Scan(&remaining).Error)
+	assert.Equal(t, []float64{30}, remaining)
+}
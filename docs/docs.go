@@ -15,121 +15,132 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/health": {
+        "/api/v1/label/{name}/values": {
             "get": {
-                "description": "Returns service status",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Health"
+                    "Prometheus"
+                ],
+                "summary": "List the values of a label",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Label name, __name__ for metric names",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Check service health",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.PromResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromResponse"
                         }
                     }
                 }
             }
         },
-        "/metrics": {
+        "/api/v1/labels": {
             "get": {
-                "description": "Fetch metrics collected between start and end timestamps.",
-                "consumes": [
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "Prometheus"
+                ],
+                "summary": "List label names",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/query": {
+            "get": {
+                "description": "Prometheus HTTP API compatible instant query over a PromQL subset: selectors with label matchers, range vectors, rate, irate, increase and *_over_time functions, sum/avg/min/max/count by or without labels, and arithmetic. cpu_percent and mem_percent are available as series with a host label.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Metrics"
+                    "Prometheus"
                 ],
-                "summary": "Get Metrics By Time Range",
+                "summary": "Evaluate a PromQL instant query",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Start timestamp (RFC3339 format, e.g., 2025-02-22T00:00:00Z)",
-                        "name": "start",
+                        "description": "PromQL expression, e.g. avg by (host) (rate(node_network_receive_bytes_total[5m]))",
+                        "name": "query",
                         "in": "query",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "End timestamp (RFC3339 format, e.g., 2025-02-22T23:59:59Z)",
-                        "name": "end",
-                        "in": "query",
-                        "required": true
+                        "description": "Evaluation time as RFC3339 or Unix seconds, defaults to now",
+                        "name": "time",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Metrics"
-                            }
+                            "$ref": "#/definitions/models.PromResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.PromResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "422": {
+                        "description": "Unprocessable Entity",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.PromResponse"
                         }
                     }
                 }
-            }
-        },
-        "/metrics/": {
-            "get": {
-                "description": "Get paginated metrics data",
-                "consumes": [
-                    "application/json"
-                ],
+            },
+            "post": {
+                "description": "Prometheus HTTP API compatible instant query over a PromQL subset: selectors with label matchers, range vectors, rate, irate, increase and *_over_time functions, sum/avg/min/max/count by or without labels, and arithmetic. cpu_percent and mem_percent are available as series with a host label.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Metrics"
+                    "Prometheus"
                 ],
-                "summary": "Retrieve all collected metrics",
+                "summary": "Evaluate a PromQL instant query",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
+                        "type": "string",
+                        "description": "PromQL expression, e.g. avg by (host) (rate(node_network_receive_bytes_total[5m]))",
+                        "name": "query",
+                        "in": "query",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Number of items per page",
-                        "name": "pageSize",
+                        "type": "string",
+                        "description": "Evaluation time as RFC3339 or Unix seconds, defaults to now",
+                        "name": "time",
                         "in": "query"
                     }
                 ],
@@ -137,59 +148,162 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Metrics"
-                            }
+                            "$ref": "#/definitions/models.PromResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.PromResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromResponse"
                         }
                     }
                 }
             }
         },
-        "/metrics/average": {
+        "/api/v1/query_range": {
             "get": {
-                "description": "Retrieve average CPU and memory usage between start and end timestamps",
-                "consumes": [
+                "description": "Prometheus HTTP API compatible range query, evaluating the expression at every step between start and end.",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "Prometheus"
+                ],
+                "summary": "Evaluate a PromQL range query",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "PromQL expression",
+                        "name": "query",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start time as RFC3339 or Unix seconds",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End time as RFC3339 or Unix seconds",
+                        "name": "end",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Step as a duration (e.g. 15s, 1m) or seconds",
+                        "name": "step",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Prometheus HTTP API compatible range query, evaluating the expression at every step between start and end.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Metrics"
+                    "Prometheus"
                 ],
-                "summary": "Get average CPU and memory usage in a time range",
+                "summary": "Evaluate a PromQL range query",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Start timestamp (RFC3339 format)",
+                        "description": "PromQL expression",
+                        "name": "query",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start time as RFC3339 or Unix seconds",
                         "name": "start",
                         "in": "query",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "End timestamp (RFC3339 format)",
+                        "description": "End time as RFC3339 or Unix seconds",
                         "name": "end",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Step as a duration (e.g. 15s, 1m) or seconds",
+                        "name": "step",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.AvgMetrics"
+                            "$ref": "#/definitions/models.PromResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/models.PromResponse"
                         }
+                    }
+                }
+            }
+        },
+        "/api/v1/read": {
+            "post": {
+                "description": "Answers a snappy compressed protobuf ReadRequest with the raw samples of the matching series, including cpu_percent and mem_percent. Only the SAMPLES response type is supported.",
+                "consumes": [
+                    "application/x-protobuf"
+                ],
+                "produces": [
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "Prometheus"
+                ],
+                "summary": "Prometheus remote_read endpoint",
+                "responses": {
+                    "200": {
+                        "description": "OK"
                     },
                     "400": {
                         "description": "Bad Request",
@@ -211,34 +325,1603 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.AvgMetrics": {
-            "type": "object",
-            "properties": {
-                "cpu_percent": {
-                    "type": "number"
-                },
-                "mem_percent": {
-                    "type": "number"
+        },
+        "/api/v1/write": {
+            "post": {
+                "description": "Stores the samples of a snappy compressed protobuf WriteRequest with their labels. Available in server mode with an ingest token, e.g. remote_write url http://host:8888/api/v1/write with authorization credentials set to the token.",
+                "consumes": [
+                    "application/x-protobuf"
+                ],
+                "tags": [
+                    "Prometheus"
+                ],
+                "summary": "Prometheus remote_write receiver",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003cingest token\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
                 }
             }
         },
-        "models.Metrics": {
-            "type": "object",
-            "properties": {
-                "cpu_percent": {
-                    "type": "number"
-                },
-                "created_at": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "mem_percent": {
-                    "type": "number"
+        "/api/v2/write": {
+            "post": {
+                "description": "Stores every numeric and boolean field as a series named \u003cmeasurement\u003e_\u003cfield\u003e labelled with the tags, e.g. for Telegraf's influxdb and influxdb_v2 outputs. The db, org and bucket parameters are ignored. Available in server mode with an ingest token, sent as \"Token \u003ctoken\u003e\", bearer token, basic auth password or the p parameter.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Ingest"
+                ],
+                "summary": "InfluxDB line protocol write",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Timestamp precision: ns (default), us, ms, s; the v1 API also accepts n, u, m and h",
+                        "name": "precision",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Line protocol",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Returns service status",
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Check service health",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/hosts": {
+            "get": {
+                "description": "Returns the inventory of every host that reported, flagging hosts that stopped reporting as stale",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hosts"
+                ],
+                "summary": "List monitored hosts",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Host"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/hosts/{id}": {
+            "get": {
+                "description": "Returns the inventory of a host looked up by id or host name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hosts"
+                ],
+                "summary": "Get one monitored host",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Host id or host name",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Host"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/hosts/{id}/labels": {
+            "put": {
+                "description": "Replaces the labels managed through the API. They take precedence over labels configured on the agent. Requires server mode and a bearer token from INGEST_TOKENS.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hosts"
+                ],
+                "summary": "Set the labels of a host",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Host id or host name",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Labels to set",
+                        "name": "labels",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.HostLabelsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Host"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/hosts/{id}/labels/{key}": {
+            "delete": {
+                "description": "Requires server mode and a bearer token from INGEST_TOKENS.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hosts"
+                ],
+                "summary": "Remove a label from a host",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Host id or host name",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Label key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ingest": {
+            "post": {
+                "description": "Stores a batch of samples labelled with the reporting host. Requires server mode and a bearer token from INGEST_TOKENS.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Ingest"
+                ],
+                "summary": "Ingest metrics pushed by an agent",
+                "parameters": [
+                    {
+                        "description": "Samples collected by the agent",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.IngestBatch"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.IngestResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "Fetch metrics collected between start and end timestamps. Long ranges are read from the rollup that keeps about ROLLUP_MAX_POINTS points, one metric per bucket holding its averages, unless resolution is raw. When resolution is set to a duration or auto, the data holds models.MetricsRollup rows of the chosen tier instead. The response names the tier in resolution.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Get Metrics By Time Range",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start timestamp (RFC3339 format, e.g., 2025-02-22T00:00:00Z)",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End timestamp (RFC3339 format, e.g., 2025-02-22T23:59:59Z)",
+                        "name": "end",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only metrics of this host",
+                        "name": "host",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Only hosts with this label, as key=value; repeat to require several",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Wanted spacing between points (e.g. 1m, 1h, 1d), auto to derive it from the range, or raw for every raw metric. Picks the coarsest rollup that satisfies it",
+                        "name": "resolution",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Metrics"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics/": {
+            "get": {
+                "description": "Get paginated metrics data",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Retrieve all collected metrics",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of items per page",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only metrics of this host",
+                        "name": "host",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Only hosts with this label, as key=value; repeat to require several",
+                        "name": "label",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Metrics"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics/aggregate": {
+            "get": {
+                "description": "Returns one point per step wide bucket between start and end, buckets starting at start. Empty buckets are left out. rate, irate and increase apply to counter series and handle counter resets.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Aggregate a metric into fixed width buckets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "cpu_percent, mem_percent or the name of a stored series, e.g. node_network_receive_bytes_total",
+                        "name": "metric",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start timestamp (RFC3339 format)",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End timestamp (RFC3339 format)",
+                        "name": "end",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bucket width, e.g. 30s, 5m, 1h or 1d",
+                        "name": "step",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "avg",
+                            "min",
+                            "max",
+                            "sum",
+                            "count",
+                            "last",
+                            "rate",
+                            "irate",
+                            "increase"
+                        ],
+                        "type": "string",
+                        "default": "avg",
+                        "description": "Aggregation function",
+                        "name": "fn",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only metrics of this host",
+                        "name": "host",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Only hosts with this label, as key=value; repeat to require several",
+                        "name": "label",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AggregateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics/average": {
+            "get": {
+                "description": "Retrieve average CPU and memory usage between start and end timestamps",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Get average CPU and memory usage in a time range",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start timestamp (RFC3339 format)",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End timestamp (RFC3339 format)",
+                        "name": "end",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only metrics of this host",
+                        "name": "host",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Only hosts with this label, as key=value; repeat to require several",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Host label to average per value of, e.g. team",
+                        "name": "group_by",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AvgMetrics"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics/chart": {
+            "get": {
+                "description": "Aggregates the metric like /metrics/aggregate and draws it with axes and a legend, one line per host (at most 10) or only the given host. The image can be linked from chat messages, tickets and email reports.",
+                "produces": [
+                    "image/svg+xml",
+                    "image/png"
+                ],
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Render a metric as a PNG or SVG line chart",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "cpu_percent, mem_percent or the name of a stored series",
+                        "name": "metric",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start timestamp (RFC3339 format), default one hour before end",
+                        "name": "start",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End timestamp (RFC3339 format), default now",
+                        "name": "end",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bucket width, e.g. 30s, 5m or 1h, default (end - start) / 300",
+                        "name": "step",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "avg",
+                            "min",
+                            "max",
+                            "sum",
+                            "count",
+                            "last",
+                            "rate",
+                            "irate",
+                            "increase"
+                        ],
+                        "type": "string",
+                        "default": "avg",
+                        "description": "Aggregation function",
+                        "name": "fn",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only this host",
+                        "name": "host",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Only hosts with this label, as key=value; repeat to require several",
+                        "name": "label",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "svg",
+                            "png"
+                        ],
+                        "type": "string",
+                        "default": "svg",
+                        "description": "Image format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 800,
+                        "description": "Width in pixels, 200 to 2000",
+                        "name": "width",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 400,
+                        "description": "Height in pixels, 150 to 1500",
+                        "name": "height",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chart title, default fn(metric)",
+                        "name": "title",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics/stats": {
+            "get": {
+                "description": "Min, max, mean, standard deviation and the 50th, 90th, 95th and 99th percentiles of CPU and memory usage between start and end, with the sample count and the first and last sample times",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Get statistics of CPU and memory usage in a time range",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start timestamp (RFC3339 format)",
+                        "name": "start",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End timestamp (RFC3339 format)",
+                        "name": "end",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only metrics of this host",
+                        "name": "host",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Only hosts with this label, as key=value; repeat to require several",
+                        "name": "label",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MetricsStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics/stream": {
+            "get": {
+                "description": "Pushes every newly stored sample, collected locally or ingested, as a Server-Sent Event named \"sample\" whose data is the sample as JSON. A client that falls behind misses samples.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Live stream of new samples",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Only samples of this metric; repeat for several",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Only samples of this host; repeat for several",
+                        "name": "host",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics/ws": {
+            "get": {
+                "description": "Clients send {\"type\":\"subscribe\",\"id\":\"cpu\",\"selector\":\"cpu_percent{host=~\\\"web-.*\\\"}\",\"backfill\":\"15m\",\"step\":\"1m\"} and {\"type\":\"unsubscribe\",\"id\":\"cpu\"}. The server answers with \"subscribed\", \"unsubscribed\" or \"error\" messages, sends the optional backfill of at most 24h averaged into step wide points as one \"backfill\" message and then every new matching sample as a \"sample\" message, each carrying the subscription id.",
+                "tags": [
+                    "Metrics"
+                ],
+                "summary": "Subscribe to live samples over a WebSocket",
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/prometheus/metrics": {
+            "get": {
+                "description": "Latest value of every series that reported in the last 5 minutes plus Metrics-Monitor's own metrics, for Prometheus to scrape. Served as OpenMetrics when the Accept header asks for application/openmetrics-text. The path is set by PROMETHEUS_PATH.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Prometheus"
+                ],
+                "summary": "Latest samples in Prometheus exposition format",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/retention": {
+            "get": {
+                "description": "Returns the configured retention policy and the rows deleted by the last pruning run of each metric",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Retention"
+                ],
+                "summary": "Get retention policy status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RetentionStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/metrics": {
+            "post": {
+                "description": "Stores the gauge and cumulative sum and histogram data points of an OpenTelemetry ExportMetricsServiceRequest, protobuf or JSON encoded and optionally gzip compressed. Resource attributes become series labels, host.name becomes the host label. Available in server mode with an ingest token.",
+                "consumes": [
+                    "application/x-protobuf",
+                    "application/json"
+                ],
+                "produces": [
+                    "application/x-protobuf",
+                    "application/json"
+                ],
+                "tags": [
+                    "OpenTelemetry"
+                ],
+                "summary": "OTLP/HTTP metrics receiver",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer \u003cingest token\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/otlp.ExportMetricsServiceResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/write": {
+            "post": {
+                "description": "Stores every numeric and boolean field as a series named \u003cmeasurement\u003e_\u003cfield\u003e labelled with the tags, e.g. for Telegraf's influxdb and influxdb_v2 outputs. The db, org and bucket parameters are ignored. Available in server mode with an ingest token, sent as \"Token \u003ctoken\u003e\", bearer token, basic auth password or the p parameter.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Ingest"
+                ],
+                "summary": "InfluxDB line protocol write",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Timestamp precision: ns (default), us, ms, s; the v1 API also accepts n, u, m and h",
+                        "name": "precision",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Line protocol",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.AggregateResponse": {
+            "type": "object",
+            "properties": {
+                "fn": {
+                    "type": "string"
+                },
+                "metric": {
+                    "type": "string"
+                },
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Point"
+                    }
+                },
+                "step": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AvgMetrics": {
+            "type": "object",
+            "properties": {
+                "cpu_percent": {
+                    "type": "number"
+                },
+                "mem_percent": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.Host": {
+            "type": "object",
+            "properties": {
+                "agent_version": {
+                    "type": "string"
+                },
+                "arch": {
+                    "type": "string"
+                },
+                "cpu_count": {
+                    "type": "integer"
+                },
+                "cpu_model": {
+                    "type": "string"
+                },
+                "first_seen": {
+                    "type": "string"
+                },
+                "hostname": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kernel": {
+                    "type": "string"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "last_seen": {
+                    "type": "string"
+                },
+                "os": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "platform_version": {
+                    "type": "string"
+                },
+                "stale": {
+                    "type": "boolean"
+                },
+                "total_memory": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.HostInfo": {
+            "type": "object",
+            "properties": {
+                "agent_version": {
+                    "type": "string"
+                },
+                "arch": {
+                    "type": "string"
+                },
+                "cpu_count": {
+                    "type": "integer"
+                },
+                "cpu_model": {
+                    "type": "string"
+                },
+                "kernel": {
+                    "type": "string"
+                },
+                "os": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "platform_version": {
+                    "type": "string"
+                },
+                "total_memory": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.HostLabelsRequest": {
+            "type": "object",
+            "required": [
+                "labels"
+            ],
+            "properties": {
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "models.IngestBatch": {
+            "type": "object",
+            "required": [
+                "host"
+            ],
+            "properties": {
+                "host": {
+                    "type": "string"
+                },
+                "host_info": {
+                    "$ref": "#/definitions/models.HostInfo"
+                },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "metrics": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Metrics"
+                    }
+                },
+                "samples": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SeriesSample"
+                    }
+                }
+            }
+        },
+        "models.IngestResponse": {
+            "type": "object",
+            "properties": {
+                "accepted": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Metrics": {
+            "type": "object",
+            "properties": {
+                "cpu_percent": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "mem_percent": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.MetricsStats": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "cpu_percent": {
+                    "$ref": "#/definitions/models.SeriesStats"
+                },
+                "first_at": {
+                    "type": "string"
+                },
+                "last_at": {
+                    "type": "string"
+                },
+                "mem_percent": {
+                    "$ref": "#/definitions/models.SeriesStats"
+                }
+            }
+        },
+        "models.Point": {
+            "type": "object",
+            "properties": {
+                "time": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.PromResponse": {
+            "type": "object",
+            "properties": {
+                "data": {},
+                "error": {
+                    "type": "string"
+                },
+                "errorType": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RetentionRun": {
+            "type": "object",
+            "properties": {
+                "batches": {
+                    "type": "integer"
+                },
+                "cutoff": {
+                    "type": "string"
+                },
+                "deleted": {
+                    "type": "integer"
+                },
+                "dropped_partitions": {
+                    "type": "integer"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "retention": {
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "target": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.RetentionStatus": {
+            "type": "object",
+            "properties": {
+                "batch_size": {
+                    "type": "integer"
+                },
+                "default": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "interval": {
+                    "type": "string"
+                },
+                "last_runs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.RetentionRun"
+                    }
+                },
+                "overrides": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "total_deleted": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.SeriesSample": {
+            "type": "object",
+            "properties": {
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ts": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
+        "models.SeriesStats": {
+            "type": "object",
+            "properties": {
+                "max": {
+                    "type": "number"
+                },
+                "mean": {
+                    "type": "number"
+                },
+                "min": {
+                    "type": "number"
+                },
+                "p50": {
+                    "type": "number"
+                },
+                "p90": {
+                    "type": "number"
+                },
+                "p95": {
+                    "type": "number"
+                },
+                "p99": {
+                    "type": "number"
+                },
+                "stddev": {
+                    "type": "number"
+                }
+            }
+        },
+        "otlp.ExportMetricsPartialSuccess": {
+            "type": "object",
+            "properties": {
+                "errorMessage": {
+                    "type": "string"
+                },
+                "rejectedDataPoints": {
+                    "type": "integer"
+                }
+            }
+        },
+        "otlp.ExportMetricsServiceResponse": {
+            "type": "object",
+            "properties": {
+                "partialSuccess": {
+                    "$ref": "#/definitions/otlp.ExportMetricsPartialSuccess"
                 }
             }
         }
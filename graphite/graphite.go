@@ -0,0 +1,204 @@
+// Package graphite parses the Graphite plaintext protocol,
+//
+//	servers.web-01.cpu.load 0.42 1700000000
+//	cpu.load;host=web-01;dc=eu 0.42 1700000000
+//
+// and maps dotted paths to metric names and labels with templates.
+package graphite
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Metric struct {
+	Path  string
+	Tags  map[string]string
+	Value float64
+	Time  time.Time
+}
+
+// ParseLine parses one "path value [timestamp]" line. A missing or negative
+// timestamp means now.
+func ParseLine(line string, now time.Time) (Metric, error) {
+	m := Metric{Time: now}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return m, fmt.Errorf("invalid line %q, expected path value [timestamp]", line)
+	}
+
+	parts := strings.Split(fields[0], ";")
+	m.Path = parts[0]
+	if m.Path == "" {
+		return m, fmt.Errorf("invalid line %q: empty path", line)
+	}
+	for _, tag := range parts[1:] {
+		k, v, ok := strings.Cut(tag, "=")
+		if !ok || k == "" || v == "" {
+			return m, fmt.Errorf("invalid line %q: invalid tag %q", line, tag)
+		}
+		if m.Tags == nil {
+			m.Tags = make(map[string]string)
+		}
+		m.Tags[k] = v
+	}
+
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return m, fmt.Errorf("invalid line %q: invalid value %q", line, fields[1])
+	}
+	m.Value = value
+
+	if len(fields) == 3 {
+		ts, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return m, fmt.Errorf("invalid line %q: invalid timestamp %q", line, fields[2])
+		}
+		if ts >= 0 {
+			m.Time = time.Unix(0, int64(ts*float64(time.Second))).UTC()
+		}
+	}
+	return m, nil
+}
+
+// Template maps the components of matching paths to a metric name and
+// labels. It is written as "[filter] template [label=value,...]":
+//
+//	servers.* .host.name* env=prod
+//
+// Each template component names what the path component in its position
+// is: "name" is part of the metric name, "name*" makes the remaining
+// components part of it too, an empty component is skipped and any other
+// word is a label. The metric name parts are joined with underscores. The
+// filter matches paths starting with its components, which may contain
+// wildcards such as "*" or "web-*".
+type Template struct {
+	filter []string
+	parts  []string
+	labels map[string]string
+}
+
+// ParseTemplate parses a single template.
+func ParseTemplate(s string) (Template, error) {
+	var t Template
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 1:
+		t.parts = strings.Split(fields[0], ".")
+	case 2, 3:
+		if strings.Contains(fields[1], "=") {
+			t.parts = strings.Split(fields[0], ".")
+			fields = append([]string{""}, fields...)
+		} else {
+			t.filter = strings.Split(fields[0], ".")
+			t.parts = strings.Split(fields[1], ".")
+		}
+		if len(fields) == 3 {
+			t.labels = make(map[string]string)
+			for _, pair := range strings.Split(fields[2], ",") {
+				k, v, ok := strings.Cut(pair, "=")
+				if !ok || k == "" {
+					return t, fmt.Errorf("invalid template %q: invalid label %q", s, pair)
+				}
+				t.labels[k] = v
+			}
+		}
+	default:
+		return t, fmt.Errorf("invalid template %q, expected [filter] template [label=value,...]", s)
+	}
+
+	for _, f := range t.filter {
+		if _, err := path.Match(f, ""); err != nil {
+			return t, fmt.Errorf("invalid template %q: invalid filter: %w", s, err)
+		}
+	}
+	hasName := false
+	for _, p := range t.parts {
+		hasName = hasName || p == "name" || p == "name*"
+	}
+	if !hasName {
+		return t, fmt.Errorf("invalid template %q: no name component", s)
+	}
+	return t, nil
+}
+
+// Matches reports whether path is mapped by t.
+func (t Template) Matches(p string) bool {
+	components := strings.Split(p, ".")
+	if len(t.filter) > len(components) {
+		return false
+	}
+	for i, f := range t.filter {
+		if ok, _ := path.Match(f, components[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply maps path to a metric name and labels. Components beyond the
+// template are dropped unless it ends in "name*".
+func (t Template) Apply(p string) (string, map[string]string) {
+	components := strings.Split(p, ".")
+	labels := make(map[string]string, len(t.labels))
+	for k, v := range t.labels {
+		labels[k] = v
+	}
+
+	var name []string
+	for i, part := range t.parts {
+		if i >= len(components) {
+			break
+		}
+		switch part {
+		case "":
+		case "name":
+			name = append(name, components[i])
+		case "name*":
+			name = append(name, components[i:]...)
+		default:
+			labels[part] = components[i]
+		}
+		if part == "name*" {
+			break
+		}
+	}
+	if len(name) == 0 {
+		name = components
+	}
+	return strings.Join(name, "_"), labels
+}
+
+// Templates are tried in order; paths that no template matches become the
+// metric name as a whole.
+type Templates []Template
+
+// ParseTemplates parses semicolon separated templates.
+func ParseTemplates(s string) (Templates, error) {
+	var ts Templates
+	for _, spec := range strings.Split(s, ";") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		t, err := ParseTemplate(spec)
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+func (ts Templates) Apply(p string) (string, map[string]string) {
+	for _, t := range ts {
+		if t.Matches(p) {
+			return t.Apply(p)
+		}
+	}
+	return strings.ReplaceAll(p, ".", "_"), map[string]string{}
+}
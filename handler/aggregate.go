@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/service"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AggregateMetrics godoc
+// @Summary Aggregate a metric into fixed width buckets
+// @Description Returns one point per step wide bucket between start and end, buckets starting at start. Empty buckets are left out. rate, irate and increase apply to counter series and handle counter resets.
+// @Tags Metrics
+// @Produce json
+// @Param metric query string true "cpu_percent, mem_percent or the name of a stored series, e.g. node_network_receive_bytes_total"
+// @Param start query string true "Start timestamp (RFC3339 format)"
+// @Param end query string true "End timestamp (RFC3339 format)"
+// @Param step query string true "Bucket width, e.g. 30s, 5m, 1h or 1d"
+// @Param fn query string false "Aggregation function" Enums(avg, min, max, sum, count, last, rate, irate, increase) default(avg)
+// @Param host query string false "Only metrics of this host"
+// @Param label query []string false "Only hosts with this label, as key=value; repeat to require several" collectionFormat(multi)
+// @Success 200 {object} models.AggregateResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /metrics/aggregate [get]
+func AggregateMetrics(c *gin.Context) {
+	logger.Log.Debug("AggregateMetrics handler")
+
+	start, err := utils.ParseTime(c.Query("start"))
+	if err != nil {
+		logger.Log.Error("Start time parsing error", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid start time format",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	end, err := utils.ParseTime(c.Query("end"))
+	if err != nil {
+		logger.Log.Error("End time parsing error", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid end time format",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "end must not be before start",
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	step, err := utils.ParseDuration(c.Query("step"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid step",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	filter, err := metricsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid label filter",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	query := models.AggregateQuery{
+		Metric: c.Query("metric"),
+		Func:   c.DefaultQuery("fn", "avg"),
+		Start:  start.UTC(),
+		End:    end.UTC(),
+		Step:   step,
+		Filter: filter,
+	}
+	if err := service.ValidateAggregateQuery(query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid query",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	points, err := service.AggregateMetrics(context.Background(), query)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownMetric) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"Message": "Invalid query",
+				"Error":   err.Error(),
+				"time":    time.Now().UTC(),
+			})
+			return
+		}
+		logger.Log.Error("AggregateMetrics error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"Message": err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	if points == nil {
+		points = []models.Point{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": models.AggregateResponse{
+			Metric: query.Metric,
+			Func:   query.Func,
+			Step:   step.String(),
+			Points: points,
+		},
+		"time": time.Now().UTC(),
+	})
+}
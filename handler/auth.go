@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/config"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequireIngestToken only lets requests through that carry one of the
+// configured INGEST_TOKENS as a bearer token.
+func RequireIngestToken() gin.HandlerFunc {
+	return requireToken(func(c *gin.Context) string {
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			return token
+		}
+		return ""
+	})
+}
+
+// RequireInfluxToken is RequireIngestToken for InfluxDB clients, which send
+// the token as "Token <token>" (v2), as the password of basic auth or of the
+// p query parameter (v1), or as a bearer token.
+func RequireInfluxToken() gin.HandlerFunc {
+	return requireToken(func(c *gin.Context) string {
+		auth := c.GetHeader("Authorization")
+		if token, ok := strings.CutPrefix(auth, "Token "); ok {
+			return token
+		}
+		if token, ok := strings.CutPrefix(auth, "Bearer "); ok {
+			return token
+		}
+		if _, password, ok := c.Request.BasicAuth(); ok {
+			return password
+		}
+		return c.Query("p")
+	})
+}
+
+// requireToken checks the token that extract finds in the request against
+// INGEST_TOKENS.
+func requireToken(extract func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if config.Cfg.Mode != config.ModeServer {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"message": "Ingestion is only available in server mode",
+				"time":    time.Now().UTC(),
+			})
+			return
+		}
+
+		if token := extract(c); token != "" {
+			for _, allowed := range config.Cfg.IngestTokens {
+				if subtle.ConstantTimeCompare([]byte(token), []byte(allowed)) == 1 {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		logger.Log.Warn("Rejected ingest request", zap.String("client", c.ClientIP()))
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"message": "Invalid or missing ingest token",
+			"time":    time.Now().UTC(),
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/chart"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/service"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// chartMaxHosts bounds the lines of a chart over all hosts.
+	chartMaxHosts = 10
+	// chartPoints is the number of points per line when no step is given.
+	chartPoints = 300
+)
+
+// RenderChart godoc
+// @Summary Render a metric as a PNG or SVG line chart
+// @Description Aggregates the metric like /metrics/aggregate and draws it with axes and a legend, one line per host (at most 10) or only the given host. The image can be linked from chat messages, tickets and email reports.
+// @Tags Metrics
+// @Produce image/svg+xml
+// @Produce image/png
+// @Param metric query string true "cpu_percent, mem_percent or the name of a stored series"
+// @Param start query string false "Start timestamp (RFC3339 format), default one hour before end"
+// @Param end query string false "End timestamp (RFC3339 format), default now"
+// @Param step query string false "Bucket width, e.g. 30s, 5m or 1h, default (end - start) / 300"
+// @Param fn query string false "Aggregation function" Enums(avg, min, max, sum, count, last, rate, irate, increase) default(avg)
+// @Param host query string false "Only this host"
+// @Param label query []string false "Only hosts with this label, as key=value; repeat to require several" collectionFormat(multi)
+// @Param format query string false "Image format" Enums(svg, png) default(svg)
+// @Param width query int false "Width in pixels, 200 to 2000" default(800)
+// @Param height query int false "Height in pixels, 150 to 1500" default(400)
+// @Param title query string false "Chart title, default fn(metric)"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /metrics/chart [get]
+func RenderChart(c *gin.Context) {
+	logger.Log.Debug("RenderChart handler")
+
+	badRequest := func(message string, err error) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": message,
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+	}
+
+	end := time.Now().UTC()
+	if value := c.Query("end"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			badRequest("Invalid end time format", err)
+			return
+		}
+		end = t.UTC()
+	}
+	start := end.Add(-time.Hour)
+	if value := c.Query("start"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			badRequest("Invalid start time format", err)
+			return
+		}
+		start = t.UTC()
+	}
+	if !end.After(start) {
+		badRequest("Invalid time range", errors.New("end must be after start"))
+		return
+	}
+
+	step := (end.Sub(start) / chartPoints).Truncate(time.Second) + time.Second
+	if value := c.Query("step"); value != "" {
+		var err error
+		if step, err = utils.ParseDuration(value); err != nil {
+			badRequest("Invalid step", err)
+			return
+		}
+	}
+
+	format := c.DefaultQuery("format", "svg")
+	if format != "svg" && format != "png" {
+		badRequest("Invalid format", errors.New("expected svg or png"))
+		return
+	}
+	width, err := sizeParam(c, "width", 800, 200, 2000)
+	if err != nil {
+		badRequest("Invalid width", err)
+		return
+	}
+	height, err := sizeParam(c, "height", 400, 150, 1500)
+	if err != nil {
+		badRequest("Invalid height", err)
+		return
+	}
+
+	filter, err := metricsFilter(c)
+	if err != nil {
+		badRequest("Invalid label filter", err)
+		return
+	}
+
+	query := models.AggregateQuery{
+		Metric: c.Query("metric"),
+		Func:   c.DefaultQuery("fn", "avg"),
+		Start:  start,
+		End:    end,
+		Step:   step,
+		Filter: filter,
+	}
+	if err := service.ValidateAggregateQuery(query); err != nil {
+		badRequest("Invalid query", err)
+		return
+	}
+
+	hosts, err := service.AggregateByHost(c.Request.Context(), query, chartMaxHosts)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownMetric) {
+			badRequest("Invalid query", err)
+			return
+		}
+		logger.Log.Error("RenderChart error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"Message": err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	title := c.Query("title")
+	if title == "" {
+		title = fmt.Sprintf("%s(%s)", query.Func, query.Metric)
+	}
+	ch := chart.Chart{
+		Title:  title,
+		Unit:   chartUnit(query.Metric, query.Func),
+		Width:  width,
+		Height: height,
+		Start:  start,
+		End:    end,
+		Step:   step,
+	}
+	for _, h := range hosts {
+		ch.Series = append(ch.Series, chart.Series{Name: h.Host, Points: h.Points})
+	}
+
+	var buf bytes.Buffer
+	contentType := "image/svg+xml"
+	if format == "png" {
+		contentType = "image/png"
+		err = ch.PNG(&buf)
+	} else {
+		err = ch.SVG(&buf)
+	}
+	if err != nil {
+		logger.Log.Error("RenderChart error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"Message": err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// chartUnit picks the value axis unit from the metric name. Counts are
+// unitless whatever the metric measures.
+func chartUnit(metric, fn string) string {
+	switch {
+	case fn == "count":
+		return chart.UnitNone
+	case strings.HasSuffix(metric, "_percent"):
+		return chart.UnitPercent
+	case strings.Contains(metric, "_bytes"):
+		return chart.UnitBytes
+	}
+	return chart.UnitNone
+}
+
+func sizeParam(c *gin.Context, name string, def, lo, hi int) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("expected a number from %d to %d", lo, hi)
+	}
+	return n, nil
+}
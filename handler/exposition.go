@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/promql"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	contentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// PrometheusExposition godoc
+// @Summary Latest samples in Prometheus exposition format
+// @Description Latest value of every series that reported in the last 5 minutes plus Metrics-Monitor's own metrics, for Prometheus to scrape. Served as OpenMetrics when the Accept header asks for application/openmetrics-text. The path is set by PROMETHEUS_PATH.
+// @Tags Prometheus
+// @Produce plain
+// @Success 200 {string} string
+// @Failure 500 {object} map[string]string
+// @Router /prometheus/metrics [get]
+func PrometheusExposition(c *gin.Context) {
+	logger.Log.Debug("PrometheusExposition handler")
+
+	families, err := service.Exposition(c.Request.Context())
+	if err != nil {
+		logger.Log.Error("PrometheusExposition error", zap.Error(err))
+		c.String(http.StatusInternalServerError, "%s", err.Error())
+		return
+	}
+
+	openMetrics := strings.Contains(c.GetHeader("Accept"), "application/openmetrics-text")
+	var buf bytes.Buffer
+	writeExposition(&buf, families, openMetrics)
+
+	contentType := contentTypeText
+	if openMetrics {
+		contentType = contentTypeOpenMetrics
+	}
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// writeExposition writes families in the Prometheus text format, or in
+// OpenMetrics, which names counter families without their _total suffix,
+// has timestamps in seconds and ends with # EOF.
+func writeExposition(buf *bytes.Buffer, families []models.MetricFamily, openMetrics bool) {
+	for _, f := range families {
+		name, typ := f.Name, f.Type
+		switch {
+		case openMetrics && typ == models.MetricTypeCounter:
+			name = strings.TrimSuffix(name, "_total")
+		case !openMetrics && typ == models.MetricTypeUnknown:
+			typ = "untyped"
+		}
+		if f.Help != "" {
+			fmt.Fprintf(buf, "# HELP %s %s\n", name, escapeHelp(f.Help))
+		}
+		fmt.Fprintf(buf, "# TYPE %s %s\n", name, typ)
+
+		for _, s := range f.Samples {
+			buf.WriteString(f.Name)
+			writeLabels(buf, s.Labels)
+			buf.WriteByte(' ')
+			buf.WriteString(promql.FormatValue(s.Value))
+			if s.Timestamp != nil {
+				if openMetrics {
+					fmt.Fprintf(buf, " %s", strconv.FormatFloat(float64(s.Timestamp.UnixMilli())/1000, 'f', -1, 64))
+				} else {
+					fmt.Fprintf(buf, " %d", s.Timestamp.UnixMilli())
+				}
+			}
+			buf.WriteByte('\n')
+		}
+	}
+	if openMetrics {
+		buf.WriteString("# EOF\n")
+	}
+}
+
+func writeLabels(buf *bytes.Buffer, labels map[string]string) {
+	if len(labels) == 0 {
+		return
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(buf, `%s="%s"`, name, escapeLabelValue(labels[name]))
+	}
+	buf.WriteByte('}')
+}
+
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabelValue(s string) string { return labelValueEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
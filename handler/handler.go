@@ -2,11 +2,14 @@ package handler
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/service"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/utils"
 	"github.com/gin-gonic/gin"
@@ -21,7 +24,10 @@ import (
 // @Produce  json
 // @Param page query int false "Page number" default(1)
 // @Param pageSize query int false "Number of items per page" default(10)
+// @Param host query string false "Only metrics of this host"
+// @Param label query []string false "Only hosts with this label, as key=value; repeat to require several" collectionFormat(multi)
 // @Success 200 {array} models.Metrics
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /metrics/ [get]
 func GetAllMetrics(c *gin.Context) {
@@ -39,7 +45,17 @@ func GetAllMetrics(c *gin.Context) {
 	}
 	offset := (page - 1) * pageSize
 
-	response, totalRecords, err := service.GetAllMetrics(context.Background(), pageSize, offset)
+	filter, err := metricsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid label filter",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	response, totalRecords, err := service.GetAllMetrics(context.Background(), pageSize, offset, filter)
 	if err != nil {
 		logger.Log.Error("GetAllMetrics error", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -67,12 +83,15 @@ func GetAllMetrics(c *gin.Context) {
 
 // GetMetricsByTimeRange godoc
 // @Summary Get Metrics By Time Range
-// @Description Fetch metrics collected between start and end timestamps.
+// @Description Fetch metrics collected between start and end timestamps. Long ranges are read from the rollup that keeps about ROLLUP_MAX_POINTS points, one metric per bucket holding its averages, unless resolution is raw. When resolution is set to a duration or auto, the data holds models.MetricsRollup rows of the chosen tier instead. The response names the tier in resolution.
 // @Tags Metrics
 // @Accept json
 // @Produce json
 // @Param start query string true "Start timestamp (RFC3339 format, e.g., 2025-02-22T00:00:00Z)"
 // @Param end query string true "End timestamp (RFC3339 format, e.g., 2025-02-22T23:59:59Z)"
+// @Param host query string false "Only metrics of this host"
+// @Param label query []string false "Only hosts with this label, as key=value; repeat to require several" collectionFormat(multi)
+// @Param resolution query string false "Wanted spacing between points (e.g. 1m, 1h, 1d), auto to derive it from the range, or raw for every raw metric. Picks the coarsest rollup that satisfies it"
 // @Success 200 {array} models.Metrics
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
@@ -106,7 +125,69 @@ func GetMetricsByTimeRange(c *gin.Context) {
 		return
 	}
 
-	response, err := service.GetMetricsByTimeRange(context.Background(), parsedStartTime, parsedEndTime)
+	filter, err := metricsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid label filter",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	// Without a resolution long ranges are still read from a rollup, but in
+	// the shape of raw metrics.
+	var resolution time.Duration
+	res, explicit := c.GetQuery("resolution")
+	if !explicit {
+		res = "auto"
+	}
+	if res != "raw" && res != "auto" {
+		resolution, err = utils.ParseDuration(res)
+		if err != nil || resolution <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"Message": "Invalid resolution",
+				"time":    time.Now().UTC(),
+			})
+			return
+		}
+	}
+
+	if res != "raw" {
+		if tier, ok := service.SelectRollupTier(parsedStartTime, parsedEndTime, resolution); ok {
+			rollups, err := service.GetRollupMetrics(context.Background(), tier, parsedStartTime, parsedEndTime, filter)
+			if err != nil {
+				logger.Log.Error("GetMetricsByTimeRange error", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"Message": err.Error(),
+					"time":    time.Now().UTC(),
+				})
+				return
+			}
+
+			if len(rollups) == 0 {
+				logger.Log.Warn("No metrics found in the given time range")
+				c.JSON(http.StatusNotFound, gin.H{
+					"message": "No metrics found in the given time range",
+					"time":    time.Now().UTC(),
+				})
+				return
+			}
+
+			var data interface{} = rollups
+			if !explicit {
+				data = service.RollupsAsMetrics(rollups)
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"data":       data,
+				"resolution": tier.Width.String(),
+				"time":       time.Now().UTC(),
+			})
+			return
+		}
+	}
+
+	response, err := service.GetMetricsByTimeRange(context.Background(), parsedStartTime, parsedEndTime, filter)
 	if err != nil {
 		logger.Log.Error("GetMetricsByTimeRange error", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -126,8 +207,9 @@ func GetMetricsByTimeRange(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-		"time": time.Now().UTC(),
+		"data":       response,
+		"resolution": "raw",
+		"time":       time.Now().UTC(),
 	})
 }
 
@@ -139,6 +221,9 @@ func GetMetricsByTimeRange(c *gin.Context) {
 // @Produce json
 // @Param start query string true "Start timestamp (RFC3339 format)"
 // @Param end query string true "End timestamp (RFC3339 format)"
+// @Param host query string false "Only metrics of this host"
+// @Param label query []string false "Only hosts with this label, as key=value; repeat to require several" collectionFormat(multi)
+// @Param group_by query string false "Host label to average per value of, e.g. team"
 // @Success 200 {object} models.AvgMetrics
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
@@ -171,7 +256,44 @@ func GetAverageMetrics(c *gin.Context) {
 		return
 	}
 
-	response, err := service.GetAverageMetrics(context.Background(), parsedStartTime, parsedEndTime)
+	filter, err := metricsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid label filter",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	if label := c.Query("group_by"); label != "" {
+		groups, err := service.GetAverageMetricsByLabel(context.Background(), parsedStartTime, parsedEndTime, filter, label)
+		if err != nil {
+			logger.Log.Error("GetAverageMetrics error", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"Error": err.Error(),
+				"time":  time.Now().UTC(),
+			})
+			return
+		}
+
+		if len(groups) == 0 {
+			logger.Log.Warn("No metrics found in the given time range")
+			c.JSON(http.StatusNotFound, gin.H{
+				"message": "No metrics found in the given time range",
+				"time":    time.Now().UTC(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data": groups,
+			"time": time.Now().UTC(),
+		})
+		return
+	}
+
+	response, err := service.GetAverageMetrics(context.Background(), parsedStartTime, parsedEndTime, filter)
 	if err != nil {
 		logger.Log.Error("GetAverageMetrics error", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -196,6 +318,98 @@ func GetAverageMetrics(c *gin.Context) {
 	})
 }
 
+// GetMetricsStats godoc
+// @Summary Get statistics of CPU and memory usage in a time range
+// @Description Min, max, mean, standard deviation and the 50th, 90th, 95th and 99th percentiles of CPU and memory usage between start and end, with the sample count and the first and last sample times
+// @Tags Metrics
+// @Accept json
+// @Produce json
+// @Param start query string true "Start timestamp (RFC3339 format)"
+// @Param end query string true "End timestamp (RFC3339 format)"
+// @Param host query string false "Only metrics of this host"
+// @Param label query []string false "Only hosts with this label, as key=value; repeat to require several" collectionFormat(multi)
+// @Success 200 {object} models.MetricsStats
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /metrics/stats [get]
+func GetMetricsStats(c *gin.Context) {
+	logger.Log.Debug("GetMetricsStats handler")
+
+	parsedStartTime, err := utils.ParseTime(c.Query("start"))
+	if err != nil {
+		logger.Log.Error("Start time parsing error", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid start time format",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	parsedEndTime, err := utils.ParseTime(c.Query("end"))
+	if err != nil {
+		logger.Log.Error("End time parsing error", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid end time format",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	filter, err := metricsFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid label filter",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	response, err := service.GetMetricsStats(context.Background(), parsedStartTime, parsedEndTime, filter)
+	if err != nil {
+		logger.Log.Error("GetMetricsStats error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"Error": err.Error(),
+			"time":  time.Now().UTC(),
+		})
+		return
+	}
+
+	if response.Count == 0 {
+		logger.Log.Warn("No metrics found in the given time range")
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "No metrics found in the given time range",
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": response,
+		"time": time.Now().UTC(),
+	})
+}
+
+// metricsFilter reads the optional host and label=key=value filters of the
+// /metrics endpoints.
+func metricsFilter(c *gin.Context) (models.MetricsFilter, error) {
+	filter := models.MetricsFilter{Host: c.Query("host")}
+	for _, label := range c.QueryArray("label") {
+		key, value, ok := strings.Cut(label, "=")
+		if !ok || key == "" {
+			return filter, fmt.Errorf("invalid label filter %q, expected key=value", label)
+		}
+		if filter.Labels == nil {
+			filter.Labels = make(map[string]string)
+		}
+		filter.Labels[key] = value
+	}
+	return filter, nil
+}
+
 // HealthCheck godoc
 // @Summary Check service health
 // @Description Returns service status
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetHosts godoc
+// @Summary List monitored hosts
+// @Description Returns the inventory of every host that reported, flagging hosts that stopped reporting as stale
+// @Tags Hosts
+// @Produce json
+// @Success 200 {array} models.Host
+// @Failure 500 {object} map[string]string
+// @Router /hosts [get]
+func GetHosts(c *gin.Context) {
+	logger.Log.Debug("GetHosts handler")
+
+	hosts, err := service.GetHosts(c.Request.Context())
+	if err != nil {
+		logger.Log.Error("GetHosts error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"Message": err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": hosts,
+		"time": time.Now().UTC(),
+	})
+}
+
+// GetHost godoc
+// @Summary Get one monitored host
+// @Description Returns the inventory of a host looked up by id or host name
+// @Tags Hosts
+// @Produce json
+// @Param id path string true "Host id or host name"
+// @Success 200 {object} models.Host
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /hosts/{id} [get]
+func GetHost(c *gin.Context) {
+	logger.Log.Debug("GetHost handler")
+
+	host, err := service.GetHost(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, service.ErrHostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "Host not found",
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+	if err != nil {
+		logger.Log.Error("GetHost error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"Message": err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": host,
+		"time": time.Now().UTC(),
+	})
+}
+
+// SetHostLabels godoc
+// @Summary Set the labels of a host
+// @Description Replaces the labels managed through the API. They take precedence over labels configured on the agent. Requires server mode and a bearer token from INGEST_TOKENS.
+// @Tags Hosts
+// @Accept json
+// @Produce json
+// @Param id path string true "Host id or host name"
+// @Param labels body models.HostLabelsRequest true "Labels to set"
+// @Success 200 {object} models.Host
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /hosts/{id}/labels [put]
+func SetHostLabels(c *gin.Context) {
+	logger.Log.Debug("SetHostLabels handler")
+
+	var req models.HostLabelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid labels",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+	for key := range req.Labels {
+		if key == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"Message": "Label keys must not be empty",
+				"time":    time.Now().UTC(),
+			})
+			return
+		}
+	}
+
+	host, err := service.SetHostLabels(c.Request.Context(), c.Param("id"), req.Labels)
+	if errors.Is(err, service.ErrHostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "Host not found",
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+	if err != nil {
+		logger.Log.Error("SetHostLabels error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"Message": err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": host,
+		"time": time.Now().UTC(),
+	})
+}
+
+// DeleteHostLabel godoc
+// @Summary Remove a label from a host
+// @Description Requires server mode and a bearer token from INGEST_TOKENS.
+// @Tags Hosts
+// @Produce json
+// @Param id path string true "Host id or host name"
+// @Param key path string true "Label key"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /hosts/{id}/labels/{key} [delete]
+func DeleteHostLabel(c *gin.Context) {
+	logger.Log.Debug("DeleteHostLabel handler")
+
+	err := service.DeleteHostLabel(c.Request.Context(), c.Param("id"), c.Param("key"))
+	if errors.Is(err, service.ErrHostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"message": "Host not found",
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+	if err != nil {
+		logger.Log.Error("DeleteHostLabel error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"Message": err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
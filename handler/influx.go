@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/influx"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// InfluxWrite godoc
+// @Summary InfluxDB line protocol write
+// @Description Stores every numeric and boolean field as a series named <measurement>_<field> labelled with the tags, e.g. for Telegraf's influxdb and influxdb_v2 outputs. The db, org and bucket parameters are ignored. Available in server mode with an ingest token, sent as "Token <token>", bearer token, basic auth password or the p parameter.
+// @Tags Ingest
+// @Accept plain
+// @Param precision query string false "Timestamp precision: ns (default), us, ms, s; the v1 API also accepts n, u, m and h"
+// @Param body body string true "Line protocol"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /write [post]
+// @Router /api/v2/write [post]
+func InfluxWrite(c *gin.Context) {
+	logger.Log.Debug("InfluxWrite handler")
+
+	precision, err := influx.ParsePrecision(c.Query("precision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid precision",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	var points []influx.Point
+	body, err := readGzipBody(c)
+	if err == nil {
+		points, err = influx.Parse(body, precision, time.Now().UTC())
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid line protocol",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	if _, err := service.IngestInflux(c.Request.Context(), points); err != nil {
+		logger.Log.Error("InfluxWrite error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"Message": err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// IngestMetrics godoc
+// @Summary Ingest metrics pushed by an agent
+// @Description Stores a batch of samples labelled with the reporting host. Requires server mode and a bearer token from INGEST_TOKENS.
+// @Tags Ingest
+// @Accept json
+// @Produce json
+// @Param batch body models.IngestBatch true "Samples collected by the agent"
+// @Success 202 {object} models.IngestResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /ingest [post]
+func IngestMetrics(c *gin.Context) {
+	logger.Log.Debug("IngestMetrics handler")
+
+	var batch models.IngestBatch
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		logger.Log.Error("Invalid ingest batch", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid ingest batch",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	accepted, err := service.IngestMetrics(c.Request.Context(), batch)
+	if errors.Is(err, service.ErrBatchTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"Message": err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+	if err != nil {
+		logger.Log.Error("IngestMetrics error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"Message": err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"data": models.IngestResponse{Accepted: accepted},
+		"time": time.Now().UTC(),
+	})
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/otlp"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeJSON     = "application/json"
+)
+
+// OTLPMetrics godoc
+// @Summary OTLP/HTTP metrics receiver
+// @Description Stores the gauge and cumulative sum and histogram data points of an OpenTelemetry ExportMetricsServiceRequest, protobuf or JSON encoded and optionally gzip compressed. Resource attributes become series labels, host.name becomes the host label. Available in server mode with an ingest token.
+// @Tags OpenTelemetry
+// @Accept application/x-protobuf
+// @Accept json
+// @Produce application/x-protobuf
+// @Produce json
+// @Param Authorization header string true "Bearer <ingest token>"
+// @Success 200 {object} otlp.ExportMetricsServiceResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /v1/metrics [post]
+func OTLPMetrics(c *gin.Context) {
+	logger.Log.Debug("OTLPMetrics handler")
+
+	contentType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if contentType != contentTypeProtobuf && contentType != contentTypeJSON {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"Message": "Unsupported content type, expected application/x-protobuf or application/json",
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	var req otlp.ExportMetricsServiceRequest
+	body, err := readGzipBody(c)
+	if err == nil {
+		if contentType == contentTypeJSON {
+			err = json.Unmarshal(body, &req)
+		} else {
+			err = req.Unmarshal(body)
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid OTLP request",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	_, rejected, err := service.IngestOTLP(c.Request.Context(), &req)
+	if err != nil {
+		logger.Log.Error("OTLPMetrics error", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"Message": err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	var resp otlp.ExportMetricsServiceResponse
+	if rejected > 0 {
+		resp.PartialSuccess = &otlp.ExportMetricsPartialSuccess{
+			RejectedDataPoints: otlp.Int64(rejected),
+			ErrorMessage:       "exponential histograms, summaries, delta temporality, unnamed metrics and histogram points without one more bucket count than bounds are not supported",
+		}
+	}
+	if contentType == contentTypeJSON {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	c.Data(http.StatusOK, contentTypeProtobuf, resp.Marshal())
+}
+
+// readGzipBody reads the request body, decompressing it when it is gzip
+// encoded.
+func readGzipBody(c *gin.Context) ([]byte, error) {
+	var body io.Reader = http.MaxBytesReader(c.Writer, c.Request.Body, maxRemoteBodySize)
+	switch encoding := c.GetHeader("Content-Encoding"); encoding {
+	case "", "identity":
+	case "gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		body = io.LimitReader(zr, maxRemoteBodySize+1)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxRemoteBodySize {
+		return nil, fmt.Errorf("decompressed body exceeds %d bytes", maxRemoteBodySize)
+	}
+	return b, nil
+}
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/promql"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	promErrorBadData   = "bad_data"
+	promErrorExecution = "execution"
+	promErrorInternal  = "internal"
+)
+
+// PromQuery godoc
+// @Summary Evaluate a PromQL instant query
+// @Description Prometheus HTTP API compatible instant query over a PromQL subset: selectors with label matchers, range vectors, rate, irate, increase and *_over_time functions, sum/avg/min/max/count by or without labels, and arithmetic. cpu_percent and mem_percent are available as series with a host label.
+// @Tags Prometheus
+// @Produce json
+// @Param query query string true "PromQL expression, e.g. avg by (host) (rate(node_network_receive_bytes_total[5m]))"
+// @Param time query string false "Evaluation time as RFC3339 or Unix seconds, defaults to now"
+// @Success 200 {object} models.PromResponse
+// @Failure 400 {object} models.PromResponse
+// @Failure 422 {object} models.PromResponse
+// @Router /api/v1/query [get]
+// @Router /api/v1/query [post]
+func PromQuery(c *gin.Context) {
+	logger.Log.Debug("PromQuery handler")
+
+	query := promParam(c, "query")
+	if _, err := promql.ParseExpr(query); err != nil {
+		promError(c, http.StatusBadRequest, promErrorBadData, err)
+		return
+	}
+
+	ts := time.Now()
+	if value := promParam(c, "time"); value != "" {
+		var err error
+		if ts, err = parsePromTime(value); err != nil {
+			promError(c, http.StatusBadRequest, promErrorBadData, fmt.Errorf("invalid parameter \"time\": %w", err))
+			return
+		}
+	}
+
+	result, err := service.QueryEngine.Instant(c.Request.Context(), query, ts)
+	if err != nil {
+		logger.Log.Error("PromQuery error", zap.String("query", query), zap.Error(err))
+		promError(c, http.StatusUnprocessableEntity, promErrorExecution, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PromResponse{Status: "success", Data: promQueryData(result)})
+}
+
+// PromQueryRange godoc
+// @Summary Evaluate a PromQL range query
+// @Description Prometheus HTTP API compatible range query, evaluating the expression at every step between start and end.
+// @Tags Prometheus
+// @Produce json
+// @Param query query string true "PromQL expression"
+// @Param start query string true "Start time as RFC3339 or Unix seconds"
+// @Param end query string true "End time as RFC3339 or Unix seconds"
+// @Param step query string true "Step as a duration (e.g. 15s, 1m) or seconds"
+// @Success 200 {object} models.PromResponse
+// @Failure 400 {object} models.PromResponse
+// @Failure 422 {object} models.PromResponse
+// @Router /api/v1/query_range [get]
+// @Router /api/v1/query_range [post]
+func PromQueryRange(c *gin.Context) {
+	logger.Log.Debug("PromQueryRange handler")
+
+	query := promParam(c, "query")
+	if _, err := promql.ParseExpr(query); err != nil {
+		promError(c, http.StatusBadRequest, promErrorBadData, err)
+		return
+	}
+
+	start, err := parsePromTime(promParam(c, "start"))
+	if err != nil {
+		promError(c, http.StatusBadRequest, promErrorBadData, fmt.Errorf("invalid parameter \"start\": %w", err))
+		return
+	}
+	end, err := parsePromTime(promParam(c, "end"))
+	if err != nil {
+		promError(c, http.StatusBadRequest, promErrorBadData, fmt.Errorf("invalid parameter \"end\": %w", err))
+		return
+	}
+	if end.Before(start) {
+		promError(c, http.StatusBadRequest, promErrorBadData, errors.New("end timestamp must not be before start time"))
+		return
+	}
+	step, err := promql.ParseDuration(promParam(c, "step"))
+	if err != nil {
+		promError(c, http.StatusBadRequest, promErrorBadData, fmt.Errorf("invalid parameter \"step\": %w", err))
+		return
+	}
+	if end.Sub(start)/step > promql.MaxPoints {
+		promError(c, http.StatusBadRequest, promErrorBadData, promql.ErrTooManyPoints)
+		return
+	}
+
+	result, err := service.QueryEngine.Range(c.Request.Context(), query, start, end, step)
+	if err != nil {
+		logger.Log.Error("PromQueryRange error", zap.String("query", query), zap.Error(err))
+		promError(c, http.StatusUnprocessableEntity, promErrorExecution, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PromResponse{Status: "success", Data: promQueryData(result)})
+}
+
+// PromLabels godoc
+// @Summary List label names
+// @Tags Prometheus
+// @Produce json
+// @Success 200 {object} models.PromResponse
+// @Failure 500 {object} models.PromResponse
+// @Router /api/v1/labels [get]
+func PromLabels(c *gin.Context) {
+	names, err := service.LabelNames(c.Request.Context())
+	if err != nil {
+		logger.Log.Error("PromLabels error", zap.Error(err))
+		promError(c, http.StatusInternalServerError, promErrorInternal, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.PromResponse{Status: "success", Data: names})
+}
+
+// PromLabelValues godoc
+// @Summary List the values of a label
+// @Tags Prometheus
+// @Produce json
+// @Param name path string true "Label name, __name__ for metric names"
+// @Success 200 {object} models.PromResponse
+// @Failure 500 {object} models.PromResponse
+// @Router /api/v1/label/{name}/values [get]
+func PromLabelValues(c *gin.Context) {
+	values, err := service.LabelValues(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		logger.Log.Error("PromLabelValues error", zap.Error(err))
+		promError(c, http.StatusInternalServerError, promErrorInternal, err)
+		return
+	}
+	c.JSON(http.StatusOK, models.PromResponse{Status: "success", Data: values})
+}
+
+// promParam reads a parameter from the query string or a form body, as
+// Prometheus clients may POST their queries.
+func promParam(c *gin.Context, name string) string {
+	if value, ok := c.GetQuery(name); ok {
+		return value
+	}
+	return c.PostForm(name)
+}
+
+// parsePromTime reads a Unix timestamp in seconds or an RFC3339 time.
+func parsePromTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		whole, frac := math.Modf(seconds)
+		return time.Unix(int64(whole), int64(frac*1e9)).UTC(), nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+func promError(c *gin.Context, status int, errorType string, err error) {
+	c.JSON(status, models.PromResponse{Status: "error", ErrorType: errorType, Error: err.Error()})
+}
+
+func promQueryData(value promql.Value) models.PromQueryData {
+	data := models.PromQueryData{ResultType: string(value.Type())}
+	switch v := value.(type) {
+	case promql.Scalar:
+		data.Result = promPoint(promql.Point(v))
+	case promql.Vector:
+		result := make([]models.PromSample, 0, len(v))
+		for _, s := range v {
+			result = append(result, models.PromSample{Metric: promMetric(s.Labels), Value: promPoint(s.Point)})
+		}
+		data.Result = result
+	case promql.Matrix:
+		result := make([]models.PromSeries, 0, len(v))
+		for _, s := range v {
+			values := make([][2]interface{}, 0, len(s.Points))
+			for _, p := range s.Points {
+				values = append(values, promPoint(p))
+			}
+			result = append(result, models.PromSeries{Metric: promMetric(s.Labels), Values: values})
+		}
+		data.Result = result
+	}
+	return data
+}
+
+func promPoint(p promql.Point) [2]interface{} {
+	return [2]interface{}{float64(p.T) / 1000, promql.FormatValue(p.V)}
+}
+
+func promMetric(labels promql.Labels) map[string]string {
+	metric := make(map[string]string, len(labels))
+	for name, value := range labels {
+		if value != "" {
+			metric[name] = value
+		}
+	}
+	return metric
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/prompb"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/service"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/snappy"
+	"go.uber.org/zap"
+)
+
+// maxRemoteBodySize bounds remote write and read bodies, compressed and
+// decompressed.
+const maxRemoteBodySize = 32 << 20
+
+// RemoteWrite godoc
+// @Summary Prometheus remote_write receiver
+// @Description Stores the samples of a snappy compressed protobuf WriteRequest with their labels. Available in server mode with an ingest token, e.g. remote_write url http://host:8888/api/v1/write with authorization credentials set to the token.
+// @Tags Prometheus
+// @Accept application/x-protobuf
+// @Param Authorization header string true "Bearer <ingest token>"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/write [post]
+func RemoteWrite(c *gin.Context) {
+	logger.Log.Debug("RemoteWrite handler")
+
+	body, err := readSnappyBody(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid remote write request",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	var req prompb.WriteRequest
+	if err := req.Unmarshal(body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid remote write request",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	if _, err := service.IngestRemoteWrite(c.Request.Context(), &req); err != nil {
+		// 4xx tells Prometheus not to retry the batch.
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrMissingMetricName) {
+			status = http.StatusBadRequest
+		}
+		logger.Log.Error("RemoteWrite error", zap.Error(err))
+		c.JSON(status, gin.H{
+			"Message": err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RemoteRead godoc
+// @Summary Prometheus remote_read endpoint
+// @Description Answers a snappy compressed protobuf ReadRequest with the raw samples of the matching series, including cpu_percent and mem_percent. Only the SAMPLES response type is supported.
+// @Tags Prometheus
+// @Accept application/x-protobuf
+// @Produce application/x-protobuf
+// @Success 200
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /api/v1/read [post]
+func RemoteRead(c *gin.Context) {
+	logger.Log.Debug("RemoteRead handler")
+
+	var req prompb.ReadRequest
+	body, err := readSnappyBody(c)
+	if err == nil {
+		err = req.Unmarshal(body)
+	}
+	if err == nil && !acceptsSamples(req.AcceptedResponseTypes) {
+		err = errors.New("only the SAMPLES response type is supported")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"Message": "Invalid remote read request",
+			"Error":   err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	resp, err := service.RemoteRead(c.Request.Context(), &req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidMatcher) {
+			status = http.StatusBadRequest
+		}
+		logger.Log.Error("RemoteRead error", zap.Error(err))
+		c.JSON(status, gin.H{
+			"Message": err.Error(),
+			"time":    time.Now().UTC(),
+		})
+		return
+	}
+
+	c.Header("Content-Encoding", "snappy")
+	c.Data(http.StatusOK, "application/x-protobuf", snappy.Encode(nil, resp.Marshal()))
+}
+
+func acceptsSamples(types []prompb.ResponseType) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if t == prompb.ResponseSamples {
+			return true
+		}
+	}
+	return false
+}
+
+// readSnappyBody reads and decompresses a snappy block encoded body.
+func readSnappyBody(c *gin.Context) ([]byte, error) {
+	compressed, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxRemoteBodySize))
+	if err != nil {
+		return nil, err
+	}
+	size, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxRemoteBodySize {
+		return nil, fmt.Errorf("decompressed body of %d bytes exceeds %d bytes", size, maxRemoteBodySize)
+	}
+	return snappy.Decode(nil, compressed)
+}
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GetRetentionStatus godoc
+// @Summary Get retention policy status
+// @Description Returns the configured retention policy and the rows deleted by the last pruning run of each metric
+// @Tags Retention
+// @Produce json
+// @Success 200 {object} models.RetentionStatus
+// @Router /retention [get]
+func GetRetentionStatus(c *gin.Context) {
+	logger.Log.Debug("GetRetentionStatus handler")
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": service.GetRetentionStatus(),
+		"time": time.Now().UTC(),
+	})
+}
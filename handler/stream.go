@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"io"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// streamKeepAlive is how often an idle stream sends a comment, so proxies do
+// not close the connection.
+const streamKeepAlive = 15 * time.Second
+
+// StreamMetrics godoc
+// @Summary Live stream of new samples
+// @Description Pushes every newly stored sample, collected locally or ingested, as a Server-Sent Event named "sample" whose data is the sample as JSON. A client that falls behind misses samples.
+// @Tags Metrics
+// @Produce text/event-stream
+// @Param name query []string false "Only samples of this metric; repeat for several" collectionFormat(multi)
+// @Param host query []string false "Only samples of this host; repeat for several" collectionFormat(multi)
+// @Success 200 {string} string
+// @Router /metrics/stream [get]
+func StreamMetrics(c *gin.Context) {
+	logger.Log.Debug("StreamMetrics handler")
+
+	sub := service.Subscribe(service.StreamFilter{Names: c.QueryArray("name"), Hosts: c.QueryArray("host")})
+	defer func() {
+		if dropped := sub.Close(); dropped > 0 {
+			logger.Log.Warn("Stream client fell behind", zap.String("client", c.ClientIP()), zap.Int64("dropped", dropped))
+		}
+	}()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	// Confirms the subscription before the first sample arrives
+	io.WriteString(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case sample := <-sub.C:
+			c.SSEvent("sample", sample)
+			return true
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
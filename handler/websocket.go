@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/service"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	// wsMaxSubscriptions bounds the subscriptions of one connection.
+	wsMaxSubscriptions = 100
+	// wsPingInterval is how often the server pings; a client that does not
+	// answer within wsPongTimeout is disconnected.
+	wsPingInterval = 30 * time.Second
+	wsPongTimeout  = 60 * time.Second
+	wsWriteTimeout = 10 * time.Second
+	// wsMaxMessageSize bounds a control message.
+	wsMaxMessageSize = 64 << 10
+	// wsDefaultStep is the backfill step when a subscription names none.
+	wsDefaultStep = time.Minute
+)
+
+var wsUpgrader = websocket.Upgrader{ReadBufferSize: 4096, WriteBufferSize: 4096}
+
+// MetricsWebSocket godoc
+// @Summary Subscribe to live samples over a WebSocket
+// @Description Clients send {"type":"subscribe","id":"cpu","selector":"cpu_percent{host=~\"web-.*\"}","backfill":"15m","step":"1m"} and {"type":"unsubscribe","id":"cpu"}. The server answers with "subscribed", "unsubscribed" or "error" messages, sends the optional backfill of at most 24h averaged into step wide points as one "backfill" message and then every new matching sample as a "sample" message, each carrying the subscription id.
+// @Tags Metrics
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 400 {object} map[string]string
+// @Router /metrics/ws [get]
+func MetricsWebSocket(c *gin.Context) {
+	logger.Log.Debug("MetricsWebSocket handler")
+
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already answered the request
+		logger.Log.Warn("WebSocket upgrade failed", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	session := &wsSession{conn: conn, ctx: ctx, out: make(chan models.StreamMessage, 256), subs: map[string]*wsSubscription{}}
+	go session.writeLoop(cancel)
+	defer session.wg.Wait()
+	defer session.unsubscribeAll()
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+
+	for {
+		var req models.StreamRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) && ctx.Err() == nil {
+				logger.Log.Debug("WebSocket closed", zap.Error(err))
+			}
+			return
+		}
+		switch req.Type {
+		case "subscribe":
+			session.subscribe(req)
+		case "unsubscribe":
+			session.unsubscribe(req.ID)
+		default:
+			session.send(models.StreamMessage{Type: "error", ID: req.ID, Error: "unknown message type, expected subscribe or unsubscribe"})
+		}
+	}
+}
+
+// wsSession is one WebSocket connection. Only writeLoop writes to the
+// connection; everything else queues messages on out.
+type wsSession struct {
+	conn *websocket.Conn
+	ctx  context.Context
+	out  chan models.StreamMessage
+	wg   sync.WaitGroup
+
+	mu   sync.Mutex
+	subs map[string]*wsSubscription
+}
+
+type wsSubscription struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (s *wsSession) send(msg models.StreamMessage) bool {
+	select {
+	case s.out <- msg:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+func (s *wsSession) writeLoop(cancel context.CancelFunc) {
+	defer cancel()
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case msg := <-s.out:
+			s.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := s.conn.WriteJSON(msg); err != nil {
+				logger.Log.Debug("WebSocket write failed", zap.Error(err))
+				s.conn.Close()
+				return
+			}
+		case <-ping.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				s.conn.Close()
+				return
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *wsSession) subscribe(req models.StreamRequest) {
+	fail := func(err error) {
+		s.send(models.StreamMessage{Type: "error", ID: req.ID, Error: err.Error()})
+	}
+	if req.ID == "" {
+		fail(errors.New("missing subscription id"))
+		return
+	}
+	matchers, err := service.ParseSelector(req.Selector)
+	if err != nil {
+		fail(err)
+		return
+	}
+	var backfill, step time.Duration
+	if req.Backfill != "" {
+		if backfill, err = utils.ParseDuration(req.Backfill); err != nil {
+			fail(errors.New("invalid backfill, expected a duration such as 15m"))
+			return
+		}
+		step = wsDefaultStep
+		if req.Step != "" {
+			if step, err = utils.ParseDuration(req.Step); err != nil {
+				fail(errors.New("invalid step, expected a duration such as 1m"))
+				return
+			}
+		}
+	}
+
+	s.mu.Lock()
+	if _, ok := s.subs[req.ID]; ok {
+		s.mu.Unlock()
+		fail(errors.New("subscription id already in use"))
+		return
+	}
+	if len(s.subs) >= wsMaxSubscriptions {
+		s.mu.Unlock()
+		fail(errors.New("too many subscriptions"))
+		return
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	ws := &wsSubscription{cancel: cancel, done: make(chan struct{})}
+	s.subs[req.ID] = ws
+	s.mu.Unlock()
+
+	// Subscribing before the backfill is read leaves no gap between the two.
+	sub := service.Subscribe(service.StreamFilter{Matchers: matchers})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(ws.done)
+		defer sub.Close()
+
+		if !s.send(models.StreamMessage{Type: "subscribed", ID: req.ID}) {
+			return
+		}
+		if backfill > 0 {
+			series, err := service.StreamBackfill(ctx, matchers, backfill, step)
+			if err != nil {
+				logger.Log.Warn("WebSocket backfill failed", zap.String("id", req.ID), zap.Error(err))
+				fail(err)
+			} else if !s.send(models.StreamMessage{Type: "backfill", ID: req.ID, Series: series}) {
+				return
+			}
+		}
+		for {
+			select {
+			case sample := <-sub.C:
+				select {
+				case s.out <- models.StreamMessage{Type: "sample", ID: req.ID, Sample: &sample}:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (s *wsSession) unsubscribe(id string) {
+	s.mu.Lock()
+	ws, ok := s.subs[id]
+	delete(s.subs, id)
+	s.mu.Unlock()
+
+	if !ok {
+		s.send(models.StreamMessage{Type: "error", ID: id, Error: "unknown subscription id"})
+		return
+	}
+	// No sample of the subscription may follow the confirmation.
+	ws.cancel()
+	<-ws.done
+	s.send(models.StreamMessage{Type: "unsubscribed", ID: id})
+}
+
+func (s *wsSession) unsubscribeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, ws := range s.subs {
+		ws.cancel()
+		delete(s.subs, id)
+	}
+}
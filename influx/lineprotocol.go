@@ -0,0 +1,200 @@
+// Package influx parses the InfluxDB line protocol:
+//
+//	measurement,tag=value field=1.5,count=3i,ok=true,msg="text" 1700000000000000000
+package influx
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Point struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      []Field
+	Time        time.Time
+}
+
+// Field is a field of a point. Value is a float64, int64, uint64, bool or
+// string.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+var precisions = map[string]time.Duration{
+	"":   time.Nanosecond,
+	"n":  time.Nanosecond,
+	"ns": time.Nanosecond,
+	"u":  time.Microsecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+}
+
+// ParsePrecision parses the precision parameter of the v1 and v2 write APIs.
+// The default is nanoseconds.
+func ParsePrecision(s string) (time.Duration, error) {
+	p, ok := precisions[s]
+	if !ok {
+		return 0, fmt.Errorf("invalid precision %q", s)
+	}
+	return p, nil
+}
+
+// Parse parses the points in data. Timestamps are counted in units of
+// precision; points without one get now. Empty lines and comments are
+// skipped.
+func Parse(data []byte, precision time.Duration, now time.Time) ([]Point, error) {
+	var points []Point
+	for n, line := range bytes.Split(data, []byte("\n")) {
+		s := strings.TrimSpace(string(line))
+		if s == "" || s[0] == '#' {
+			continue
+		}
+		p, err := parseLine(s, precision, now)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+func parseLine(line string, precision time.Duration, now time.Time) (Point, error) {
+	p := Point{Time: now}
+
+	end := scan(line, 0, ' ', false)
+	key := splitUnescaped(line[:end], ',', false)
+	p.Measurement = unescape(key[0], ",= ")
+	if p.Measurement == "" {
+		return p, fmt.Errorf("missing measurement")
+	}
+	for _, tag := range key[1:] {
+		k, v, err := splitKeyValue(tag)
+		if err != nil {
+			return p, fmt.Errorf("invalid tag %q: %w", tag, err)
+		}
+		if p.Tags == nil {
+			p.Tags = make(map[string]string)
+		}
+		p.Tags[k] = unescape(v, ",= ")
+	}
+
+	rest := strings.TrimLeft(line[end:], " ")
+	end = scan(rest, 0, ' ', true)
+	if end == 0 {
+		return p, fmt.Errorf("missing fields")
+	}
+	for _, field := range splitUnescaped(rest[:end], ',', true) {
+		k, v, err := splitKeyValue(field)
+		if err != nil {
+			return p, fmt.Errorf("invalid field %q: %w", field, err)
+		}
+		value, err := parseFieldValue(v)
+		if err != nil {
+			return p, fmt.Errorf("invalid value of field %q: %w", k, err)
+		}
+		p.Fields = append(p.Fields, Field{Key: k, Value: value})
+	}
+
+	if ts := strings.TrimSpace(rest[end:]); ts != "" {
+		n, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return p, fmt.Errorf("invalid timestamp %q", ts)
+		}
+		p.Time = time.Unix(0, n*int64(precision)).UTC()
+	}
+	return p, nil
+}
+
+func splitKeyValue(s string) (string, string, error) {
+	i := scan(s, 0, '=', false)
+	if i == len(s) {
+		return "", "", fmt.Errorf("missing =")
+	}
+	key := unescape(s[:i], ",= ")
+	if key == "" {
+		return "", "", fmt.Errorf("empty key")
+	}
+	return key, s[i+1:], nil
+}
+
+func parseFieldValue(v string) (interface{}, error) {
+	switch {
+	case v == "":
+		return nil, fmt.Errorf("empty value")
+	case v[0] == '"':
+		if len(v) < 2 || v[len(v)-1] != '"' {
+			return nil, fmt.Errorf("unterminated string")
+		}
+		return unescape(v[1:len(v)-1], `"\`), nil
+	case v == "t" || v == "T" || v == "true" || v == "True" || v == "TRUE":
+		return true, nil
+	case v == "f" || v == "F" || v == "false" || v == "False" || v == "FALSE":
+		return false, nil
+	case strings.HasSuffix(v, "i"):
+		return strconv.ParseInt(v[:len(v)-1], 10, 64)
+	case strings.HasSuffix(v, "u"):
+		return strconv.ParseUint(v[:len(v)-1], 10, 64)
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return nil, err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("%q is not a finite number", v)
+	}
+	return f, nil
+}
+
+// scan returns the index of the first unescaped sep in s at or after i, or
+// len(s). With quoted set, separators inside double quoted strings are
+// skipped too.
+func scan(s string, i int, sep byte, quoted bool) int {
+	inQuotes := false
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case quoted && c == '"':
+			inQuotes = !inQuotes
+		case c == sep && !inQuotes:
+			return i
+		}
+	}
+	return len(s)
+}
+
+func splitUnescaped(s string, sep byte, quoted bool) []string {
+	var parts []string
+	for {
+		i := scan(s, 0, sep, quoted)
+		parts = append(parts, s[:i])
+		if i == len(s) {
+			return parts
+		}
+		s = s[i+1:]
+	}
+}
+
+// unescape removes the backslashes in front of the characters in escaped.
+func unescape(s, escaped string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(escaped, s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,66 @@
+// Package protoutil holds the protobuf wire helpers shared by the hand
+// written prompb and otlp codecs.
+package protoutil
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ErrTruncated is returned when a field value runs past the end of a message.
+var ErrTruncated = errors.New("truncated message")
+
+// DecodeFields calls field for every field of a message. field returns how
+// many bytes of b the field value took, or a negative protowire error code.
+func DecodeFields(b []byte, field func(protowire.Number, protowire.Type, []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		n, err := field(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if n > len(b) {
+			return ErrTruncated
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// SkipField consumes the value of a field the decoder does not know.
+func SkipField(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+	return protowire.ConsumeFieldValue(num, typ, b), nil
+}
+
+// AppendMessage appends msg as a length delimited field.
+func AppendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+// AppendVarint appends v as a varint field, omitting it when zero.
+func AppendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// AppendString appends s as a length delimited field, omitting it when empty.
+func AppendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
@@ -2,25 +2,47 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/config"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/database"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/router"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/service"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
+// sinkShutdownTimeout bounds how long shutdown waits for queued sink batches.
+const sinkShutdownTimeout = 10 * time.Second
+
 func main() {
 	logger.InitLogger()
 	cfg := config.LoadConfig()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(cfg, os.Args[2:]))
+	}
+	if cfg.Mode == config.ModeAgent {
+		runAgent(cfg)
+		return
+	}
 	database.InitDB(cfg)
+	errChan := make(chan error, 10)
+	if err := service.StartSinks(cfg, errChan); err != nil {
+		logger.Log.Fatal("Failed to start sinks", zap.Error(err))
+	}
+	if err := service.RegisterLocalHost(context.Background()); err != nil {
+		logger.Log.Error("Failed to register local host", zap.Error(err))
+	}
 	r := gin.Default()
 	router.SetRouter(r)
 
@@ -30,12 +52,73 @@ func main() {
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	// Outputs fed by the sinks stop after the sinks are drained.
+	outputCtx, cancelOutputs := context.WithCancel(context.Background())
 
-	errChan := make(chan error, 10)
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	go service.MetricsCollector(ctx, cfg.MetricsInterval, errChan)
+	var workers, outputs sync.WaitGroup
+	if cfg.OTLPEndpoint != "" {
+		outputs.Add(1)
+		go func() {
+			defer outputs.Done()
+			service.OTLPExporter(outputCtx, cfg, errChan)
+		}()
+	}
+
+	if cfg.GraphiteOutputAddr != "" {
+		outputs.Add(1)
+		go func() {
+			defer outputs.Done()
+			service.GraphiteOutput(outputCtx, cfg, errChan)
+		}()
+	}
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		service.MetricsCollector(ctx, cfg.MetricsInterval, errChan)
+	}()
+
+	if cfg.StatsDUDPAddr != "" || cfg.StatsDTCPAddr != "" {
+		if _, _, err := service.StartStatsD(ctx, cfg); err != nil {
+			logger.Log.Fatal("Failed to start StatsD listener", zap.Error(err))
+		}
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			service.StatsDFlusher(ctx, cfg, errChan)
+		}()
+	}
+
+	if cfg.GraphiteAddr != "" {
+		if _, err := service.StartGraphite(ctx, cfg, errChan); err != nil {
+			logger.Log.Fatal("Failed to start Graphite listener", zap.Error(err))
+		}
+	}
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		service.RollupScheduler(ctx, cfg, errChan)
+	}()
+
+	if database.Partitioned {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			service.PartitionMaintainer(ctx, cfg, errChan)
+		}()
+	}
+
+	if service.RetentionEnabled(cfg) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			service.RetentionPruner(ctx, cfg, errChan)
+		}()
+	}
 
 	go func() {
 		for err := range errChan {
@@ -55,9 +138,13 @@ func main() {
 	sig := <-sigChan
 	logger.Log.Info("Received termination signal", zap.String("signal", sig.String()))
 
-	// Stop Metrics Collector gracefully
+	// Stop Metrics Collector gracefully, then write what the sinks still have
+	// queued and flush the outputs
 	cancel()
-	time.Sleep(1 * time.Second)
+	workers.Wait()
+	stopSinks()
+	cancelOutputs()
+	outputs.Wait()
 	close(errChan)
 
 	// Close Database
@@ -82,3 +169,161 @@ func main() {
 	time.Sleep(1 * time.Second)
 	os.Exit(0)
 }
+
+// runAgent collects metrics without a local database or API and pushes them
+// to the server at cfg.AgentServerURL, exports them to cfg.OTLPEndpoint,
+// sends them to cfg.GraphiteOutputAddr and writes them to cfg.Sinks, whichever
+// are set.
+func runAgent(cfg *models.Config) {
+	errChan := make(chan error, 10)
+	if err := service.StartSinks(cfg, errChan); err != nil {
+		logger.Log.Fatal("Failed to start sinks", zap.Error(err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// Outputs fed by the sinks stop after the sinks are drained.
+	outputCtx, cancelOutputs := context.WithCancel(context.Background())
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	var collectors, outputs sync.WaitGroup
+	collectors.Add(1)
+	go func() {
+		defer collectors.Done()
+		service.MetricsCollector(ctx, cfg.MetricsInterval, errChan)
+	}()
+	if cfg.StatsDUDPAddr != "" || cfg.StatsDTCPAddr != "" {
+		if _, _, err := service.StartStatsD(ctx, cfg); err != nil {
+			logger.Log.Fatal("Failed to start StatsD listener", zap.Error(err))
+		}
+		collectors.Add(1)
+		go func() {
+			defer collectors.Done()
+			service.StatsDFlusher(ctx, cfg, errChan)
+		}()
+	}
+	if cfg.GraphiteAddr != "" {
+		if _, err := service.StartGraphite(ctx, cfg, errChan); err != nil {
+			logger.Log.Fatal("Failed to start Graphite listener", zap.Error(err))
+		}
+	}
+	if cfg.GraphiteOutputAddr != "" {
+		outputs.Add(1)
+		go func() {
+			defer outputs.Done()
+			service.GraphiteOutput(outputCtx, cfg, errChan)
+		}()
+	}
+	if cfg.AgentServerURL != "" {
+		outputs.Add(1)
+		go func() {
+			defer outputs.Done()
+			service.AgentPusher(outputCtx, cfg, errChan)
+		}()
+	}
+	if cfg.OTLPEndpoint != "" {
+		outputs.Add(1)
+		go func() {
+			defer outputs.Done()
+			service.OTLPExporter(outputCtx, cfg, errChan)
+		}()
+	}
+
+	go func() {
+		for err := range errChan {
+			logger.Log.Error("Agent error", zap.Error(err))
+		}
+	}()
+
+	logger.Log.Info("Starting agent", zap.String("host", cfg.Hostname), zap.String("server", cfg.AgentServerURL), zap.String("otlp_endpoint", cfg.OTLPEndpoint))
+
+	sig := <-sigChan
+	logger.Log.Info("Received termination signal", zap.String("signal", sig.String()))
+
+	// Stop collecting and push what is left
+	cancel()
+	collectors.Wait()
+	stopSinks()
+	cancelOutputs()
+	outputs.Wait()
+	time.Sleep(1 * time.Second)
+	close(errChan)
+
+	logger.Log.Info("Agent stopped")
+}
+
+// stopSinks writes what the sinks still have queued, giving up on a failing
+// sink after sinkShutdownTimeout so it cannot hold up the exit.
+func stopSinks() {
+	ctx, cancel := context.WithTimeout(context.Background(), sinkShutdownTimeout)
+	defer cancel()
+	service.StopSinks(ctx)
+}
+
+// runMigrate implements `metrics-monitor migrate up|down [steps]|status`.
+func runMigrate(cfg *models.Config, args []string) int {
+	database.Connect(cfg)
+	ctx := context.Background()
+
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := database.MigrateUp(ctx)
+		if err != nil {
+			logger.Log.Error("Migration failed", zap.Error(err))
+			return 1
+		}
+		fmt.Printf("applied %d migration(s)\n", applied)
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				fmt.Fprintln(os.Stderr, "usage: metrics-monitor migrate down [steps]")
+				return 2
+			}
+			steps = n
+		}
+		reverted, err := database.MigrateDown(ctx, steps)
+		if err != nil {
+			logger.Log.Error("Migration failed", zap.Error(err))
+			return 1
+		}
+		fmt.Printf("reverted %d migration(s)\n", reverted)
+
+	case "status":
+		states, err := database.MigrationStatus(ctx)
+		if err != nil {
+			logger.Log.Error("Reading migration status failed", zap.Error(err))
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, state := range states {
+			appliedAt := "pending"
+			if state.AppliedAt != nil {
+				appliedAt = state.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			name := state.Name
+			if name == "" {
+				name = "(unknown to this binary)"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", state.Version, name, appliedAt)
+		}
+		w.Flush()
+		if err := database.CheckSchemaVersion(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, "usage: metrics-monitor migrate up|down [steps]|status")
+		return 2
+	}
+	return 0
+}
@@ -1,22 +1,39 @@
 package main_test
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/config"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/database"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/otlp"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/prompb"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/router"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/service"
 	"github.com/gin-gonic/gin"
+	"github.com/golang/snappy"
 	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 	"gorm.io/driver/sqlite"
@@ -33,7 +50,10 @@ func setupTestDB() {
 	}
 
 	// AutoMigrate necessary models
-	_ = db.AutoMigrate(&models.Metrics{}) // Ensure this model is correct
+	_ = db.AutoMigrate(&models.Metrics{}, &models.Host{}, &models.HostLabel{}, &models.Series{}, &models.SeriesLabel{}, &models.Sample{}) // Ensure this model is correct
+	for _, table := range []string{models.RollupTable1m, models.RollupTable1h, models.RollupTable1d} {
+		_ = db.Table(table).AutoMigrate(&models.MetricsRollup{})
+	}
 
 	db.Create(&models.Metrics{
 		ID:         uuid.New(),
@@ -43,6 +63,7 @@ func setupTestDB() {
 
 	// Assign test database
 	database.DB = db
+	service.ResetSeriesCache()
 }
 
 func TestGetMetrics(t *testing.T) {
@@ -153,6 +174,56 @@ func TestGetAverageMetrics(t *testing.T) {
 	assert.NotZero(t, response.Data.MemPercent, "MemPercent should not be zero")
 }
 
+func TestGetAverageMetricsTimescale(t *testing.T) {
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	// A plain table stands in for the continuous aggregate
+	assert.NoError(t, database.DB.Exec("CREATE TABLE "+database.TimescaleCaggHourly+
+		" (bucket DATETIME, host TEXT, cpu_sum REAL, mem_sum REAL, sample_count INTEGER)").Error)
+	base := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, row := range []struct {
+		bucket   time.Time
+		host     string
+		cpu, mem float64
+		count    int64
+	}{
+		{base, "web-01", 1000, 1000, 1}, // before the first whole hour
+		{base.Add(time.Hour), "web-01", 100, 200, 2},
+		{base.Add(2 * time.Hour), "web-01", 60, 60, 1},
+		{base.Add(2 * time.Hour), "db-01", 1000, 1000, 1},
+	} {
+		assert.NoError(t, database.DB.Exec("INSERT INTO "+database.TimescaleCaggHourly+" VALUES (?, ?, ?, ?, ?)",
+			row.bucket, row.host, row.cpu, row.mem, row.count).Error)
+	}
+	database.DB.Create(&[]models.Metrics{
+		{ID: uuid.New(), Host: "web-01", CPUPercent: 20, MemPercent: 10, CreatedAt: base.Add(45 * time.Minute)},
+		{ID: uuid.New(), Host: "web-01", CPUPercent: 999, MemPercent: 999, CreatedAt: base.Add(90 * time.Minute)},
+		{ID: uuid.New(), Host: "web-01", CPUPercent: 40, MemPercent: 30, CreatedAt: base.Add(3*time.Hour + 10*time.Minute)},
+	})
+
+	ctx := context.Background()
+	start, end := base.Add(30*time.Minute), base.Add(3*time.Hour+15*time.Minute)
+	filter := models.MetricsFilter{Host: "web-01"}
+
+	avg, err := service.GetAverageMetrics(ctx, start, end, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, 353.0, avg.CPUPercent, "Expected raw rows without TimescaleDB")
+
+	defer func() { database.Timescale = false }()
+	database.Timescale = true
+	avg, err = service.GetAverageMetrics(ctx, start, end, filter)
+	assert.NoError(t, err)
+	assert.Equal(t, 44.0, avg.CPUPercent, "Expected whole hours from the aggregate and raw rows for the partial hours")
+	assert.Equal(t, 60.0, avg.MemPercent)
+
+	// Ranges within one hour only read raw rows
+	avg, err = service.GetAverageMetrics(ctx, base.Add(40*time.Minute), base.Add(50*time.Minute), filter)
+	assert.NoError(t, err)
+	assert.Equal(t, 20.0, avg.CPUPercent)
+}
+
 func TestCollectAndSaveMetrics_Success(t *testing.T) {
 	errChan := make(chan error, 1)
 	logger.Log, _ = zap.NewDevelopment()
@@ -204,7 +275,9 @@ func TestMetricsCollectorGracefulShutdown(t *testing.T) {
 	defer close(errChan)
 
 
+	stopped := make(chan struct{})
 	go func() {
+		defer close(stopped)
 		defer func() {
 			if r := recover(); r != nil {
 				t.Errorf("Recovered from panic: %v", r)
@@ -215,6 +288,7 @@ func TestMetricsCollectorGracefulShutdown(t *testing.T) {
 
 	time.Sleep(2 * time.Second)
 	cancel()
+	<-stopped
 
 	assert.True(t, true, "Metrics collector should exit cleanly")
 }
@@ -223,3 +297,1786 @@ func cleanupTestDB() {
 	// Drop tables after test to clean up
 	database.DB.Exec("DROP TABLE metrics;")
 }
+
+// queryTime formats t for the start and end parameters, which
+// utils.ParseTime reads as Indian Standard Time.
+func queryTime(t time.Time) string {
+	ist, _ := time.LoadLocation("Asia/Kolkata")
+	return url.QueryEscape(t.In(ist).Format(time.RFC3339))
+}
+
+func TestPruneExpiredMetrics(t *testing.T) {
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	now := time.Now().UTC()
+	database.DB.Create(&[]models.Metrics{
+		{ID: uuid.New(), CPUPercent: 11, MemPercent: 21, CreatedAt: now.Add(-72 * time.Hour)},
+		{ID: uuid.New(), CPUPercent: 12, MemPercent: 22, CreatedAt: now.Add(-48 * time.Hour)},
+		{ID: uuid.New(), CPUPercent: 13, MemPercent: 23, CreatedAt: now.Add(-1 * time.Hour)},
+	})
+	// Series sharing timestamps must still be deleted one batch at a time
+	var samples []models.SeriesSample
+	for _, host := range []string{"web-01", "web-02", "web-03"} {
+		for _, age := range []time.Duration{72 * time.Hour, 48 * time.Hour, time.Hour} {
+			samples = append(samples, models.SeriesSample{Name: "queue_depth", Labels: map[string]string{"host": host}, Timestamp: now.Add(-age), Value: 1})
+		}
+	}
+	assert.NoError(t, service.WriteSamples(context.Background(), samples))
+
+	cfg := &models.Config{
+		RetentionPeriod:    24 * time.Hour,
+		RetentionBatchSize: 1,
+	}
+	runs := service.PruneExpiredMetrics(context.Background(), cfg)
+
+	assert.NotEmpty(t, runs)
+	assert.Equal(t, "metrics", runs[0].Target)
+	assert.Empty(t, runs[0].Error)
+	assert.Equal(t, int64(2), runs[0].Deleted)
+	assert.Equal(t, 3, runs[0].Batches, "Expected one batch per row plus a final empty batch")
+	for _, run := range runs {
+		if run.Target == "samples" {
+			assert.Equal(t, int64(6), run.Deleted)
+			assert.Equal(t, 7, run.Batches, "Expected batches to be bounded by the batch size")
+		}
+	}
+
+	var count int64
+	database.DB.Model(&models.Metrics{}).Count(&count)
+	assert.Equal(t, int64(2), count, "Expected only recent metrics to remain")
+}
+
+func TestRollupsAndResolution(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	now := time.Now().UTC()
+	base := now.Truncate(time.Hour).Add(-2 * time.Hour)
+	database.DB.Create(&[]models.Metrics{
+		{ID: uuid.New(), CPUPercent: 10, MemPercent: 40, CreatedAt: base.Add(5 * time.Second)},
+		{ID: uuid.New(), CPUPercent: 30, MemPercent: 60, CreatedAt: base.Add(35 * time.Second)},
+		{ID: uuid.New(), CPUPercent: 50, MemPercent: 80, CreatedAt: base.Add(90 * time.Second)},
+	})
+
+	err := service.RunRollups(context.Background(), now)
+	assert.NoError(t, err)
+
+	var minutes []models.MetricsRollup
+	database.DB.Table(models.RollupTable1m).Order("bucket ASC").Find(&minutes)
+	assert.Len(t, minutes, 2)
+	assert.Equal(t, int64(2), minutes[0].SampleCount)
+	assert.Equal(t, 20.0, minutes[0].CPUAvg)
+	assert.Equal(t, 10.0, minutes[0].CPUMin)
+	assert.Equal(t, 30.0, minutes[0].CPUMax)
+	assert.Equal(t, 30.0, minutes[0].CPULast)
+
+	var hours []models.MetricsRollup
+	database.DB.Table(models.RollupTable1h).Find(&hours)
+	assert.Len(t, hours, 1)
+	assert.Equal(t, int64(3), hours[0].SampleCount)
+	assert.Equal(t, 30.0, hours[0].CPUAvg)
+	assert.Equal(t, 50.0, hours[0].CPULast)
+
+	tier, ok := service.SelectRollupTier(now.Add(-90*24*time.Hour), now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, models.RollupTable1h, tier.Name)
+	_, ok = service.SelectRollupTier(now.Add(-time.Hour), now, 0)
+	assert.False(t, ok, "Short ranges should be served from raw samples")
+
+	r := gin.Default()
+	router.SetRouter(r)
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/metrics?start=%s&end=%s&resolution=5m",
+		base.Add(-time.Hour).Format(time.RFC3339), now.Format(time.RFC3339)), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data       []models.MetricsRollup `json:"data"`
+		Resolution string                 `json:"resolution"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "1m0s", response.Resolution)
+	assert.Len(t, response.Data, 2)
+
+	// Long ranges are read from a rollup in the raw shape unless raw
+	// samples are asked for
+	params := fmt.Sprintf("/metrics?start=%s&end=%s", now.Add(-90*24*time.Hour).Format(time.RFC3339), now.Format(time.RFC3339))
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", params, nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var raw struct {
+		Data       []models.Metrics `json:"data"`
+		Resolution string           `json:"resolution"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &raw))
+	assert.Equal(t, "1h0m0s", raw.Resolution)
+	if assert.Len(t, raw.Data, 1) {
+		assert.Equal(t, 30.0, raw.Data[0].CPUPercent)
+		assert.True(t, hours[0].Bucket.Equal(raw.Data[0].CreatedAt))
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", params+"&resolution=raw", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &raw))
+	assert.Equal(t, "raw", raw.Resolution)
+	assert.Len(t, raw.Data, 3)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", params+"&resolution=auto", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "1h0m0s", response.Resolution)
+
+	// A sample pushed after its buckets were rolled up is rolled up on the
+	// next pass
+	_, err = service.IngestMetrics(context.Background(), models.IngestBatch{
+		Host:    "late-01",
+		Metrics: []models.Metrics{{CPUPercent: 70, MemPercent: 20, CreatedAt: base.Add(10 * time.Second)}},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, service.RunRollups(context.Background(), now))
+	var late []models.MetricsRollup
+	database.DB.Table(models.RollupTable1m).Where("host = ?", "late-01").Find(&late)
+	assert.Len(t, late, 1)
+	database.DB.Table(models.RollupTable1h).Where("host = ?", "late-01").Find(&late)
+	if assert.Len(t, late, 1) {
+		assert.Equal(t, 70.0, late[0].CPUAvg)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := database.LoadMigrations()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.Equal(t, int64(i+1), m.Version, "Expected migration versions without gaps")
+		assert.NotEmpty(t, m.Up, "Migration %d has no up script", m.Version)
+		assert.NotEmpty(t, m.Down, "Migration %d has no down script", m.Version)
+	}
+}
+
+func TestAgentPushesToServer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	defer func(cfg *models.Config, store func(models.Metrics) error, storeSamples func(context.Context, []models.SeriesSample) error) {
+		config.Cfg, service.StoreMetrics, service.StoreSamples = cfg, store, storeSamples
+	}(config.Cfg, service.StoreMetrics, service.StoreSamples)
+
+	config.Cfg = &models.Config{Mode: config.ModeServer, IngestTokens: []string{"secret"}, Hostname: "vm-01"}
+
+	r := gin.Default()
+	router.SetRouter(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	// Requests without a valid token are rejected
+	body, _ := json.Marshal(models.IngestBatch{Host: "vm-02", Metrics: []models.Metrics{{CPUPercent: 1, MemPercent: 2}}})
+	req, _ := http.NewRequest("POST", "/ingest", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer wrong")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	agentCfg := &models.Config{Mode: config.ModeAgent, AgentServerURL: server.URL, AgentToken: "secret", AgentBufferSize: 100}
+	errChan := make(chan error, 1)
+	assert.NoError(t, service.StartSinks(agentCfg, errChan))
+	defer service.StopSinks(context.Background())
+
+	service.CollectAndSaveMetrics(errChan)
+	service.CollectAndSaveMetrics(errChan)
+	service.CollectAndSaveCounters(errChan)
+	assert.Empty(t, errChan)
+	service.DrainSinks()
+
+	var count int64
+	database.DB.Model(&models.Metrics{}).Count(&count)
+	assert.Equal(t, int64(1), count, "Agent samples should be buffered, not stored locally")
+
+	err := service.FlushAgentBuffer(context.Background(), agentCfg)
+	assert.NoError(t, err)
+
+	database.DB.Model(&models.Metrics{}).Where("host = ?", "vm-01").Count(&count)
+	assert.Equal(t, int64(2), count, "Expected pushed samples to be stored with the agent host")
+
+	database.DB.Model(&models.Series{}).Where("name = ? AND host = ?", service.CounterContextSwitches, "vm-01").Count(&count)
+	assert.Equal(t, int64(1), count, "Expected pushed counters to be stored with the agent host")
+
+	// Samples collected while a push is in flight survive the buffer trimming
+	// the pushed ones
+	service.StopSinks(context.Background())
+	var pushed []models.Metrics
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch models.IngestBatch
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+		if len(pushed) == 0 {
+			for i := 0; i < 2; i++ {
+				assert.NoError(t, service.StoreMetrics(models.Metrics{ID: uuid.New(), CPUPercent: float64(10 + i)}))
+			}
+			service.DrainSinks()
+		}
+		pushed = append(pushed, batch.Metrics...)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer slow.Close()
+	slowCfg := &models.Config{Mode: config.ModeAgent, AgentServerURL: slow.URL, AgentBufferSize: 3}
+	assert.NoError(t, service.StartSinks(slowCfg, errChan))
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, service.StoreMetrics(models.Metrics{ID: uuid.New(), CPUPercent: float64(i)}))
+	}
+	service.DrainSinks()
+	assert.NoError(t, service.FlushAgentBuffer(context.Background(), slowCfg))
+	if assert.Len(t, pushed, 5) {
+		assert.Equal(t, []float64{10, 11}, []float64{pushed[3].CPUPercent, pushed[4].CPUPercent})
+	}
+}
+
+func TestHostRegistry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	defer func(cfg *models.Config) { config.Cfg = cfg }(config.Cfg)
+	config.Cfg = &models.Config{HostStaleAfter: time.Minute}
+
+	ctx := context.Background()
+	_, err := service.IngestMetrics(ctx, models.IngestBatch{
+		Host:     "db-01",
+		HostInfo: &models.HostInfo{OS: "linux", CPUCount: 8, AgentVersion: "1.0.0"},
+		Metrics:  []models.Metrics{{CPUPercent: 70, MemPercent: 80}},
+	})
+	assert.NoError(t, err)
+	// A heartbeat from an old agent without inventory keeps the known inventory
+	assert.NoError(t, service.TouchHost(ctx, "db-01", nil, time.Now().UTC()))
+	assert.NoError(t, service.TouchHost(ctx, "web-01", nil, time.Now().UTC().Add(-time.Hour)))
+
+	r := gin.Default()
+	router.SetRouter(r)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/hosts", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var list struct {
+		Data []models.Host `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Len(t, list.Data, 2)
+	assert.Equal(t, "db-01", list.Data[0].Hostname)
+	assert.Equal(t, 8, list.Data[0].CPUCount)
+	assert.False(t, list.Data[0].Stale)
+	assert.True(t, list.Data[1].Stale, "Hosts that stopped reporting should be stale")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/hosts/"+list.Data[0].ID.String(), nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/hosts/unknown", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/metrics/?host=db-01", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var metrics struct {
+		Data         []models.Metrics `json:"data"`
+		TotalRecords int              `json:"totalRecords"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &metrics))
+	assert.Equal(t, 1, metrics.TotalRecords)
+	assert.Equal(t, "db-01", metrics.Data[0].Host)
+}
+
+func TestHostLabels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	ctx := context.Background()
+	for host, labels := range map[string]map[string]string{
+		"pay-01": {"env": "prod", "team": "payments"},
+		"pay-02": {"env": "staging", "team": "payments"},
+		"web-01": {"env": "prod", "team": "web"},
+	} {
+		_, err := service.IngestMetrics(ctx, models.IngestBatch{
+			Host:    host,
+			Labels:  labels,
+			Metrics: []models.Metrics{{CPUPercent: 10, MemPercent: 20}},
+		})
+		assert.NoError(t, err)
+	}
+	database.DB.Model(&models.Metrics{}).Where("host = ?", "web-01").Update("cpu_percent", 50)
+
+	defer func(cfg *models.Config) { config.Cfg = cfg }(config.Cfg)
+	config.Cfg = &models.Config{Mode: config.ModeServer, IngestTokens: []string{"secret"}}
+
+	r := gin.Default()
+	router.SetRouter(r)
+
+	// Changing labels requires a token
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PUT", "/hosts/pay-02/labels", bytes.NewBufferString(`{"labels":{"env":"dev"}}`))
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/hosts/pay-02/labels/env", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// An API label overrides the agent label and survives the next push
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("PUT", "/hosts/pay-02/labels", bytes.NewBufferString(`{"labels":{"env":"prod","role":"db"}}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	_, err := service.IngestMetrics(ctx, models.IngestBatch{Host: "pay-02", Labels: map[string]string{"env": "staging", "team": "payments"}})
+	assert.NoError(t, err)
+
+	host, err := service.GetHost(ctx, "pay-02")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "payments", "role": "db"}, host.Labels)
+
+	start := time.Now().Add(-1 * time.Hour).UTC().Format(time.RFC3339)
+	end := time.Now().Add(1 * time.Hour).UTC().Format(time.RFC3339)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/metrics?start=%s&end=%s&label=env=prod&label=team=payments", start, end), nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var ranged struct {
+		Data []models.Metrics `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &ranged))
+	assert.Len(t, ranged.Data, 2)
+
+	// A malformed label filter is rejected rather than ignored
+	for _, path := range []string{"/metrics/?label=env", "/metrics/stats?start=" + start + "&end=" + end + "&label==prod"} {
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", path, nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+	}
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/metrics/average?start=%s&end=%s&group_by=team", start, end), nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var grouped struct {
+		Data []models.LabelAvgMetrics `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &grouped))
+	// setupTestDB's sample has no host and lands in the unlabelled group
+	assert.Len(t, grouped.Data, 3)
+	assert.Equal(t, "", grouped.Data[0].Value)
+	assert.Equal(t, "payments", grouped.Data[1].Value)
+	assert.Equal(t, 10.0, grouped.Data[1].CPUPercent)
+	assert.Equal(t, "web", grouped.Data[2].Value)
+	assert.Equal(t, 50.0, grouped.Data[2].CPUPercent)
+}
+
+func TestAggregateMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	base := time.Now().UTC().Truncate(time.Hour).Add(-2 * time.Hour)
+	for i, cpu := range []float64{10, 20, 30, 40, 50, 60} {
+		database.DB.Create(&models.Metrics{
+			ID:         uuid.New(),
+			Host:       "agg-01",
+			CPUPercent: cpu,
+			MemPercent: 50,
+			CreatedAt:  base.Add(time.Duration(i) * 10 * time.Minute),
+		})
+	}
+
+	r := gin.Default()
+	router.SetRouter(r)
+
+	start, end := queryTime(base), queryTime(base.Add(time.Hour))
+
+	query := func(fn string) []models.Point {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/metrics/aggregate?metric=cpu_percent&fn=%s&step=30m&host=agg-01&start=%s&end=%s",
+			fn, start, end), nil)
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp struct {
+			Data models.AggregateResponse `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data.Points
+	}
+
+	points := query("avg")
+	assert.Len(t, points, 2)
+	assert.True(t, points[0].Time.Equal(base))
+	assert.Equal(t, 20.0, points[0].Value)
+	assert.True(t, points[1].Time.Equal(base.Add(30*time.Minute)))
+	assert.Equal(t, 50.0, points[1].Value)
+
+	last := query("last")
+	assert.Equal(t, 30.0, last[0].Value)
+	assert.Equal(t, 60.0, last[1].Value)
+	assert.Equal(t, 3.0, query("count")[0].Value)
+	assert.Equal(t, 40.0, query("min")[1].Value)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/metrics/aggregate?metric=disk&step=1m&start=%s&end=%s", start, end), nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/metrics/aggregate?metric=cpu_percent&step=1s&start=%s&end=%s",
+		base.Add(-24*time.Hour).Format(time.RFC3339), base.Format(time.RFC3339)), nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/metrics/aggregate?metric=cpu_percent&step=1m&start=yesterday&end=%s", end), nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid start time format")
+}
+
+func TestGetMetricsStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	now := time.Now().UTC()
+	for i := 1; i <= 10; i++ {
+		database.DB.Create(&models.Metrics{
+			ID:         uuid.New(),
+			Host:       "stats-01",
+			CPUPercent: float64(i * 10),
+			MemPercent: 50,
+			CreatedAt:  now.Add(time.Duration(i-10) * time.Minute),
+		})
+	}
+
+	r := gin.Default()
+	router.SetRouter(r)
+
+	start := now.Add(-1 * time.Hour).Format(time.RFC3339)
+	end := now.Add(1 * time.Hour).Format(time.RFC3339)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/metrics/stats?start=%s&end=%s&host=stats-01", start, end), nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Data models.MetricsStats `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(10), resp.Data.Count)
+	assert.Equal(t, models.SeriesStats{Min: 10, Max: 100, Mean: 55, StdDev: 30.28, P50: 55, P90: 91, P95: 95.5, P99: 99.1}, resp.Data.CPU)
+	assert.Equal(t, 0.0, resp.Data.Mem.StdDev)
+	assert.True(t, resp.Data.FirstAt.Before(*resp.Data.LastAt))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", fmt.Sprintf("/metrics/stats?start=%s&end=%s&host=unknown", start, end), nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCounterFunctions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	base := time.Now().UTC().Truncate(time.Minute).Add(-time.Hour)
+	var samples []models.SeriesSample
+	// eth0 grows by 60 per 10s and is reset to 0 at 50s, eth1 grows by 10
+	for i, value := range []float64{0, 60, 120, 180, 240, 0, 60, 120, 180, 240, 300, 360} {
+		ts := base.Add(time.Duration(i) * 10 * time.Second)
+		samples = append(samples,
+			models.SeriesSample{Name: service.CounterNetworkReceiveBytes, Labels: map[string]string{"host": "ctr-01", "device": "eth0"}, Timestamp: ts, Value: value},
+			models.SeriesSample{Name: service.CounterNetworkReceiveBytes, Labels: map[string]string{"host": "ctr-01", "device": "eth1"}, Timestamp: ts, Value: float64(i * 10)})
+	}
+	assert.NoError(t, service.WriteSamples(context.Background(), samples))
+	// Writing again neither duplicates series nor samples
+	assert.NoError(t, service.WriteSamples(context.Background(), samples))
+
+	var count int64
+	database.DB.Model(&models.Series{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+	database.DB.Model(&models.Sample{}).Count(&count)
+	assert.Equal(t, int64(24), count)
+
+	query := func(fn string) []models.Point {
+		points, err := service.AggregateMetrics(context.Background(), models.AggregateQuery{
+			Metric: service.CounterNetworkReceiveBytes,
+			Func:   fn,
+			Start:  base,
+			End:    base.Add(2 * time.Minute),
+			Step:   time.Minute,
+			Filter: models.MetricsFilter{Host: "ctr-01"},
+		})
+		assert.NoError(t, err)
+		return points
+	}
+
+	// First minute: eth0 240 + reset 0 = 240, eth1 50; second minute: eth0 60*6, eth1 10*6
+	increase := query("increase")
+	assert.Len(t, increase, 2)
+	assert.Equal(t, 290.0, increase[0].Value)
+	assert.Equal(t, 420.0, increase[1].Value)
+
+	rate := query("rate")
+	assert.InDelta(t, 7.0, rate[1].Value, 0.001)
+
+	irate := query("irate")
+	assert.InDelta(t, 7.0, irate[1].Value, 0.001)
+
+	_, err := service.AggregateMetrics(context.Background(), models.AggregateQuery{
+		Metric: "cpu_percent", Func: "rate", Start: base, End: base.Add(time.Minute), Step: time.Minute,
+	})
+	assert.ErrorIs(t, err, service.ErrNotCounter)
+}
+
+func TestPromQLQueries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	base := time.Now().UTC().Truncate(time.Minute)
+	var samples []models.SeriesSample
+	for i := 0; i <= 40; i++ {
+		ts := base.Add(time.Duration(i-40) * 15 * time.Second)
+		samples = append(samples,
+			models.SeriesSample{Name: service.CounterNetworkReceiveBytes, Labels: map[string]string{"host": "web-01", "device": "eth0"}, Timestamp: ts, Value: float64(i * 150)},
+			models.SeriesSample{Name: service.CounterNetworkReceiveBytes, Labels: map[string]string{"host": "web-01", "device": "eth1"}, Timestamp: ts, Value: float64(i * 30)},
+			models.SeriesSample{Name: service.CounterNetworkReceiveBytes, Labels: map[string]string{"host": "db-01", "device": "lo"}, Timestamp: ts, Value: float64(i * 15)})
+	}
+	assert.NoError(t, service.WriteSamples(context.Background(), samples))
+	for i, cpu := range []float64{10, 20, 30} {
+		database.DB.Create(&models.Metrics{ID: uuid.New(), Host: "web-01", CPUPercent: cpu, MemPercent: 40, CreatedAt: base.Add(time.Duration(i-2) * time.Minute)})
+	}
+
+	r := gin.Default()
+	router.SetRouter(r)
+
+	type promResult struct {
+		Status string `json:"status"`
+		Data   struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+		ErrorType string `json:"errorType"`
+	}
+	query := func(path string, params url.Values) (int, promResult) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path+"?"+params.Encode(), nil)
+		r.ServeHTTP(w, req)
+		var res promResult
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		return w.Code, res
+	}
+	at := strconv.FormatInt(base.Unix(), 10)
+
+	code, res := query("/api/v1/query", url.Values{"query": {`sum by (host) (rate(node_network_receive_bytes_total{device=~"eth.*"}[1m]))`}, "time": {at}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "vector", res.Data.ResultType)
+	var vector []models.PromSample
+	assert.NoError(t, json.Unmarshal(res.Data.Result, &vector))
+	assert.Len(t, vector, 1)
+	assert.Equal(t, map[string]string{"host": "web-01"}, vector[0].Metric)
+	assert.Equal(t, "12", vector[0].Value[1])
+
+	// A parenthesized range selector is a valid range function argument
+	for _, q := range []string{`sum(rate((node_network_receive_bytes_total{device=~"eth.*"}[1m])))`, `sum(rate(((node_network_receive_bytes_total{device=~"eth.*"}[1m]))))`} {
+		code, res = query("/api/v1/query", url.Values{"query": {q}, "time": {at}})
+		assert.Equal(t, http.StatusOK, code, q)
+		assert.NoError(t, json.Unmarshal(res.Data.Result, &vector))
+		if assert.Len(t, vector, 1, q) {
+			assert.Equal(t, "12", vector[0].Value[1], q)
+		}
+	}
+	code, res = query("/api/v1/query", url.Values{"query": {`increase((node_network_receive_bytes_total{device="lo"}[1m]))`}, "time": {at}})
+	assert.Equal(t, http.StatusOK, code)
+
+	code, res = query("/api/v1/query", url.Values{"query": {`node_network_receive_bytes_total{device!~"eth.*"} / 15`}, "time": {at}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NoError(t, json.Unmarshal(res.Data.Result, &vector))
+	assert.Len(t, vector, 1)
+	assert.Equal(t, map[string]string{"host": "db-01", "device": "lo"}, vector[0].Metric)
+	assert.Equal(t, "40", vector[0].Value[1])
+
+	code, res = query("/api/v1/query", url.Values{"query": {`cpu_percent + on(host) mem_percent`}, "time": {at}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NoError(t, json.Unmarshal(res.Data.Result, &vector))
+	assert.Len(t, vector, 1)
+	assert.Equal(t, "70", vector[0].Value[1])
+
+	code, res = query("/api/v1/query", url.Values{"query": {"1 + 2 * 3 ^ 2"}, "time": {at}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "scalar", res.Data.ResultType)
+	assert.JSONEq(t, fmt.Sprintf(`[%s, "19"]`, at), string(res.Data.Result))
+
+	code, res = query("/api/v1/query_range", url.Values{
+		"query": {`max_over_time(cpu_percent{host="web-01"}[1m])`},
+		"start": {base.Add(-2 * time.Minute).Format(time.RFC3339)},
+		"end":   {at},
+		"step":  {"1m"},
+	})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "matrix", res.Data.ResultType)
+	var matrix []models.PromSeries
+	assert.NoError(t, json.Unmarshal(res.Data.Result, &matrix))
+	assert.Len(t, matrix, 1)
+	assert.Len(t, matrix[0].Values, 3)
+	assert.Equal(t, "30", matrix[0].Values[2][1])
+
+	// Steps under a millisecond would never advance the millisecond timestamps
+	for _, step := range []string{"0.0001", "500us"} {
+		code, res = query("/api/v1/query_range", url.Values{"query": {"1"}, "start": {at}, "end": {at}, "step": {step}})
+		assert.Equal(t, http.StatusBadRequest, code, step)
+		assert.Equal(t, "bad_data", res.ErrorType, step)
+	}
+	_, err := service.QueryEngine.Range(context.Background(), "1", base, base, 100*time.Microsecond)
+	assert.Error(t, err)
+
+	code, res = query("/api/v1/query", url.Values{"query": {`quantile_over_time(0.5, cpu_percent[5m])`}, "time": {at}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.NoError(t, json.Unmarshal(res.Data.Result, &vector))
+	assert.Equal(t, "20", vector[0].Value[1])
+
+	code, res = query("/api/v1/query", url.Values{"query": {`rate(cpu_percent)`}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "bad_data", res.ErrorType)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/api/v1/label/device/values", nil)
+	r.ServeHTTP(w, req)
+	assert.JSONEq(t, `{"status":"success","data":["eth0","eth1","lo"]}`, w.Body.String())
+}
+
+func TestPrometheusExposition(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	defer func(cfg *models.Config) { config.Cfg = cfg }(config.Cfg)
+	config.Cfg = &models.Config{PrometheusPath: "/prometheus/metrics"}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	database.DB.Create(&models.Metrics{ID: uuid.New(), Host: "web-01", CPUPercent: 12.5, MemPercent: 40, CreatedAt: now.Add(-time.Minute)})
+	database.DB.Create(&models.Metrics{ID: uuid.New(), Host: "web-01", CPUPercent: 15, MemPercent: 41, CreatedAt: now})
+	assert.NoError(t, service.WriteSamples(context.Background(), []models.SeriesSample{
+		{Name: service.CounterContextSwitches, Labels: map[string]string{"host": "web-01"}, Timestamp: now.Add(-time.Minute), Value: 100},
+		{Name: service.CounterContextSwitches, Labels: map[string]string{"host": "web-01"}, Timestamp: now, Value: 250},
+		{Name: service.CounterContextSwitches, Labels: map[string]string{"host": "old-01"}, Timestamp: now.Add(-time.Hour), Value: 5},
+		{Name: "cpu_percent", Labels: map[string]string{"host": "db-01"}, Timestamp: now, Value: 99},
+		{Name: "go_goroutines", Labels: map[string]string{"job": "app"}, Timestamp: now, Value: 99},
+		{Name: "metrics_monitor_hosts", Timestamp: now, Value: 99},
+	}))
+
+	r := gin.Default()
+	router.SetRouter(r)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/prometheus/metrics", nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "version=0.0.4")
+	body := w.Body.String()
+	assert.Contains(t, body, "# TYPE cpu_percent gauge\n")
+	assert.Contains(t, body, fmt.Sprintf("cpu_percent{host=\"web-01\"} 15 %d\n", now.UnixMilli()))
+	assert.Contains(t, body, "# TYPE node_context_switches_total counter\n")
+	assert.Contains(t, body, fmt.Sprintf("node_context_switches_total{host=\"web-01\"} 250 %d\n", now.UnixMilli()))
+	assert.NotContains(t, body, "old-01", "Series that stopped reporting should not be exposed")
+	assert.Contains(t, body, "metrics_monitor_database_up 1\n")
+	assert.Contains(t, body, "metrics_monitor_series 5\n")
+	for _, name := range []string{"cpu_percent", "go_goroutines", "metrics_monitor_hosts"} {
+		assert.Equal(t, 1, strings.Count(body, "# TYPE "+name+" "), "Stored series should not repeat the %s family", name)
+	}
+	assert.NotContains(t, body, " 99 ")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/prometheus/metrics", nil)
+	req.Header.Set("Accept", "application/openmetrics-text;version=1.0.0")
+	r.ServeHTTP(w, req)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/openmetrics-text")
+	body = w.Body.String()
+	assert.Contains(t, body, "# TYPE node_context_switches counter\n")
+	assert.Contains(t, body, fmt.Sprintf("node_context_switches_total{host=\"web-01\"} 250 %d\n", now.Unix()))
+	assert.True(t, strings.HasSuffix(body, "# EOF\n"))
+}
+
+func TestPrometheusRemoteWrite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	defer func(cfg *models.Config) { config.Cfg = cfg }(config.Cfg)
+	config.Cfg = &models.Config{Mode: config.ModeServer, IngestTokens: []string{"secret"}}
+
+	r := gin.Default()
+	router.SetRouter(r)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	write := prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+		{
+			Labels: []prompb.Label{{Name: "__name__", Value: "http_requests_total"}, {Name: "host", Value: "web-01"}, {Name: "job", Value: "api"}},
+			Samples: []prompb.Sample{
+				{Value: 10, Timestamp: now.Add(-time.Minute).UnixMilli()},
+				{Value: 25, Timestamp: now.UnixMilli()},
+				{Value: math.Float64frombits(0x7ff0000000000002), Timestamp: now.Add(time.Minute).UnixMilli()},
+			},
+		},
+		{
+			Labels:  []prompb.Label{{Name: "__name__", Value: "up"}, {Name: "instance", Value: "web-01:9100"}},
+			Samples: []prompb.Sample{{Value: 1, Timestamp: now.UnixMilli()}},
+		},
+	}}
+	post := func(token string, payload []byte) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/v1/write", bytes.NewReader(snappy.Encode(nil, payload)))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Encoding", "snappy")
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, post("wrong", write.Marshal()).Code)
+	assert.Equal(t, http.StatusNoContent, post("secret", write.Marshal()).Code)
+
+	var count int64
+	database.DB.Model(&models.Sample{}).Count(&count)
+	assert.Equal(t, int64(3), count, "Expected staleness markers to be skipped")
+
+	var series models.Series
+	assert.NoError(t, database.DB.Where("name = ?", "http_requests_total").First(&series).Error)
+	assert.Equal(t, "web-01", series.Host)
+	database.DB.Model(&models.SeriesLabel{}).Where("series_id = ? AND key = ? AND value = ?", series.ID, "job", "api").Count(&count)
+	assert.Equal(t, int64(1), count, "Expected labels to be stored with the series")
+
+	// Replayed batches are accepted without duplicating samples
+	assert.Equal(t, http.StatusNoContent, post("secret", write.Marshal()).Code)
+	database.DB.Model(&models.Sample{}).Count(&count)
+	assert.Equal(t, int64(3), count)
+
+	unnamed := prompb.WriteRequest{Timeseries: []prompb.TimeSeries{{Labels: []prompb.Label{{Name: "job", Value: "api"}}, Samples: []prompb.Sample{{Value: 1, Timestamp: now.UnixMilli()}}}}}
+	assert.Equal(t, http.StatusBadRequest, post("secret", unnamed.Marshal()).Code)
+
+	req, _ := http.NewRequest("POST", "/api/v1/write", strings.NewReader("not snappy"))
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestPrometheusRemoteRead(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	database.DB.Create(&models.Metrics{ID: uuid.New(), Host: "web-01", CPUPercent: 12.5, MemPercent: 40, CreatedAt: now.Add(-2 * time.Hour)})
+	database.DB.Create(&models.Metrics{ID: uuid.New(), Host: "web-02", CPUPercent: 80, MemPercent: 60, CreatedAt: now.Add(-2 * time.Hour)})
+	assert.NoError(t, service.WriteSamples(context.Background(), []models.SeriesSample{
+		{Name: "http_requests_total", Labels: map[string]string{"host": "web-01", "job": "api"}, Timestamp: now.Add(-time.Hour), Value: 10},
+		{Name: "http_requests_total", Labels: map[string]string{"host": "web-01", "job": "api"}, Timestamp: now, Value: 25},
+		{Name: "http_requests_total", Labels: map[string]string{"host": "web-02", "job": "api"}, Timestamp: now, Value: 3},
+	}))
+
+	r := gin.Default()
+	router.SetRouter(r)
+
+	read := func(req prompb.ReadRequest) (*httptest.ResponseRecorder, prompb.ReadResponse) {
+		httpReq, _ := http.NewRequest("POST", "/api/v1/read", bytes.NewReader(snappy.Encode(nil, req.Marshal())))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httpReq)
+
+		var resp prompb.ReadResponse
+		if w.Code == http.StatusOK {
+			body, err := snappy.Decode(nil, w.Body.Bytes())
+			assert.NoError(t, err)
+			assert.NoError(t, resp.Unmarshal(body))
+		}
+		return w, resp
+	}
+
+	w, resp := read(prompb.ReadRequest{Queries: []prompb.Query{{
+		StartTimestampMs: now.Add(-3 * time.Hour).UnixMilli(),
+		EndTimestampMs:   now.UnixMilli(),
+		Matchers: []prompb.LabelMatcher{
+			{Type: prompb.MatchRegexp, Name: "__name__", Value: "cpu_percent|http_requests_total"},
+			{Type: prompb.MatchEqual, Name: "host", Value: "web-01"},
+		},
+	}}})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "snappy", w.Header().Get("Content-Encoding"))
+	assert.Len(t, resp.Results, 1)
+	assert.Equal(t, []prompb.TimeSeries{
+		{
+			Labels:  []prompb.Label{{Name: "__name__", Value: "cpu_percent"}, {Name: "host", Value: "web-01"}},
+			Samples: []prompb.Sample{{Value: 12.5, Timestamp: now.Add(-2 * time.Hour).UnixMilli()}},
+		},
+		{
+			Labels: []prompb.Label{{Name: "__name__", Value: "http_requests_total"}, {Name: "host", Value: "web-01"}, {Name: "job", Value: "api"}},
+			Samples: []prompb.Sample{
+				{Value: 10, Timestamp: now.Add(-time.Hour).UnixMilli()},
+				{Value: 25, Timestamp: now.UnixMilli()},
+			},
+		},
+	}, resp.Results[0].Timeseries)
+
+	// The time range is applied to every query
+	_, resp = read(prompb.ReadRequest{Queries: []prompb.Query{{
+		StartTimestampMs: now.Add(-30 * time.Minute).UnixMilli(),
+		EndTimestampMs:   now.UnixMilli(),
+		Matchers:         []prompb.LabelMatcher{{Type: prompb.MatchNotEqual, Name: "host", Value: "web-01"}},
+	}}})
+	assert.Len(t, resp.Results[0].Timeseries, 1)
+	assert.Equal(t, prompb.Label{Name: "host", Value: "web-02"}, resp.Results[0].Timeseries[0].Labels[1])
+
+	w, _ = read(prompb.ReadRequest{Queries: []prompb.Query{{Matchers: []prompb.LabelMatcher{{Type: prompb.MatchRegexp, Name: "job", Value: "("}}}}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = read(prompb.ReadRequest{AcceptedResponseTypes: []prompb.ResponseType{prompb.ResponseStreamedXORChunks}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestOTLPMetricsIngestion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	defer func(cfg *models.Config) { config.Cfg = cfg }(config.Cfg)
+	config.Cfg = &models.Config{Mode: config.ModeServer, IngestTokens: []string{"secret"}}
+
+	r := gin.Default()
+	router.SetRouter(r)
+
+	post := func(contentType, encoding string, body []byte) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/v1/metrics", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Content-Encoding", encoding)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	seriesValue := func(name string, labels map[string]string) float64 {
+		var series models.Series
+		assert.NoError(t, database.DB.Where("fingerprint = ?", service.SeriesFingerprint(name, labels)).First(&series).Error, name)
+		var sample models.Sample
+		database.DB.Where("series_id = ?", series.ID).Order("ts DESC").First(&sample)
+		return sample.Value
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	ts := strconv.FormatInt(now.UnixNano(), 10)
+	body := `{"resourceMetrics": [{
+		"resource": {"attributes": [
+			{"key": "host.name", "value": {"stringValue": "web-01"}},
+			{"key": "service.name", "value": {"stringValue": "checkout"}}
+		]},
+		"scopeMetrics": [{"scope": {"name": "app"}, "metrics": [
+			{"name": "queue.depth", "gauge": {"dataPoints": [{"asInt": "7", "timeUnixNano": "` + ts + `"}]}},
+			{"name": "http.server.requests", "sum": {"aggregationTemporality": 2, "isMonotonic": true, "dataPoints": [
+				{"asDouble": 42, "timeUnixNano": "` + ts + `", "attributes": [{"key": "http.route", "value": {"stringValue": "/pay"}}]}
+			]}},
+			{"name": "latency", "histogram": {"aggregationTemporality": "AGGREGATION_TEMPORALITY_CUMULATIVE", "dataPoints": [
+				{"timeUnixNano": "` + ts + `", "count": "6", "sum": 1.5, "bucketCounts": ["1", "2", "3"], "explicitBounds": [0.1, 0.5]}
+			]}},
+			{"name": "sizes", "summary": {"dataPoints": [{}, {}]}},
+			{"name": "http.client.requests", "sum": {"aggregationTemporality": 1, "isMonotonic": true, "dataPoints": [{"asInt": "3", "timeUnixNano": "` + ts + `"}]}},
+			{"name": "payload", "histogram": {"aggregationTemporality": "AGGREGATION_TEMPORALITY_DELTA", "dataPoints": [
+				{"timeUnixNano": "` + ts + `", "count": "1", "bucketCounts": ["1"]}
+			]}},
+			{"name": "batch.size", "histogram": {"aggregationTemporality": 2, "dataPoints": [
+				{"timeUnixNano": "` + ts + `", "count": "6", "bucketCounts": ["1", "2", "3"], "explicitBounds": [10]},
+				{"timeUnixNano": "` + ts + `", "count": "2", "explicitBounds": [10]}
+			]}}
+		]}]
+	}]}`
+
+	w := post("application/json", "", []byte(body))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "6", resp["partialSuccess"]["rejectedDataPoints"], "Summaries, delta temporality and malformed histograms are not supported")
+	var malformed int64
+	database.DB.Model(&models.Series{}).Where("name LIKE ?", "batch_size%").Count(&malformed)
+	assert.Zero(t, malformed, "Expected no series of histogram points with too many or no bucket counts")
+
+	resource := map[string]string{"host": "web-01", "service_name": "checkout"}
+	assert.Equal(t, 7.0, seriesValue("queue_depth", resource))
+	assert.Equal(t, 42.0, seriesValue("http_server_requests_total", map[string]string{"host": "web-01", "service_name": "checkout", "http_route": "/pay"}))
+	assert.Equal(t, 1.0, seriesValue("latency_bucket", map[string]string{"host": "web-01", "service_name": "checkout", "le": "0.1"}))
+	assert.Equal(t, 3.0, seriesValue("latency_bucket", map[string]string{"host": "web-01", "service_name": "checkout", "le": "0.5"}))
+	assert.Equal(t, 6.0, seriesValue("latency_bucket", map[string]string{"host": "web-01", "service_name": "checkout", "le": "+Inf"}))
+	assert.Equal(t, 6.0, seriesValue("latency_count", resource))
+	assert.Equal(t, 1.5, seriesValue("latency_sum", resource))
+
+	// Host filters of the aggregate API apply to OTLP series too
+	w = httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/metrics/aggregate?metric=queue_depth&host=web-01&start=%s&end=%s&step=1m",
+		queryTime(now.Add(-time.Minute)), queryTime(now.Add(time.Minute))), nil)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"value":7`)
+
+	// Protobuf, gzip compressed
+	value := 0.25
+	pb := otlp.ExportMetricsServiceRequest{ResourceMetrics: []otlp.ResourceMetrics{{
+		Resource: otlp.Resource{Attributes: []otlp.KeyValue{{Key: "host.name", Value: otlp.StringValue("web-02")}}},
+		ScopeMetrics: []otlp.ScopeMetrics{{Metrics: []otlp.Metric{
+			{Name: "cache.hit_ratio", Gauge: &otlp.Gauge{DataPoints: []otlp.NumberDataPoint{{AsDouble: &value, TimeUnixNano: otlp.Uint64(now.UnixNano())}}}},
+		}}},
+	}}}
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(pb.Marshal())
+	zw.Close()
+
+	w = post("application/x-protobuf", "gzip", compressed.Bytes())
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-protobuf", w.Header().Get("Content-Type"))
+	assert.Equal(t, 0.25, seriesValue("cache_hit_ratio", map[string]string{"host": "web-02"}))
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, post("text/plain", "", []byte("x")).Code)
+	assert.Equal(t, http.StatusBadRequest, post("application/x-protobuf", "", []byte{0xff}).Code)
+
+	// Attribute values nested past the depth limit are rejected, not decoded
+	nested := otlp.StringValue("x")
+	for i := 0; i < 200; i++ {
+		nested = otlp.AnyValue{ArrayValue: &otlp.ArrayValue{Values: []otlp.AnyValue{nested}}}
+	}
+	pb.ResourceMetrics[0].Resource.Attributes = append(pb.ResourceMetrics[0].Resource.Attributes, otlp.KeyValue{Key: "deep", Value: nested})
+	assert.Equal(t, http.StatusBadRequest, post("application/x-protobuf", "", pb.Marshal()).Code)
+}
+
+func TestOTLPExport(t *testing.T) {
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	defer func(store func(models.Metrics) error, storeSamples func(context.Context, []models.SeriesSample) error, backoff time.Duration) {
+		service.StoreMetrics, service.StoreSamples, service.OTLPRetryBackoff = store, storeSamples, backoff
+	}(service.StoreMetrics, service.StoreSamples, service.OTLPRetryBackoff)
+	service.OTLPRetryBackoff = 10 * time.Millisecond
+
+	var (
+		mu       sync.Mutex
+		status   = []int{http.StatusServiceUnavailable}
+		requests []otlp.ExportMetricsServiceRequest
+		attempts int
+		during   func()
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if during != nil {
+			during()
+			during = nil
+		}
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		assert.Equal(t, "Bearer collector", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		if len(status) > 0 {
+			code := status[0]
+			status = status[1:]
+			w.WriteHeader(code)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req otlp.ExportMetricsServiceRequest
+		assert.NoError(t, req.Unmarshal(body))
+		requests = append(requests, req)
+	}))
+	defer receiver.Close()
+
+	cfg := &models.Config{
+		OTLPEndpoint:   receiver.URL + "/v1/metrics",
+		OTLPHeaders:    map[string]string{"Authorization": "Bearer collector"},
+		OTLPBatchSize:  2,
+		OTLPMaxRetries: 2,
+		OTLPBufferSize: 100,
+		Sinks:          []string{config.SinkDatabase},
+	}
+	assert.NoError(t, service.StartSinks(cfg, make(chan error, 10)))
+	defer service.StopSinks(context.Background())
+
+	now := time.Now().UTC().Truncate(time.Second)
+	assert.NoError(t, service.StoreMetrics(models.Metrics{ID: uuid.New(), Host: "web-01", CPUPercent: 12.5, MemPercent: 40, CreatedAt: now}))
+	assert.NoError(t, service.StoreSamples(context.Background(), []models.SeriesSample{
+		{Name: service.CounterContextSwitches, Labels: map[string]string{"host": "web-01", "cpu": "0"}, Timestamp: now, Value: 250},
+	}))
+	service.DrainSinks()
+
+	var count int64
+	database.DB.Model(&models.Metrics{}).Where("host = ?", "web-01").Count(&count)
+	assert.Equal(t, int64(1), count, "Exporting should not replace local storage")
+
+	// The first attempt fails with a retryable status, the retry succeeds
+	assert.NoError(t, service.FlushOTLPExport(context.Background(), cfg))
+	assert.Equal(t, 3, attempts)
+	assert.Len(t, requests, 2, "Expected 3 samples in batches of 2")
+
+	rm := requests[0].ResourceMetrics[0]
+	assert.Equal(t, []otlp.KeyValue{{Key: "host.name", Value: otlp.StringValue("web-01")}}, rm.Resource.Attributes)
+	metrics := rm.ScopeMetrics[0].Metrics
+	assert.Equal(t, "cpu_percent", metrics[0].Name)
+	assert.Equal(t, 12.5, metrics[0].Gauge.DataPoints[0].Value())
+	assert.Equal(t, uint64(now.UnixNano()), uint64(metrics[0].Gauge.DataPoints[0].TimeUnixNano))
+	assert.Equal(t, "mem_percent", metrics[1].Name)
+
+	counter := requests[1].ResourceMetrics[0].ScopeMetrics[0].Metrics[0]
+	assert.Equal(t, "node_context_switches", counter.Name)
+	assert.True(t, counter.Sum.IsMonotonic)
+	assert.Equal(t, otlp.AggregationTemporalityCumulative, counter.Sum.AggregationTemporality)
+	assert.Equal(t, []otlp.KeyValue{{Key: "cpu", Value: otlp.StringValue("0")}}, counter.Sum.DataPoints[0].Attributes)
+	assert.Equal(t, 250.0, counter.Sum.DataPoints[0].Value())
+
+	// Samples stay buffered while the endpoint is unavailable
+	service.StoreMetrics(models.Metrics{ID: uuid.New(), Host: "web-01", CreatedAt: now.Add(time.Second)})
+	service.DrainSinks()
+	mu.Lock()
+	status = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+	mu.Unlock()
+	assert.Error(t, service.FlushOTLPExport(context.Background(), cfg))
+	assert.NoError(t, service.FlushOTLPExport(context.Background(), cfg))
+	assert.Len(t, requests, 3)
+
+	// Rejected batches are dropped instead of retried
+	service.StoreMetrics(models.Metrics{ID: uuid.New(), Host: "web-01", CreatedAt: now.Add(2 * time.Second)})
+	service.DrainSinks()
+	mu.Lock()
+	status = []int{http.StatusBadRequest}
+	mu.Unlock()
+	assert.Error(t, service.FlushOTLPExport(context.Background(), cfg))
+	assert.NoError(t, service.FlushOTLPExport(context.Background(), cfg))
+	assert.Len(t, requests, 3)
+	assert.Equal(t, 8, attempts)
+
+	// Samples collected while an export is in flight survive the buffer
+	// trimming the exported ones
+	service.StopSinks(context.Background())
+	cfg.OTLPBufferSize = 2
+	assert.NoError(t, service.StartSinks(cfg, make(chan error, 10)))
+	service.StoreMetrics(models.Metrics{ID: uuid.New(), Host: "web-01", CPUPercent: 1, CreatedAt: now.Add(3 * time.Second)})
+	service.DrainSinks()
+	mu.Lock()
+	during = func() {
+		service.StoreMetrics(models.Metrics{ID: uuid.New(), Host: "web-01", CPUPercent: 2, CreatedAt: now.Add(4 * time.Second)})
+		service.DrainSinks()
+	}
+	mu.Unlock()
+	assert.NoError(t, service.FlushOTLPExport(context.Background(), cfg))
+	if assert.Len(t, requests, 5) {
+		metrics = requests[4].ResourceMetrics[0].ScopeMetrics[0].Metrics
+		assert.Equal(t, 2.0, metrics[0].Gauge.DataPoints[0].Value())
+	}
+}
+
+func TestInfluxLineProtocol(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	defer func(cfg *models.Config) { config.Cfg = cfg }(config.Cfg)
+	config.Cfg = &models.Config{Mode: config.ModeServer, IngestTokens: []string{"secret"}}
+
+	r := gin.Default()
+	router.SetRouter(r)
+
+	write := func(target string, auth string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", target, strings.NewReader(body))
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	seriesValue := func(name string, labels map[string]string) (float64, time.Time) {
+		var series models.Series
+		assert.NoError(t, database.DB.Where("fingerprint = ?", service.SeriesFingerprint(name, labels)).First(&series).Error, name)
+		var sample models.Sample
+		database.DB.Where("series_id = ?", series.ID).Order("ts DESC").First(&sample)
+		return sample.Value, sample.Timestamp.UTC()
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	body := fmt.Sprintf(`# Telegraf
+cpu,cpu=cpu-total,host=web-01 usage_idle=92.5,usage_user=4.25 %[1]d
+disk,host=web-01,path=/var/lib\ data free=1024i,inodes_free=12u,mounted=true,fstype="ext4" %[1]d
+
+system.temp,host=web-01,room=server\,rack\=2 value=41.5
+`, now.Unix())
+
+	assert.Equal(t, http.StatusUnauthorized, write("/api/v2/write?precision=s", "Token wrong", body).Code)
+	assert.Equal(t, http.StatusNoContent, write("/api/v2/write?org=acme&bucket=telegraf&precision=s", "Token secret", body).Code)
+
+	value, ts := seriesValue("cpu_usage_idle", map[string]string{"host": "web-01", "cpu": "cpu-total"})
+	assert.Equal(t, 92.5, value)
+	assert.Equal(t, now, ts)
+	value, _ = seriesValue("cpu_usage_user", map[string]string{"host": "web-01", "cpu": "cpu-total"})
+	assert.Equal(t, 4.25, value)
+	value, _ = seriesValue("disk_free", map[string]string{"host": "web-01", "path": "/var/lib data"})
+	assert.Equal(t, 1024.0, value)
+	value, _ = seriesValue("disk_inodes_free", map[string]string{"host": "web-01", "path": "/var/lib data"})
+	assert.Equal(t, 12.0, value)
+	value, _ = seriesValue("disk_mounted", map[string]string{"host": "web-01", "path": "/var/lib data"})
+	assert.Equal(t, 1.0, value)
+	value, _ = seriesValue("system_temp", map[string]string{"host": "web-01", "room": "server,rack=2"})
+	assert.Equal(t, 41.5, value, "A field called value is stored under the measurement name")
+
+	var count int64
+	database.DB.Model(&models.Series{}).Where("name = ?", "disk_fstype").Count(&count)
+	assert.Equal(t, int64(0), count, "String fields should be skipped")
+
+	// v1 API with the token in the p parameter, gzip compressed, millisecond precision
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	fmt.Fprintf(zw, "mem,host=web-02 used_percent=63.1 %d\n", now.UnixMilli())
+	zw.Close()
+	req, _ := http.NewRequest("POST", "/write?db=telegraf&precision=ms&u=telegraf&p=secret", &compressed)
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	value, ts = seriesValue("mem_used_percent", map[string]string{"host": "web-02"})
+	assert.Equal(t, 63.1, value)
+	assert.Equal(t, now, ts)
+
+	w = write("/write", "Bearer secret", "cpu,host=web-01 usage_idle=1\ncpu,host=web-01 usage_idle=\"unterminated\n")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "line 2")
+	assert.Equal(t, http.StatusBadRequest, write("/write", "Bearer secret", "cpu,host=web-01\n").Code)
+	assert.Equal(t, http.StatusBadRequest, write("/write?precision=d", "Bearer secret", "cpu value=1\n").Code)
+}
+
+func TestStatsDListener(t *testing.T) {
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	udpAddr, tcpAddr, err := service.StartStatsD(ctx, &models.Config{StatsDUDPAddr: "127.0.0.1:0", StatsDTCPAddr: "127.0.0.1:0"})
+	assert.NoError(t, err)
+
+	// StatsD state outlives the test database, so every run uses its own names.
+	run := fmt.Sprintf("run%d", time.Now().UnixNano())
+	latest := func(name string, labels map[string]string) (float64, bool) {
+		name = run + "_" + name
+		var series models.Series
+		if database.DB.Where("fingerprint = ?", service.SeriesFingerprint(name, labels)).First(&series).Error != nil {
+			return 0, false
+		}
+		var sample models.Sample
+		database.DB.Where("series_id = ?", series.ID).Order("ts DESC").First(&sample)
+		return sample.Value, true
+	}
+
+	udp, err := net.Dial("udp", udpAddr.String())
+	assert.NoError(t, err)
+	defer udp.Close()
+	fmt.Fprint(udp, run+"."+strings.Join([]string{
+		"api.requests:1|c|#route:/pay,host:web-01",
+		"api.requests:2|c|@0.5|#route:/pay,host:web-01",
+		"queue.depth:10|g",
+		"queue.depth:-3|g",
+		"api.latency:10|ms|#host:web-01",
+		"api.latency:20|ms|#host:web-01",
+		"api.latency:30|ms|@0.5|#host:web-01",
+		"users.online:alice|s",
+		"users.online:bob|s",
+		"users.online:alice|s",
+		"broken|c",
+	}, "\n"+run+".")+"\n_e{5,4}:title|text")
+
+	tcp, err := net.Dial("tcp", tcpAddr.String())
+	assert.NoError(t, err)
+	defer tcp.Close()
+	fmt.Fprint(tcp, run+".jobs.done:5|c\n"+run+".jobs.done:2|c\n")
+
+	assert.Eventually(t, func() bool {
+		assert.NoError(t, service.FlushStatsD(context.Background()))
+		_, udpDone := latest("users_online", map[string]string{})
+		jobs, _ := latest("jobs_done_total", map[string]string{})
+		return udpDone && jobs == 7
+	}, 2*time.Second, 20*time.Millisecond, "Expected StatsD packets over UDP and TCP to be flushed")
+
+	value, _ := latest("api_requests_total", map[string]string{"host": "web-01", "route": "/pay"})
+	assert.Equal(t, 5.0, value, "Counters are scaled by the sample rate")
+	value, _ = latest("queue_depth", map[string]string{})
+	assert.Equal(t, 7.0, value, "Signed gauges adjust the current value")
+	value, _ = latest("api_latency_count", map[string]string{"host": "web-01"})
+	assert.Equal(t, 4.0, value)
+	value, _ = latest("api_latency_sum", map[string]string{"host": "web-01"})
+	assert.Equal(t, 60.0, value)
+	value, _ = latest("api_latency_max", map[string]string{"host": "web-01"})
+	assert.Equal(t, 30.0, value)
+	value, _ = latest("api_latency", map[string]string{"host": "web-01", "quantile": "0.5"})
+	assert.Equal(t, 20.0, value)
+	value, _ = latest("users_online", map[string]string{})
+	assert.Equal(t, 2.0, value, "Sets count unique values")
+
+	// Counters keep counting across flushes
+	fmt.Fprint(tcp, run+".jobs.done:3|c\n")
+	assert.Eventually(t, func() bool {
+		assert.NoError(t, service.FlushStatsD(context.Background()))
+		jobs, _ := latest("jobs_done_total", map[string]string{})
+		return jobs == 10
+	}, 2*time.Second, 20*time.Millisecond)
+
+	// Counters and gauges without updates expire
+	samples := func(name string) int64 {
+		var count int64
+		database.DB.Model(&models.Sample{}).
+			Joins("JOIN series ON series.id = samples.series_id").
+			Where("series.name = ?", run+"_"+name).
+			Count(&count)
+		return count
+	}
+	for i := 0; i <= service.StatsDIdleFlushes; i++ {
+		assert.NoError(t, service.FlushStatsD(context.Background()))
+	}
+	jobs, gauges := samples("jobs_done_total"), samples("queue_depth")
+	assert.NoError(t, service.FlushStatsD(context.Background()))
+	assert.Equal(t, jobs, samples("jobs_done_total"))
+	assert.Equal(t, gauges, samples("queue_depth"))
+}
+
+func TestGraphite(t *testing.T) {
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := &models.Config{
+		GraphiteAddr:      "127.0.0.1:0",
+		GraphiteTemplates: "servers.* .host.name* env=prod; collectd.* .host.name.name",
+	}
+	addr, err := service.StartGraphite(ctx, cfg, make(chan error, 10))
+	assert.NoError(t, err)
+
+	latest := func(name string, labels map[string]string) (float64, time.Time, bool) {
+		var series models.Series
+		if database.DB.Where("fingerprint = ?", service.SeriesFingerprint(name, labels)).First(&series).Error != nil {
+			return 0, time.Time{}, false
+		}
+		var sample models.Sample
+		database.DB.Where("series_id = ?", series.ID).Order("ts DESC").First(&sample)
+		return sample.Value, sample.Timestamp.UTC(), true
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	conn, err := net.Dial("tcp", addr.String())
+	assert.NoError(t, err)
+	fmt.Fprintf(conn, "servers.web-01.cpu.load 0.42 %[1]d\ncollectd.web-02.memory.used.extra 2048 %[1]d\napp.requests;route=/pay 5 -1\nnot a valid line\n", now.Unix())
+	conn.Close()
+
+	assert.Eventually(t, func() bool {
+		_, _, ok := latest("app_requests", map[string]string{"route": "/pay"})
+		return ok
+	}, 3*time.Second, 50*time.Millisecond, "Expected received lines to be stored")
+
+	value, ts, _ := latest("cpu_load", map[string]string{"host": "web-01", "env": "prod"})
+	assert.Equal(t, 0.42, value)
+	assert.Equal(t, now, ts)
+	value, _, _ = latest("memory_used", map[string]string{"host": "web-02"})
+	assert.Equal(t, 2048.0, value, "Components beyond the template are dropped")
+
+	// Output sink
+	defer func(store func(models.Metrics) error, storeSamples func(context.Context, []models.SeriesSample) error) {
+		service.StoreMetrics, service.StoreSamples = store, storeSamples
+	}(service.StoreMetrics, service.StoreSamples)
+
+	server, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer server.Close()
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := server.Accept()
+			if err != nil {
+				return
+			}
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+			conn.Close()
+		}
+	}()
+
+	out := &models.Config{GraphiteOutputAddr: server.Addr().String(), GraphiteOutputPrefix: "mm"}
+	assert.NoError(t, service.StartSinks(out, make(chan error, 10)))
+	defer service.StopSinks(context.Background())
+	assert.NoError(t, service.StoreMetrics(models.Metrics{ID: uuid.New(), Host: "web.01", CPUPercent: 12.5, MemPercent: 40, CreatedAt: now}))
+	assert.NoError(t, service.StoreSamples(context.Background(), []models.SeriesSample{
+		{Name: service.CounterNetworkReceiveBytes, Labels: map[string]string{"host": "web.01", "device": "eth0"}, Timestamp: now, Value: 123456789},
+	}))
+	service.DrainSinks()
+	assert.NoError(t, service.FlushGraphiteOutput(context.Background(), out))
+
+	var received []string
+	for i := 0; i < 3; i++ {
+		select {
+		case line := <-lines:
+			received = append(received, line)
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected 3 lines from the Graphite output")
+		}
+	}
+	assert.Equal(t, []string{
+		fmt.Sprintf("mm.web_01.cpu_percent 12.5 %d", now.Unix()),
+		fmt.Sprintf("mm.web_01.mem_percent 40 %d", now.Unix()),
+		fmt.Sprintf("mm.web_01.node_network_receive_bytes_total.eth0 123456789 %d", now.Unix()),
+	}, received)
+}
+
+func TestSinks(t *testing.T) {
+	logger.Log, _ = zap.NewDevelopment()
+
+	defer func(store func(models.Metrics) error, storeSamples func(context.Context, []models.SeriesSample) error, backoff time.Duration) {
+		service.StoreMetrics, service.StoreSamples, service.SinkRetryBackoff = store, storeSamples, backoff
+	}(service.StoreMetrics, service.StoreSamples, service.SinkRetryBackoff)
+	service.SinkRetryBackoff = 10 * time.Millisecond
+
+	// The webhook hangs on its first request and then fails it once
+	release := make(chan struct{})
+	var (
+		mu       sync.Mutex
+		attempts int
+		received []models.SampleBatch
+	)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		attempt := attempts
+		mu.Unlock()
+		assert.Equal(t, "secret", r.Header.Get("X-Token"))
+		if attempt == 1 {
+			<-release
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var batch models.SampleBatch
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+		mu.Lock()
+		received = append(received, batch)
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+
+	path := t.TempDir() + "/metrics.ndjson"
+	cfg := &models.Config{
+		Sinks:              []string{config.SinkFile, config.SinkWebhook},
+		SinkFilePath:       path,
+		SinkFileMaxSize:    300,
+		SinkFileMaxBackups: 1,
+		SinkWebhookURL:     webhook.URL,
+		SinkWebhookHeaders: map[string]string{"X-Token": "secret"},
+	}
+	errChan := make(chan error, 10)
+	assert.NoError(t, service.StartSinks(cfg, errChan))
+	defer service.StopSinks(context.Background())
+
+	now := time.Now().UTC().Truncate(time.Second)
+	assert.NoError(t, service.StoreMetrics(models.Metrics{ID: uuid.New(), Host: "web-01", CPUPercent: 12.5, MemPercent: 40, CreatedAt: now}))
+
+	readLines := func(path string) []string {
+		data, _ := os.ReadFile(path)
+		return strings.Split(strings.TrimSpace(string(data)), "\n")
+	}
+	// The file sink is not held up by the hanging webhook
+	assert.Eventually(t, func() bool { return len(readLines(path)) == 2 }, 2*time.Second, 10*time.Millisecond)
+	var sample models.SeriesSample
+	assert.NoError(t, json.Unmarshal([]byte(readLines(path)[0]), &sample))
+	assert.Equal(t, models.SeriesSample{Name: "cpu_percent", Labels: map[string]string{"host": "web-01"}, Timestamp: sample.Timestamp, Value: 12.5}, sample)
+	assert.True(t, now.Equal(sample.Timestamp))
+
+	close(release)
+	service.DrainSinks()
+	mu.Lock()
+	assert.Equal(t, 2, attempts, "Expected the failed webhook request to be retried")
+	assert.Len(t, received, 1)
+	assert.Len(t, received[0].Samples, 2)
+	mu.Unlock()
+
+	// The file rotates and keeps one backup
+	for i := 1; i <= 5; i++ {
+		assert.NoError(t, service.StoreSamples(context.Background(), []models.SeriesSample{
+			{Name: "queue_depth", Labels: map[string]string{"host": "web-01"}, Timestamp: now.Add(time.Duration(i) * time.Second), Value: float64(i)},
+		}))
+	}
+	service.DrainSinks()
+	assert.FileExists(t, path+".1")
+	assert.NoFileExists(t, path+".2")
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), int64(300))
+	assert.Empty(t, errChan)
+
+	// A batch the sink keeps failing to write is reported like a failed
+	// collection
+	service.StopSinks(context.Background())
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	assert.NoError(t, service.StartSinks(&models.Config{Sinks: []string{config.SinkWebhook}, SinkWebhookURL: failing.URL}, errChan))
+	assert.NoError(t, service.StoreMetrics(models.Metrics{ID: uuid.New(), Host: "web-01", CreatedAt: now}))
+	service.DrainSinks()
+	select {
+	case err := <-errChan:
+		assert.ErrorContains(t, err, "sink webhook")
+	default:
+		t.Error("Expected the dropped batch to be reported")
+	}
+
+	// Shutdown gives up on a failing sink once its context expires
+	service.StopSinks(context.Background())
+	service.SinkRetryBackoff = time.Hour
+	assert.NoError(t, service.StartSinks(&models.Config{Sinks: []string{config.SinkWebhook}, SinkWebhookURL: failing.URL}, errChan))
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, service.StoreMetrics(models.Metrics{ID: uuid.New(), Host: "web-01", CreatedAt: now}))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	service.StopSinks(ctx)
+	assert.Less(t, time.Since(started), 2*time.Second)
+	assert.Empty(t, errChan, "Batches dropped on shutdown are not write failures")
+}
+
+func TestMetricsStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	defer func(store func(models.Metrics) error, storeSamples func(context.Context, []models.SeriesSample) error) {
+		service.StoreMetrics, service.StoreSamples = store, storeSamples
+	}(service.StoreMetrics, service.StoreSamples)
+	assert.NoError(t, service.StartSinks(&models.Config{Mode: config.ModeStandalone, Sinks: []string{config.SinkDatabase}}, make(chan error, 10)))
+	defer service.StopSinks(context.Background())
+
+	r := gin.Default()
+	router.SetRouter(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics/stream?name=cpu_percent&name=" + service.CounterContextSwitches + "&host=web-01")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan string, 10)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			events <- scanner.Text()
+		}
+		close(events)
+	}()
+	next := func() string {
+		select {
+		case line := <-events:
+			return line
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected a line from the stream")
+			return ""
+		}
+	}
+	assert.Equal(t, ": connected", next())
+	assert.Equal(t, "", next())
+
+	now := time.Now().UTC().Truncate(time.Second)
+	assert.NoError(t, service.StoreMetrics(models.Metrics{ID: uuid.New(), Host: "web-02", CPUPercent: 99, CreatedAt: now}))
+	assert.NoError(t, service.StoreMetrics(models.Metrics{ID: uuid.New(), Host: "web-01", CPUPercent: 12.5, MemPercent: 40, CreatedAt: now}))
+	assert.NoError(t, service.StoreSamples(context.Background(), []models.SeriesSample{
+		{Name: service.CounterContextSwitches, Labels: map[string]string{"host": "web-01"}, Timestamp: now, Value: 250},
+	}))
+
+	var received []models.SeriesSample
+	for len(received) < 2 {
+		assert.Equal(t, "event:sample", next())
+		data, ok := strings.CutPrefix(next(), "data:")
+		assert.True(t, ok)
+		var sample models.SeriesSample
+		assert.NoError(t, json.Unmarshal([]byte(data), &sample))
+		received = append(received, sample)
+		assert.Equal(t, "", next())
+	}
+	assert.Equal(t, "cpu_percent", received[0].Name)
+	assert.Equal(t, 12.5, received[0].Value)
+	assert.Equal(t, "web-01", received[0].Labels["host"])
+	assert.Equal(t, service.CounterContextSwitches, received[1].Name)
+	assert.Equal(t, 250.0, received[1].Value)
+
+	// Streaming does not replace storage
+	service.DrainSinks()
+	var count int64
+	database.DB.Model(&models.Metrics{}).Where("host = ?", "web-01").Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// Samples pushed by agents are streamed once stored
+	defer func(cfg *models.Config) { config.Cfg = cfg }(config.Cfg)
+	config.Cfg = &models.Config{Mode: config.ModeServer, IngestTokens: []string{"secret"}}
+	body, _ := json.Marshal(models.IngestBatch{Host: "web-01", Metrics: []models.Metrics{{CPUPercent: 33, CreatedAt: now.Add(time.Second)}}})
+	req, _ := http.NewRequest("POST", server.URL+"/ingest", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	ingest, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	ingest.Body.Close()
+	assert.Equal(t, http.StatusAccepted, ingest.StatusCode)
+
+	assert.Equal(t, "event:sample", next())
+	data, _ := strings.CutPrefix(next(), "data:")
+	var sample models.SeriesSample
+	assert.NoError(t, json.Unmarshal([]byte(data), &sample))
+	assert.Equal(t, "cpu_percent", sample.Name)
+	assert.Equal(t, 33.0, sample.Value)
+	assert.Equal(t, "web-01", sample.Labels["host"])
+}
+
+func TestMetricsWebSocket(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	defer func(store func(models.Metrics) error, storeSamples func(context.Context, []models.SeriesSample) error) {
+		service.StoreMetrics, service.StoreSamples = store, storeSamples
+	}(service.StoreMetrics, service.StoreSamples)
+	assert.NoError(t, service.StartSinks(&models.Config{Mode: config.ModeStandalone, Sinks: []string{config.SinkDatabase}}, make(chan error, 10)))
+	defer service.StopSinks(context.Background())
+
+	web01 := map[string]string{"host": "web-01"}
+	web02 := map[string]string{"host": "web-02"}
+	base := time.Now().UTC().Truncate(time.Minute).Add(-5 * time.Minute)
+	assert.NoError(t, service.WriteSamples(context.Background(), []models.SeriesSample{
+		{Name: "queue_depth", Labels: web01, Timestamp: base.Add(10 * time.Second), Value: 1},
+		{Name: "queue_depth", Labels: web01, Timestamp: base.Add(20 * time.Second), Value: 3},
+		{Name: "queue_depth", Labels: web01, Timestamp: base.Add(2 * time.Minute), Value: 5},
+		{Name: "queue_depth", Labels: web02, Timestamp: base.Add(10 * time.Second), Value: 100},
+	}))
+
+	r := gin.Default()
+	router.SetRouter(r)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/metrics/ws", nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	next := func() models.StreamMessage {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var msg models.StreamMessage
+		assert.NoError(t, conn.ReadJSON(&msg))
+		return msg
+	}
+
+	assert.NoError(t, conn.WriteJSON(models.StreamRequest{Type: "subscribe", ID: "bad", Selector: "rate(queue_depth[5m])"}))
+	msg := next()
+	assert.Equal(t, "error", msg.Type)
+	assert.Equal(t, "bad", msg.ID)
+
+	assert.NoError(t, conn.WriteJSON(models.StreamRequest{Type: "subscribe", ID: "q", Selector: `queue_depth{host="web-01"}`, Backfill: "10m", Step: "1m"}))
+	assert.Equal(t, models.StreamMessage{Type: "subscribed", ID: "q"}, next())
+	msg = next()
+	assert.Equal(t, "backfill", msg.Type)
+	if assert.Len(t, msg.Series, 1) {
+		assert.Equal(t, "queue_depth", msg.Series[0].Name)
+		assert.Equal(t, web01, msg.Series[0].Labels)
+		assert.Len(t, msg.Series[0].Points, 2, "Expected the samples averaged into 1m steps")
+		assert.True(t, base.Equal(msg.Series[0].Points[0].Time))
+		assert.Equal(t, 2.0, msg.Series[0].Points[0].Value)
+		assert.Equal(t, 5.0, msg.Series[0].Points[1].Value)
+	}
+	_, err = service.StreamBackfill(context.Background(), nil, 1000*7*24*time.Hour, 7*24*time.Hour)
+	assert.ErrorIs(t, err, service.ErrInvalidBackfill, "Expected the backfill window to be capped")
+
+	// Live samples are filtered by the selector
+	now := time.Now().UTC()
+	assert.NoError(t, service.StoreSamples(context.Background(), []models.SeriesSample{
+		{Name: "queue_depth", Labels: web02, Timestamp: now, Value: 9},
+		{Name: "queue_depth", Labels: web01, Timestamp: now, Value: 7},
+	}))
+	msg = next()
+	assert.Equal(t, "sample", msg.Type)
+	assert.Equal(t, "q", msg.ID)
+	if assert.NotNil(t, msg.Sample) {
+		assert.Equal(t, 7.0, msg.Sample.Value)
+		assert.Equal(t, web01, msg.Sample.Labels)
+	}
+
+	assert.NoError(t, conn.WriteJSON(models.StreamRequest{Type: "unsubscribe", ID: "q"}))
+	assert.Equal(t, models.StreamMessage{Type: "unsubscribed", ID: "q"}, next())
+	assert.NoError(t, conn.WriteJSON(models.StreamRequest{Type: "subscribe", ID: "all", Selector: `{__name__="queue_depth"}`}))
+	assert.Equal(t, models.StreamMessage{Type: "subscribed", ID: "all"}, next())
+
+	assert.NoError(t, service.StoreSamples(context.Background(), []models.SeriesSample{
+		{Name: "queue_depth", Labels: web02, Timestamp: now.Add(time.Second), Value: 11},
+	}))
+	msg = next()
+	assert.Equal(t, "all", msg.ID, "Expected no samples of the cancelled subscription")
+	if assert.NotNil(t, msg.Sample) {
+		assert.Equal(t, 11.0, msg.Sample.Value)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+
+	r := gin.Default()
+	router.SetRouter(r)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/")
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/dashboard/", w.Header().Get("Location"))
+
+	w = get("/dashboard/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `<script src="app.js"></script>`)
+
+	w = get("/dashboard/app.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "javascript")
+	// The dashboard only uses the existing APIs
+	for _, path := range []string{"/hosts", "/api/v1/label/__name__/values", "/api/v1/query_range?", "/metrics/stream?"} {
+		assert.Contains(t, w.Body.String(), `"`+path, path)
+	}
+
+	assert.Equal(t, http.StatusOK, get("/dashboard/style.css").Code)
+	assert.Equal(t, http.StatusNotFound, get("/dashboard/missing.js").Code)
+}
+
+func TestRenderChart(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger.Log, _ = zap.NewDevelopment()
+	setupTestDB()
+	defer cleanupTestDB()
+
+	defer func(cfg *models.Config) { config.Cfg = cfg }(config.Cfg)
+	config.Cfg = &models.Config{HostStaleAfter: time.Minute}
+
+	ctx := context.Background()
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, host := range []string{"web-01", "db-01"} {
+		var metrics []models.Metrics
+		for j := 0; j < 6; j++ {
+			metrics = append(metrics, models.Metrics{ID: uuid.New(), Host: host, CPUPercent: float64(10*i + j), MemPercent: 50, CreatedAt: base.Add(time.Duration(j) * 10 * time.Minute)})
+		}
+		_, err := service.IngestMetrics(ctx, models.IngestBatch{Host: host, Metrics: metrics})
+		assert.NoError(t, err)
+	}
+
+	r := gin.Default()
+	router.SetRouter(r)
+	get := func(target string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	params := "/metrics/chart?metric=cpu_percent&start=2024-03-01T12:00:00Z&end=2024-03-01T13:00:00Z&step=10m"
+
+	w := get(params)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/svg+xml", w.Header().Get("Content-Type"))
+	svg := w.Body.String()
+	assert.True(t, strings.HasPrefix(svg, "<svg "))
+	assert.Contains(t, svg, ">avg(cpu_percent)</text>")
+	assert.Contains(t, svg, ">db-01</text>", "Expected one line per host in the legend")
+	assert.Contains(t, svg, ">web-01</text>")
+	assert.Contains(t, svg, ">100%</text>")
+	assert.Contains(t, svg, ">12:30</text>")
+	assert.Equal(t, 2, strings.Count(svg, `stroke-width="1.5"`))
+
+	w = get(params + "&host=web-01&title=Web%20CPU")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), ">Web CPU</text>")
+	assert.NotContains(t, w.Body.String(), ">db-01</text>")
+
+	w = get(params + "&format=png&width=640&height=320")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	img, err := png.Decode(w.Body)
+	if assert.NoError(t, err) {
+		assert.Equal(t, image.Rect(0, 0, 640, 320), img.Bounds())
+	}
+
+	w = get("/metrics/chart?metric=cpu_percent&host=nowhere")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), ">No data</text>")
+
+	// Sample store series are grouped by their host label, series without
+	// one are left out.
+	var samples []models.SeriesSample
+	for i, host := range []string{"web-01", "db-01", ""} {
+		labels := map[string]string{"host": host}
+		if host == "" {
+			labels = nil
+		}
+		for j := 0; j < 6; j++ {
+			samples = append(samples, models.SeriesSample{Name: "requests_total", Labels: labels, Timestamp: base.Add(time.Duration(j) * 10 * time.Minute), Value: float64((i + 1) * j * 600)})
+		}
+	}
+	assert.NoError(t, service.WriteSamples(ctx, samples))
+	w = get("/metrics/chart?metric=requests_total&fn=rate&start=2024-03-01T12:00:00Z&end=2024-03-01T13:00:00Z&step=10m")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), ">db-01</text>")
+	assert.Contains(t, w.Body.String(), ">web-01</text>")
+	assert.Equal(t, 2, strings.Count(w.Body.String(), `stroke-width="1.5"`))
+
+	assert.Equal(t, http.StatusBadRequest, get(params+"&format=gif").Code)
+	assert.Equal(t, http.StatusBadRequest, get(params+"&width=10").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/metrics/chart?metric=cpu_percent;drop").Code)
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// AggregateQuery asks for one Func(Metric) value per Step wide bucket
+// between Start and End.
+type AggregateQuery struct {
+	Metric string
+	Func   string
+	Start  time.Time
+	End    time.Time
+	Step   time.Duration
+	Filter MetricsFilter
+}
+
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+type AggregateResponse struct {
+	Metric string  `json:"metric"`
+	Func   string  `json:"fn"`
+	Step   string  `json:"step"`
+	Points []Point `json:"points"`
+}
+
+// HostPoints are the aggregated points of one host.
+type HostPoints struct {
+	Host   string  `json:"host"`
+	Points []Point `json:"points"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type Config struct {
 	DBHost          string
 	DBUser          string
@@ -8,4 +10,54 @@ type Config struct {
 	Port            string
 	DBPort          string
 	MetricsInterval int
+
+	RetentionPeriod    time.Duration
+	RetentionOverrides map[string]time.Duration
+	RetentionInterval  time.Duration
+	RetentionBatchSize int
+
+	RollupInterval  time.Duration
+	RollupMaxPoints int
+
+	TimescaleEnabled       bool
+	TimescaleChunkInterval time.Duration
+	TimescaleCompressAfter time.Duration
+
+	PartitionInterval string
+	PartitionPremake  int
+
+	Mode              string
+	Hostname          string
+	AgentServerURL    string
+	AgentToken        string
+	AgentPushInterval time.Duration
+	AgentBufferSize   int
+	IngestTokens      []string
+	HostStaleAfter    time.Duration
+	HostLabels        map[string]string
+
+	PrometheusPath string
+
+	OTLPEndpoint       string
+	OTLPHeaders        map[string]string
+	OTLPExportInterval time.Duration
+	OTLPBatchSize      int
+	OTLPMaxRetries     int
+	OTLPBufferSize     int
+
+	StatsDUDPAddr string
+	StatsDTCPAddr string
+
+	GraphiteAddr         string
+	GraphiteTemplates    string
+	GraphiteOutputAddr   string
+	GraphiteOutputPrefix string
+
+	Sinks              []string
+	SinkQueueSize      int
+	SinkFilePath       string
+	SinkFileMaxSize    int64
+	SinkFileMaxBackups int
+	SinkWebhookURL     string
+	SinkWebhookHeaders map[string]string
 }
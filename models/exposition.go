@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	MetricTypeCounter = "counter"
+	MetricTypeGauge   = "gauge"
+	MetricTypeUnknown = "unknown"
+)
+
+// MetricFamily is the latest value of every series sharing a name, as
+// written in the Prometheus exposition formats. Counter names keep their
+// _total suffix.
+type MetricFamily struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []ExposedSample
+}
+
+type ExposedSample struct {
+	Labels    map[string]string
+	Value     float64
+	Timestamp *time.Time
+}
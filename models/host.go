@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HostInfo is the inventory an agent reports with every push.
+type HostInfo struct {
+	OS              string `json:"os"`
+	Platform        string `json:"platform"`
+	PlatformVersion string `json:"platform_version"`
+	Kernel          string `json:"kernel"`
+	Arch            string `json:"arch"`
+	CPUModel        string `json:"cpu_model"`
+	CPUCount        int    `json:"cpu_count"`
+	TotalMemory     int64  `json:"total_memory"`
+	AgentVersion    string `json:"agent_version"`
+}
+
+type Host struct {
+	ID        uuid.UUID `gorm:"primaryKey" json:"id"`
+	Hostname  string    `gorm:"uniqueIndex;not null" json:"hostname"`
+	HostInfo  `gorm:"embedded"`
+	FirstSeen time.Time         `gorm:"not null" json:"first_seen"`
+	LastSeen  time.Time         `gorm:"not null" json:"last_seen"`
+	Labels    map[string]string `gorm:"-" json:"labels"`
+	Stale     bool              `gorm:"-" json:"stale"`
+}
+
+const (
+	LabelSourceAgent = "agent"
+	LabelSourceAPI   = "api"
+)
+
+// HostLabel is one key/value label of a host. Labels set through the API
+// take precedence over, and are never removed by, the agent's own labels.
+type HostLabel struct {
+	HostID uuid.UUID `gorm:"primaryKey"`
+	Key    string    `gorm:"primaryKey"`
+	Value  string    `gorm:"not null"`
+	Source string    `gorm:"not null"`
+}
+
+type HostLabelsRequest struct {
+	Labels map[string]string `json:"labels" binding:"required"`
+}
+
+// MetricsFilter narrows the /metrics queries down to some hosts, by name
+// and/or by host labels that all have to match.
+type MetricsFilter struct {
+	Host   string
+	Labels map[string]string
+}
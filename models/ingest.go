@@ -0,0 +1,23 @@
+package models
+
+// IngestBatch is the body an agent POSTs to the server's /ingest endpoint.
+// A batch without metrics or samples is a heartbeat. Labels are the agent's configured
+// host labels; agents that predate labels leave them nil.
+type IngestBatch struct {
+	Host     string            `json:"host" binding:"required"`
+	HostInfo *HostInfo         `json:"host_info,omitempty"`
+	Labels   map[string]string `json:"labels"`
+	Metrics  []Metrics         `json:"metrics"`
+	Samples  []SeriesSample    `json:"samples,omitempty"`
+}
+
+type IngestResponse struct {
+	Accepted int `json:"accepted"`
+}
+
+// SampleBatch is what the collectors hand to the output sinks in one call:
+// rows of the metrics table, series samples, or both.
+type SampleBatch struct {
+	Metrics []Metrics      `json:"metrics,omitempty"`
+	Samples []SeriesSample `json:"samples,omitempty"`
+}
@@ -8,6 +8,7 @@ import (
 
 type Metrics struct {
 	ID         uuid.UUID `gorm:"primaryKey" json:"id"`
+	Host       string    `gorm:"not null;default:''" json:"host"`
 	CPUPercent float64   `gorm:"not null" json:"cpu_percent"`
 	MemPercent float64   `gorm:"not null" json:"mem_percent"`
 	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
@@ -18,6 +19,14 @@ type AvgMetrics struct {
 	MemPercent float64 `gorm:"not null" json:"mem_percent"`
 }
 
+// LabelAvgMetrics is the average of every host whose Label has Value.
+type LabelAvgMetrics struct {
+	Label      string  `json:"label"`
+	Value      string  `json:"value"`
+	CPUPercent float64 `json:"cpu_percent"`
+	MemPercent float64 `json:"mem_percent"`
+}
+
 type MetricsResponse struct {
 	Data         []Metrics `json:"data"`
 	TotalRecords int       `json:"totalRecords"`
@@ -28,3 +37,24 @@ type RangeMetricsResponse struct {
 	Data []Metrics `json:"data"`
 	Time time.Time `json:"time"`
 }
+
+// SeriesStats summarises the samples of one metric. Percentiles are
+// interpolated between the closest ranks like Postgres' percentile_cont.
+type SeriesStats struct {
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+	P50    float64 `json:"p50"`
+	P90    float64 `json:"p90"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+}
+
+type MetricsStats struct {
+	CPU     SeriesStats `json:"cpu_percent"`
+	Mem     SeriesStats `json:"mem_percent"`
+	Count   int64       `json:"count"`
+	FirstAt *time.Time  `json:"first_at"`
+	LastAt  *time.Time  `json:"last_at"`
+}
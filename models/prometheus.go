@@ -0,0 +1,28 @@
+package models
+
+// PromResponse is the envelope of the Prometheus HTTP API, returned by the
+// /api/v1 endpoints so Prometheus tooling can query Metrics-Monitor.
+type PromResponse struct {
+	Status    string      `json:"status"`
+	Data      interface{} `json:"data,omitempty"`
+	ErrorType string      `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// PromQueryData is the data of a query response. Result is a list of
+// PromSample for vectors, of PromSeries for matrices, and a single
+// [timestamp, "value"] pair for scalars.
+type PromQueryData struct {
+	ResultType string      `json:"resultType"`
+	Result     interface{} `json:"result"`
+}
+
+type PromSample struct {
+	Metric map[string]string `json:"metric"`
+	Value  [2]interface{}    `json:"value"`
+}
+
+type PromSeries struct {
+	Metric map[string]string `json:"metric"`
+	Values [][2]interface{}  `json:"values"`
+}
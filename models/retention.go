@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type RetentionRun struct {
+	Target     string    `json:"target"`
+	Retention  string    `json:"retention"`
+	Cutoff     time.Time `json:"cutoff"`
+	Deleted    int64     `json:"deleted"`
+	Batches    int       `json:"batches"`
+	Dropped    int       `json:"dropped_partitions"`
+	DurationMs int64     `json:"duration_ms"`
+	StartedAt  time.Time `json:"started_at"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type RetentionStatus struct {
+	Enabled      bool              `json:"enabled"`
+	Default      string            `json:"default"`
+	Overrides    map[string]string `json:"overrides"`
+	Interval     string            `json:"interval"`
+	BatchSize    int               `json:"batch_size"`
+	TotalDeleted int64             `json:"total_deleted"`
+	LastRuns     []RetentionRun    `json:"last_runs"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+const (
+	RollupTable1m = "metrics_1m"
+	RollupTable1h = "metrics_1h"
+	RollupTable1d = "metrics_1d"
+)
+
+// MetricsRollup summarises every raw sample of a host that falls into one
+// bucket.
+type MetricsRollup struct {
+	Host        string    `gorm:"primaryKey" json:"host"`
+	Bucket      time.Time `gorm:"primaryKey" json:"bucket"`
+	CPUMin      float64   `gorm:"not null" json:"cpu_min"`
+	CPUMax      float64   `gorm:"not null" json:"cpu_max"`
+	CPUAvg      float64   `gorm:"not null" json:"cpu_avg"`
+	CPULast     float64   `gorm:"not null" json:"cpu_last"`
+	MemMin      float64   `gorm:"not null" json:"mem_min"`
+	MemMax      float64   `gorm:"not null" json:"mem_max"`
+	MemAvg      float64   `gorm:"not null" json:"mem_avg"`
+	MemLast     float64   `gorm:"not null" json:"mem_last"`
+	SampleCount int64     `gorm:"not null" json:"sample_count"`
+	LastAt      time.Time `gorm:"not null" json:"last_at"`
+}
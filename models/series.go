@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Series is one named and labelled time series of the generic sample store,
+// which holds everything that does not fit the fixed CPU/memory columns of
+// Metrics, such as counters. The host label is also kept in Host so series
+// can be filtered like metrics.
+type Series struct {
+	ID          uint64            `gorm:"primaryKey" json:"id"`
+	Name        string            `gorm:"not null;index" json:"name"`
+	Host        string            `gorm:"not null;default:'';index" json:"host"`
+	Fingerprint string            `gorm:"not null;uniqueIndex" json:"-"`
+	Labels      map[string]string `gorm:"-" json:"labels"`
+}
+
+func (Series) TableName() string { return "series" }
+
+type SeriesLabel struct {
+	SeriesID uint64 `gorm:"primaryKey;autoIncrement:false"`
+	Key      string `gorm:"primaryKey"`
+	Value    string `gorm:"not null"`
+}
+
+type Sample struct {
+	SeriesID  uint64    `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	Timestamp time.Time `gorm:"primaryKey;column:ts" json:"ts"`
+	Value     float64   `gorm:"not null" json:"value"`
+}
+
+// SeriesSample is a sample together with the identity of its series, as
+// produced by collectors and receivers.
+type SeriesSample struct {
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Timestamp time.Time         `json:"ts"`
+	Value     float64           `json:"value"`
+}
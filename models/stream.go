@@ -0,0 +1,31 @@
+package models
+
+// StreamRequest is a control message a client sends on the metrics WebSocket.
+// Type is "subscribe" or "unsubscribe". A subscription may ask for Backfill,
+// e.g. "15m", of history averaged into Step wide points before the live
+// samples.
+type StreamRequest struct {
+	Type     string `json:"type"`
+	ID       string `json:"id"`
+	Selector string `json:"selector,omitempty"`
+	Backfill string `json:"backfill,omitempty"`
+	Step     string `json:"step,omitempty"`
+}
+
+// StreamMessage is a message the server sends on the metrics WebSocket. Type
+// is "subscribed", "unsubscribed", "backfill", "sample" or "error"; ID is the
+// subscription it belongs to.
+type StreamMessage struct {
+	Type   string         `json:"type"`
+	ID     string         `json:"id,omitempty"`
+	Sample *SeriesSample  `json:"sample,omitempty"`
+	Series []StreamSeries `json:"series,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// StreamSeries is the backfill of one series.
+type StreamSeries struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+	Points []Point           `json:"points"`
+}
@@ -0,0 +1,619 @@
+package otlp
+
+import (
+	"errors"
+	"math"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/internal/protoutil"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// maxValueDepth bounds how deeply array and key-value list values may nest.
+// The decoder recurses once per level, so without a bound a small gzipped
+// body could overflow the stack.
+const maxValueDepth = 100
+
+var errTooDeep = errors.New("attribute values nested too deeply")
+
+type message interface {
+	Unmarshal([]byte) error
+}
+
+// unmarshalFunc adapts a decoder that takes extra arguments to message.
+type unmarshalFunc func([]byte) error
+
+func (f unmarshalFunc) Unmarshal(b []byte) error { return f(b) }
+
+// Unmarshal decodes the protobuf encoding of an ExportMetricsServiceRequest.
+func (m *ExportMetricsServiceRequest) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			var rm ResourceMetrics
+			n, err := consumeMessage(b, &rm)
+			m.ResourceMetrics = append(m.ResourceMetrics, rm)
+			return n, err
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+// Marshal returns the protobuf encoding of an ExportMetricsServiceRequest.
+func (m *ExportMetricsServiceRequest) Marshal() []byte {
+	var b []byte
+	for i := range m.ResourceMetrics {
+		b = protoutil.AppendMessage(b, 1, m.ResourceMetrics[i].Marshal())
+	}
+	return b
+}
+
+func (m *ExportMetricsServiceResponse) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			m.PartialSuccess = &ExportMetricsPartialSuccess{}
+			return consumeMessage(b, m.PartialSuccess)
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *ExportMetricsServiceResponse) Marshal() []byte {
+	if m.PartialSuccess == nil {
+		return nil
+	}
+	return protoutil.AppendMessage(nil, 1, m.PartialSuccess.Marshal())
+}
+
+func (m *ExportMetricsPartialSuccess) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			m.RejectedDataPoints = Int64(v)
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			m.ErrorMessage = string(v)
+			return n, nil
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *ExportMetricsPartialSuccess) Marshal() []byte {
+	var b []byte
+	b = protoutil.AppendVarint(b, 1, uint64(m.RejectedDataPoints))
+	b = protoutil.AppendString(b, 2, m.ErrorMessage)
+	return b
+}
+
+func (m *ResourceMetrics) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			return consumeMessage(b, &m.Resource)
+		case num == 2 && typ == protowire.BytesType:
+			var sm ScopeMetrics
+			n, err := consumeMessage(b, &sm)
+			m.ScopeMetrics = append(m.ScopeMetrics, sm)
+			return n, err
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *ResourceMetrics) Marshal() []byte {
+	b := protoutil.AppendMessage(nil, 1, m.Resource.Marshal())
+	for i := range m.ScopeMetrics {
+		b = protoutil.AppendMessage(b, 2, m.ScopeMetrics[i].Marshal())
+	}
+	return b
+}
+
+func (m *Resource) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			return consumeKeyValue(b, &m.Attributes)
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *Resource) Marshal() []byte {
+	return appendKeyValues(nil, 1, m.Attributes)
+}
+
+func (m *ScopeMetrics) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			return consumeMessage(b, &m.Scope)
+		case num == 2 && typ == protowire.BytesType:
+			var metric Metric
+			n, err := consumeMessage(b, &metric)
+			m.Metrics = append(m.Metrics, metric)
+			return n, err
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *ScopeMetrics) Marshal() []byte {
+	b := protoutil.AppendMessage(nil, 1, m.Scope.Marshal())
+	for i := range m.Metrics {
+		b = protoutil.AppendMessage(b, 2, m.Metrics[i].Marshal())
+	}
+	return b
+}
+
+func (m *InstrumentationScope) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if (num == 1 || num == 2) && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if num == 1 {
+				m.Name = string(v)
+			} else {
+				m.Version = string(v)
+			}
+			return n, nil
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *InstrumentationScope) Marshal() []byte {
+	var b []byte
+	b = protoutil.AppendString(b, 1, m.Name)
+	b = protoutil.AppendString(b, 2, m.Version)
+	return b
+}
+
+func (m *Metric) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if typ != protowire.BytesType {
+			return protoutil.SkipField(num, typ, b)
+		}
+		switch num {
+		case 1, 2, 3:
+			v, n := protowire.ConsumeBytes(b)
+			switch num {
+			case 1:
+				m.Name = string(v)
+			case 2:
+				m.Description = string(v)
+			default:
+				m.Unit = string(v)
+			}
+			return n, nil
+		case 5:
+			m.Gauge = &Gauge{}
+			return consumeMessage(b, m.Gauge)
+		case 7:
+			m.Sum = &Sum{}
+			return consumeMessage(b, m.Sum)
+		case 9:
+			m.Histogram = &Histogram{}
+			return consumeMessage(b, m.Histogram)
+		case 10:
+			m.ExponentialHistogram = &OpaqueData{}
+			return consumeMessage(b, m.ExponentialHistogram)
+		case 11:
+			m.Summary = &OpaqueData{}
+			return consumeMessage(b, m.Summary)
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *Metric) Marshal() []byte {
+	var b []byte
+	b = protoutil.AppendString(b, 1, m.Name)
+	b = protoutil.AppendString(b, 2, m.Description)
+	b = protoutil.AppendString(b, 3, m.Unit)
+	switch {
+	case m.Gauge != nil:
+		b = protoutil.AppendMessage(b, 5, m.Gauge.Marshal())
+	case m.Sum != nil:
+		b = protoutil.AppendMessage(b, 7, m.Sum.Marshal())
+	case m.Histogram != nil:
+		b = protoutil.AppendMessage(b, 9, m.Histogram.Marshal())
+	}
+	return b
+}
+
+func (m *Gauge) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			return consumeNumberDataPoint(b, &m.DataPoints)
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *Gauge) Marshal() []byte {
+	var b []byte
+	for i := range m.DataPoints {
+		b = protoutil.AppendMessage(b, 1, m.DataPoints[i].Marshal())
+	}
+	return b
+}
+
+func (m *Sum) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			return consumeNumberDataPoint(b, &m.DataPoints)
+		case num == 2 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			m.AggregationTemporality = AggregationTemporality(v)
+			return n, nil
+		case num == 3 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			m.IsMonotonic = protowire.DecodeBool(v)
+			return n, nil
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *Sum) Marshal() []byte {
+	var b []byte
+	for i := range m.DataPoints {
+		b = protoutil.AppendMessage(b, 1, m.DataPoints[i].Marshal())
+	}
+	b = protoutil.AppendVarint(b, 2, uint64(m.AggregationTemporality))
+	b = protoutil.AppendVarint(b, 3, protowire.EncodeBool(m.IsMonotonic))
+	return b
+}
+
+func (m *Histogram) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var p HistogramDataPoint
+			n, err := consumeMessage(b, &p)
+			m.DataPoints = append(m.DataPoints, p)
+			return n, err
+		case num == 2 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			m.AggregationTemporality = AggregationTemporality(v)
+			return n, nil
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *Histogram) Marshal() []byte {
+	var b []byte
+	for i := range m.DataPoints {
+		b = protoutil.AppendMessage(b, 1, m.DataPoints[i].Marshal())
+	}
+	b = protoutil.AppendVarint(b, 2, uint64(m.AggregationTemporality))
+	return b
+}
+
+// Unmarshal only counts the data points of an unsupported metric type.
+func (m *OpaqueData) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			m.DataPoints = append(m.DataPoints, nil)
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *NumberDataPoint) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 7 && typ == protowire.BytesType:
+			return consumeKeyValue(b, &m.Attributes)
+		case (num == 2 || num == 3 || num == 4 || num == 6) && typ == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			switch num {
+			case 2:
+				m.StartTimeUnixNano = Uint64(v)
+			case 3:
+				m.TimeUnixNano = Uint64(v)
+			case 4:
+				f := math.Float64frombits(v)
+				m.AsDouble = &f
+			default:
+				i := Int64(v)
+				m.AsInt = &i
+			}
+			return n, nil
+		case num == 8 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			m.Flags = uint32(v)
+			return n, nil
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *NumberDataPoint) Marshal() []byte {
+	var b []byte
+	b = appendFixed64(b, 2, uint64(m.StartTimeUnixNano))
+	b = appendFixed64(b, 3, uint64(m.TimeUnixNano))
+	if m.AsDouble != nil {
+		b = protowire.AppendTag(b, 4, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(*m.AsDouble))
+	}
+	if m.AsInt != nil {
+		b = protowire.AppendTag(b, 6, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, uint64(*m.AsInt))
+	}
+	b = appendKeyValues(b, 7, m.Attributes)
+	b = protoutil.AppendVarint(b, 8, uint64(m.Flags))
+	return b
+}
+
+func (m *HistogramDataPoint) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 9 && typ == protowire.BytesType:
+			return consumeKeyValue(b, &m.Attributes)
+		case num == 6 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			for len(v) > 0 {
+				c, cn := protowire.ConsumeFixed64(v)
+				if cn < 0 {
+					return cn, nil
+				}
+				m.BucketCounts = append(m.BucketCounts, Uint64(c))
+				v = v[cn:]
+			}
+			return n, nil
+		case num == 7 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			for len(v) > 0 {
+				f, fn := protowire.ConsumeFixed64(v)
+				if fn < 0 {
+					return fn, nil
+				}
+				m.ExplicitBounds = append(m.ExplicitBounds, math.Float64frombits(f))
+				v = v[fn:]
+			}
+			return n, nil
+		case typ == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			f := math.Float64frombits(v)
+			switch num {
+			case 2:
+				m.StartTimeUnixNano = Uint64(v)
+			case 3:
+				m.TimeUnixNano = Uint64(v)
+			case 4:
+				m.Count = Uint64(v)
+			case 5:
+				m.Sum = &f
+			case 6:
+				m.BucketCounts = append(m.BucketCounts, Uint64(v))
+			case 7:
+				m.ExplicitBounds = append(m.ExplicitBounds, f)
+			case 11:
+				m.Min = &f
+			case 12:
+				m.Max = &f
+			}
+			return n, nil
+		case num == 10 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			m.Flags = uint32(v)
+			return n, nil
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *HistogramDataPoint) Marshal() []byte {
+	var b []byte
+	b = appendFixed64(b, 2, uint64(m.StartTimeUnixNano))
+	b = appendFixed64(b, 3, uint64(m.TimeUnixNano))
+	b = appendFixed64(b, 4, uint64(m.Count))
+	b = appendDouble(b, 5, m.Sum)
+	if len(m.BucketCounts) > 0 {
+		var packed []byte
+		for _, c := range m.BucketCounts {
+			packed = protowire.AppendFixed64(packed, uint64(c))
+		}
+		b = protoutil.AppendMessage(b, 6, packed)
+	}
+	if len(m.ExplicitBounds) > 0 {
+		var packed []byte
+		for _, f := range m.ExplicitBounds {
+			packed = protowire.AppendFixed64(packed, math.Float64bits(f))
+		}
+		b = protoutil.AppendMessage(b, 7, packed)
+	}
+	b = appendKeyValues(b, 9, m.Attributes)
+	b = protoutil.AppendVarint(b, 10, uint64(m.Flags))
+	b = appendDouble(b, 11, m.Min)
+	b = appendDouble(b, 12, m.Max)
+	return b
+}
+
+func (m *KeyValue) Unmarshal(b []byte) error {
+	return m.unmarshal(b, 0)
+}
+
+func (m *KeyValue) unmarshal(b []byte, depth int) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			m.Key = string(v)
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			return consumeMessage(b, unmarshalFunc(func(b []byte) error {
+				return m.Value.unmarshal(b, depth)
+			}))
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *KeyValue) Marshal() []byte {
+	b := protoutil.AppendString(nil, 1, m.Key)
+	return protoutil.AppendMessage(b, 2, m.Value.Marshal())
+}
+
+func (m *AnyValue) Unmarshal(b []byte) error {
+	return m.unmarshal(b, 0)
+}
+
+// unmarshal decodes an AnyValue nested depth arrays or key-value lists deep.
+func (m *AnyValue) unmarshal(b []byte, depth int) error {
+	if depth > maxValueDepth {
+		return errTooDeep
+	}
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			s := string(v)
+			m.StringValue = &s
+			return n, nil
+		case num == 2 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			t := protowire.DecodeBool(v)
+			m.BoolValue = &t
+			return n, nil
+		case num == 3 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			i := Int64(v)
+			m.IntValue = &i
+			return n, nil
+		case num == 4 && typ == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			f := math.Float64frombits(v)
+			m.DoubleValue = &f
+			return n, nil
+		case num == 5 && typ == protowire.BytesType:
+			m.ArrayValue = &ArrayValue{}
+			return consumeMessage(b, unmarshalFunc(func(b []byte) error {
+				return m.ArrayValue.unmarshal(b, depth+1)
+			}))
+		case num == 6 && typ == protowire.BytesType:
+			m.KvlistValue = &KeyValueList{}
+			return consumeMessage(b, unmarshalFunc(func(b []byte) error {
+				return m.KvlistValue.unmarshal(b, depth+1)
+			}))
+		case num == 7 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			m.BytesValue = append([]byte{}, v...)
+			return n, nil
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *AnyValue) Marshal() []byte {
+	var b []byte
+	switch {
+	case m.StringValue != nil:
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, *m.StringValue)
+	case m.BoolValue != nil:
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(*m.BoolValue))
+	case m.IntValue != nil:
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(*m.IntValue))
+	case m.DoubleValue != nil:
+		b = appendDouble(b, 4, m.DoubleValue)
+	case m.ArrayValue != nil:
+		var values []byte
+		for i := range m.ArrayValue.Values {
+			values = protoutil.AppendMessage(values, 1, m.ArrayValue.Values[i].Marshal())
+		}
+		b = protoutil.AppendMessage(b, 5, values)
+	case m.KvlistValue != nil:
+		b = protoutil.AppendMessage(b, 6, appendKeyValues(nil, 1, m.KvlistValue.Values))
+	case m.BytesValue != nil:
+		b = protoutil.AppendMessage(b, 7, m.BytesValue)
+	}
+	return b
+}
+
+func (m *ArrayValue) Unmarshal(b []byte) error {
+	return m.unmarshal(b, 0)
+}
+
+func (m *ArrayValue) unmarshal(b []byte, depth int) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			var v AnyValue
+			n, err := consumeMessage(b, unmarshalFunc(func(b []byte) error {
+				return v.unmarshal(b, depth)
+			}))
+			m.Values = append(m.Values, v)
+			return n, err
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *KeyValueList) Unmarshal(b []byte) error {
+	return m.unmarshal(b, 0)
+}
+
+func (m *KeyValueList) unmarshal(b []byte, depth int) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			var kv KeyValue
+			n, err := consumeMessage(b, unmarshalFunc(func(b []byte) error {
+				return kv.unmarshal(b, depth)
+			}))
+			m.Values = append(m.Values, kv)
+			return n, err
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func consumeKeyValue(b []byte, kvs *[]KeyValue) (int, error) {
+	var kv KeyValue
+	n, err := consumeMessage(b, &kv)
+	*kvs = append(*kvs, kv)
+	return n, err
+}
+
+func consumeNumberDataPoint(b []byte, points *[]NumberDataPoint) (int, error) {
+	var p NumberDataPoint
+	n, err := consumeMessage(b, &p)
+	*points = append(*points, p)
+	return n, err
+}
+
+// consumeMessage decodes the length delimited message at the start of b into
+// msg.
+func consumeMessage(b []byte, msg message) (int, error) {
+	v, n := protowire.ConsumeBytes(b)
+	if n < 0 {
+		return n, nil
+	}
+	return n, msg.Unmarshal(v)
+}
+
+func appendKeyValues(b []byte, num protowire.Number, kvs []KeyValue) []byte {
+	for i := range kvs {
+		b = protoutil.AppendMessage(b, num, kvs[i].Marshal())
+	}
+	return b
+}
+
+func appendFixed64(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, v)
+}
+
+func appendDouble(b []byte, num protowire.Number, f *float64) []byte {
+	if f == nil {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(b, math.Float64bits(*f))
+}
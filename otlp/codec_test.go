@@ -0,0 +1,230 @@
+package otlp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// The golden payloads are encoded and decoded by the generated OTLP types,
+// so every field this package handles is checked against the upstream wire
+// format.
+
+func upstreamString(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+// upstreamRequest returns the golden request. With unsupported set it also
+// holds the metric types and fields this package only skips or counts.
+func upstreamRequest(unsupported bool) *colmetricspb.ExportMetricsServiceRequest {
+	sum, min, max := 7.5, 0.25, 4.0
+	attrs := []*commonpb.KeyValue{
+		{Key: "host.name", Value: upstreamString("web-01")},
+		{Key: "retries", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: -3}}},
+		{Key: "sampled", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}}},
+		{Key: "ratio", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 0.5}}},
+		{Key: "id", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: []byte{0xca, 0xfe}}}},
+		{Key: "tags", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{
+			Values: []*commonpb.AnyValue{upstreamString("a"), upstreamString("b")},
+		}}}},
+		{Key: "owner", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+			Values: []*commonpb.KeyValue{{Key: "team", Value: upstreamString("infra")}},
+		}}}},
+	}
+	metrics := []*metricspb.Metric{
+		{Name: "queue.depth", Description: "Jobs waiting", Unit: "{job}", Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+			DataPoints: []*metricspb.NumberDataPoint{
+				{TimeUnixNano: 1700000000000000000, Value: &metricspb.NumberDataPoint_AsInt{AsInt: -7}},
+				{TimeUnixNano: 1700000001000000000, Flags: 1},
+			},
+		}}},
+		{Name: "http.server.requests", Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+			DataPoints: []*metricspb.NumberDataPoint{{
+				StartTimeUnixNano: 1690000000000000000,
+				TimeUnixNano:      1700000000000000000,
+				Value:             &metricspb.NumberDataPoint_AsDouble{AsDouble: 42.5},
+				Attributes:        []*commonpb.KeyValue{{Key: "http.route", Value: upstreamString("/pay")}},
+			}},
+		}}},
+		{Name: "latency", Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+			DataPoints: []*metricspb.HistogramDataPoint{{
+				TimeUnixNano:   1700000000000000000,
+				Count:          6,
+				Sum:            &sum,
+				BucketCounts:   []uint64{1, 2, 3},
+				ExplicitBounds: []float64{0.1, 0.5},
+				Attributes:     []*commonpb.KeyValue{{Key: "method", Value: upstreamString("GET")}},
+				Flags:          1,
+				Min:            &min,
+				Max:            &max,
+			}},
+		}}},
+	}
+	if unsupported {
+		metrics[0].Metadata = []*commonpb.KeyValue{{Key: "unknown", Value: upstreamString("skipped")}}
+		metrics[0].GetGauge().DataPoints[0].Exemplars = []*metricspb.Exemplar{{TimeUnixNano: 1, SpanId: []byte{1}}}
+		metrics = append(metrics,
+			&metricspb.Metric{Name: "sizes", Data: &metricspb.Metric_ExponentialHistogram{ExponentialHistogram: &metricspb.ExponentialHistogram{
+				DataPoints: []*metricspb.ExponentialHistogramDataPoint{{Count: 1}, {Count: 2}},
+			}}},
+			&metricspb.Metric{Name: "durations", Data: &metricspb.Metric_Summary{Summary: &metricspb.Summary{
+				DataPoints: []*metricspb.SummaryDataPoint{{Count: 1}},
+			}}},
+		)
+	}
+	return &colmetricspb.ExportMetricsServiceRequest{ResourceMetrics: []*metricspb.ResourceMetrics{{
+		Resource:  &resourcepb.Resource{Attributes: attrs},
+		SchemaUrl: "https://opentelemetry.io/schemas/1.21.0",
+		ScopeMetrics: []*metricspb.ScopeMetrics{{
+			Scope:   &commonpb.InstrumentationScope{Name: "app", Version: "1.2.0"},
+			Metrics: metrics,
+		}},
+	}}}
+}
+
+// wantRequest is upstreamRequest in the types of this package.
+func wantRequest(unsupported bool) ExportMetricsServiceRequest {
+	sum, min, max := 7.5, 0.25, 4.0
+	retries, sampled, ratio := Int64(-3), true, 0.5
+	asInt, asDouble := Int64(-7), 42.5
+	metrics := []Metric{
+		{Name: "queue.depth", Description: "Jobs waiting", Unit: "{job}", Gauge: &Gauge{DataPoints: []NumberDataPoint{
+			{TimeUnixNano: 1700000000000000000, AsInt: &asInt},
+			{TimeUnixNano: 1700000001000000000, Flags: FlagNoRecordedValue},
+		}}},
+		{Name: "http.server.requests", Sum: &Sum{
+			AggregationTemporality: AggregationTemporalityCumulative,
+			IsMonotonic:            true,
+			DataPoints: []NumberDataPoint{{
+				StartTimeUnixNano: 1690000000000000000,
+				TimeUnixNano:      1700000000000000000,
+				AsDouble:          &asDouble,
+				Attributes:        []KeyValue{{Key: "http.route", Value: StringValue("/pay")}},
+			}},
+		}},
+		{Name: "latency", Histogram: &Histogram{
+			AggregationTemporality: AggregationTemporalityDelta,
+			DataPoints: []HistogramDataPoint{{
+				TimeUnixNano:   1700000000000000000,
+				Count:          6,
+				Sum:            &sum,
+				BucketCounts:   []Uint64{1, 2, 3},
+				ExplicitBounds: []float64{0.1, 0.5},
+				Attributes:     []KeyValue{{Key: "method", Value: StringValue("GET")}},
+				Flags:          FlagNoRecordedValue,
+				Min:            &min,
+				Max:            &max,
+			}},
+		}},
+	}
+	if unsupported {
+		metrics = append(metrics,
+			Metric{Name: "sizes", ExponentialHistogram: &OpaqueData{DataPoints: make([]json.RawMessage, 2)}},
+			Metric{Name: "durations", Summary: &OpaqueData{DataPoints: make([]json.RawMessage, 1)}},
+		)
+	}
+	return ExportMetricsServiceRequest{ResourceMetrics: []ResourceMetrics{{
+		Resource: Resource{Attributes: []KeyValue{
+			{Key: "host.name", Value: StringValue("web-01")},
+			{Key: "retries", Value: AnyValue{IntValue: &retries}},
+			{Key: "sampled", Value: AnyValue{BoolValue: &sampled}},
+			{Key: "ratio", Value: AnyValue{DoubleValue: &ratio}},
+			{Key: "id", Value: AnyValue{BytesValue: []byte{0xca, 0xfe}}},
+			{Key: "tags", Value: AnyValue{ArrayValue: &ArrayValue{Values: []AnyValue{StringValue("a"), StringValue("b")}}}},
+			{Key: "owner", Value: AnyValue{KvlistValue: &KeyValueList{Values: []KeyValue{{Key: "team", Value: StringValue("infra")}}}}},
+		}},
+		ScopeMetrics: []ScopeMetrics{{
+			Scope:   InstrumentationScope{Name: "app", Version: "1.2.0"},
+			Metrics: metrics,
+		}},
+	}}}
+}
+
+func TestUnmarshalUpstreamRequest(t *testing.T) {
+	b, err := proto.Marshal(upstreamRequest(true))
+	if !assert.NoError(t, err) {
+		return
+	}
+	var got ExportMetricsServiceRequest
+	assert.NoError(t, got.Unmarshal(b))
+	assert.Equal(t, wantRequest(true), got)
+
+	b, err = protojson.Marshal(upstreamRequest(true))
+	if !assert.NoError(t, err) {
+		return
+	}
+	got = ExportMetricsServiceRequest{}
+	assert.NoError(t, json.Unmarshal(b, &got))
+	// Unsupported data points are kept as raw JSON, only their number matters.
+	for i := range got.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		m := &got.ResourceMetrics[0].ScopeMetrics[0].Metrics[i]
+		for _, data := range []*OpaqueData{m.ExponentialHistogram, m.Summary} {
+			if data != nil {
+				data.DataPoints = make([]json.RawMessage, len(data.DataPoints))
+			}
+		}
+	}
+	assert.Equal(t, wantRequest(true), got)
+}
+
+func TestMarshalRequestForUpstream(t *testing.T) {
+	want := wantRequest(false)
+	// The resource schema URL is not kept, so it is not written either.
+	upstream := upstreamRequest(false)
+	upstream.ResourceMetrics[0].SchemaUrl = ""
+
+	// The upstream encoder writes oneof fields last, so the messages are
+	// compared instead of the bytes.
+	var got colmetricspb.ExportMetricsServiceRequest
+	assert.NoError(t, proto.Unmarshal(want.Marshal(), &got))
+	assert.True(t, proto.Equal(upstream, &got), "got %v", &got)
+
+	j, err := json.Marshal(want)
+	if !assert.NoError(t, err) {
+		return
+	}
+	got.Reset()
+	assert.NoError(t, protojson.Unmarshal(j, &got))
+	assert.True(t, proto.Equal(upstream, &got), "got %v", &got)
+}
+
+func TestResponseUpstream(t *testing.T) {
+	upstream := &colmetricspb.ExportMetricsServiceResponse{PartialSuccess: &colmetricspb.ExportMetricsPartialSuccess{
+		RejectedDataPoints: 3,
+		ErrorMessage:       "summaries are not supported",
+	}}
+	want := ExportMetricsServiceResponse{PartialSuccess: &ExportMetricsPartialSuccess{
+		RejectedDataPoints: 3,
+		ErrorMessage:       "summaries are not supported",
+	}}
+
+	b, err := proto.Marshal(upstream)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var got ExportMetricsServiceResponse
+	assert.NoError(t, got.Unmarshal(b))
+	assert.Equal(t, want, got)
+	assert.Equal(t, b, want.Marshal())
+
+	j, err := json.Marshal(want)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var decoded colmetricspb.ExportMetricsServiceResponse
+	assert.NoError(t, protojson.Unmarshal(j, &decoded))
+	assert.True(t, proto.Equal(upstream, &decoded), "got %v", &decoded)
+
+	// A full success is an empty message.
+	assert.Empty(t, (&ExportMetricsServiceResponse{}).Marshal())
+}
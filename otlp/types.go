@@ -0,0 +1,235 @@
+// Package otlp encodes and decodes the OpenTelemetry metrics messages
+// exchanged over OTLP/HTTP, in both the protobuf and the JSON encoding. Only
+// the fields Metrics-Monitor uses are kept; unknown fields are skipped when
+// decoding.
+package otlp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+type ExportMetricsServiceRequest struct {
+	ResourceMetrics []ResourceMetrics `json:"resourceMetrics"`
+}
+
+type ExportMetricsServiceResponse struct {
+	PartialSuccess *ExportMetricsPartialSuccess `json:"partialSuccess,omitempty"`
+}
+
+type ExportMetricsPartialSuccess struct {
+	RejectedDataPoints Int64  `json:"rejectedDataPoints,omitempty"`
+	ErrorMessage       string `json:"errorMessage,omitempty"`
+}
+
+type ResourceMetrics struct {
+	Resource     Resource       `json:"resource"`
+	ScopeMetrics []ScopeMetrics `json:"scopeMetrics"`
+}
+
+type Resource struct {
+	Attributes []KeyValue `json:"attributes,omitempty"`
+}
+
+type ScopeMetrics struct {
+	Scope   InstrumentationScope `json:"scope"`
+	Metrics []Metric             `json:"metrics"`
+}
+
+type InstrumentationScope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// Metric holds one of Gauge, Sum and Histogram. Exponential histograms and
+// summaries are not supported; their data points are only kept to be counted
+// as rejected.
+type Metric struct {
+	Name                 string      `json:"name"`
+	Description          string      `json:"description,omitempty"`
+	Unit                 string      `json:"unit,omitempty"`
+	Gauge                *Gauge      `json:"gauge,omitempty"`
+	Sum                  *Sum        `json:"sum,omitempty"`
+	Histogram            *Histogram  `json:"histogram,omitempty"`
+	ExponentialHistogram *OpaqueData `json:"exponentialHistogram,omitempty"`
+	Summary              *OpaqueData `json:"summary,omitempty"`
+}
+
+type Gauge struct {
+	DataPoints []NumberDataPoint `json:"dataPoints"`
+}
+
+type Sum struct {
+	DataPoints             []NumberDataPoint      `json:"dataPoints"`
+	AggregationTemporality AggregationTemporality `json:"aggregationTemporality,omitempty"`
+	IsMonotonic            bool                   `json:"isMonotonic,omitempty"`
+}
+
+type Histogram struct {
+	DataPoints             []HistogramDataPoint   `json:"dataPoints"`
+	AggregationTemporality AggregationTemporality `json:"aggregationTemporality,omitempty"`
+}
+
+type OpaqueData struct {
+	DataPoints []json.RawMessage `json:"dataPoints"`
+}
+
+// FlagNoRecordedValue marks a data point without a value, e.g. the first
+// point after a series went away.
+const FlagNoRecordedValue = 1
+
+type NumberDataPoint struct {
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano Uint64     `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      Uint64     `json:"timeUnixNano,omitempty"`
+	AsDouble          *float64   `json:"asDouble,omitempty"`
+	AsInt             *Int64     `json:"asInt,omitempty"`
+	Flags             uint32     `json:"flags,omitempty"`
+}
+
+// Value returns the value of the point, whichever field holds it.
+func (p *NumberDataPoint) Value() float64 {
+	if p.AsInt != nil {
+		return float64(*p.AsInt)
+	}
+	if p.AsDouble != nil {
+		return *p.AsDouble
+	}
+	return 0
+}
+
+type HistogramDataPoint struct {
+	Attributes        []KeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano Uint64     `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      Uint64     `json:"timeUnixNano,omitempty"`
+	Count             Uint64     `json:"count,omitempty"`
+	Sum               *float64   `json:"sum,omitempty"`
+	BucketCounts      []Uint64   `json:"bucketCounts,omitempty"`
+	ExplicitBounds    []float64  `json:"explicitBounds,omitempty"`
+	Flags             uint32     `json:"flags,omitempty"`
+	Min               *float64   `json:"min,omitempty"`
+	Max               *float64   `json:"max,omitempty"`
+}
+
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+type AnyValue struct {
+	StringValue *string       `json:"stringValue,omitempty"`
+	BoolValue   *bool         `json:"boolValue,omitempty"`
+	IntValue    *Int64        `json:"intValue,omitempty"`
+	DoubleValue *float64      `json:"doubleValue,omitempty"`
+	ArrayValue  *ArrayValue   `json:"arrayValue,omitempty"`
+	KvlistValue *KeyValueList `json:"kvlistValue,omitempty"`
+	BytesValue  []byte        `json:"bytesValue,omitempty"`
+}
+
+type ArrayValue struct {
+	Values []AnyValue `json:"values"`
+}
+
+type KeyValueList struct {
+	Values []KeyValue `json:"values"`
+}
+
+// String renders the value as a label value. Arrays and key/value lists are
+// rendered as JSON.
+func (v AnyValue) String() string {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return strconv.FormatBool(*v.BoolValue)
+	case v.IntValue != nil:
+		return strconv.FormatInt(int64(*v.IntValue), 10)
+	case v.DoubleValue != nil:
+		return strconv.FormatFloat(*v.DoubleValue, 'g', -1, 64)
+	case v.BytesValue != nil:
+		return fmt.Sprintf("%x", v.BytesValue)
+	case v.ArrayValue != nil, v.KvlistValue != nil:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return ""
+}
+
+// StringValue returns an AnyValue holding s.
+func StringValue(s string) AnyValue {
+	return AnyValue{StringValue: &s}
+}
+
+type AggregationTemporality int32
+
+const (
+	AggregationTemporalityUnspecified AggregationTemporality = iota
+	AggregationTemporalityDelta
+	AggregationTemporalityCumulative
+)
+
+var temporalityNames = map[string]AggregationTemporality{
+	"AGGREGATION_TEMPORALITY_UNSPECIFIED": AggregationTemporalityUnspecified,
+	"AGGREGATION_TEMPORALITY_DELTA":       AggregationTemporalityDelta,
+	"AGGREGATION_TEMPORALITY_CUMULATIVE":  AggregationTemporalityCumulative,
+}
+
+// UnmarshalJSON accepts the enum as a number or by name.
+func (t *AggregationTemporality) UnmarshalJSON(b []byte) error {
+	var name string
+	if json.Unmarshal(b, &name) == nil {
+		v, ok := temporalityNames[name]
+		if !ok {
+			return fmt.Errorf("unknown aggregation temporality %q", name)
+		}
+		*t = v
+		return nil
+	}
+	var v int32
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*t = AggregationTemporality(v)
+	return nil
+}
+
+// Int64 is an int64 that is a string in JSON, as in the protobuf JSON
+// mapping. Numbers are accepted too.
+type Int64 int64
+
+func (v Int64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatInt(int64(v), 10))
+}
+
+func (v *Int64) UnmarshalJSON(b []byte) error {
+	n, err := strconv.ParseInt(unquote(b), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid int64 %s", b)
+	}
+	*v = Int64(n)
+	return nil
+}
+
+// Uint64 is the unsigned counterpart of Int64.
+type Uint64 uint64
+
+func (v Uint64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatUint(uint64(v), 10))
+}
+
+func (v *Uint64) UnmarshalJSON(b []byte) error {
+	n, err := strconv.ParseUint(unquote(b), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid uint64 %s", b)
+	}
+	*v = Uint64(n)
+	return nil
+}
+
+func unquote(b []byte) string {
+	if s, err := strconv.Unquote(string(b)); err == nil {
+		return s
+	}
+	return string(b)
+}
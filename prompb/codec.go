@@ -0,0 +1,291 @@
+package prompb
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/internal/protoutil"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Unmarshal decodes a WriteRequest.
+func (m *WriteRequest) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			var ts TimeSeries
+			if err := ts.Unmarshal(v); err != nil {
+				return 0, fmt.Errorf("timeseries: %w", err)
+			}
+			m.Timeseries = append(m.Timeseries, ts)
+			return n, nil
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+// Marshal encodes a WriteRequest.
+func (m *WriteRequest) Marshal() []byte {
+	var b []byte
+	for i := range m.Timeseries {
+		b = protoutil.AppendMessage(b, 1, m.Timeseries[i].Marshal())
+	}
+	return b
+}
+
+func (m *TimeSeries) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			var l Label
+			if err := l.Unmarshal(v); err != nil {
+				return 0, fmt.Errorf("label: %w", err)
+			}
+			m.Labels = append(m.Labels, l)
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			var s Sample
+			if err := s.Unmarshal(v); err != nil {
+				return 0, fmt.Errorf("sample: %w", err)
+			}
+			m.Samples = append(m.Samples, s)
+			return n, nil
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *TimeSeries) Marshal() []byte {
+	var b []byte
+	for i := range m.Labels {
+		b = protoutil.AppendMessage(b, 1, m.Labels[i].Marshal())
+	}
+	for i := range m.Samples {
+		b = protoutil.AppendMessage(b, 2, m.Samples[i].Marshal())
+	}
+	return b
+}
+
+func (m *Label) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if (num == 1 || num == 2) && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if num == 1 {
+				m.Name = string(v)
+			} else {
+				m.Value = string(v)
+			}
+			return n, nil
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *Label) Marshal() []byte {
+	var b []byte
+	b = protoutil.AppendString(b, 1, m.Name)
+	b = protoutil.AppendString(b, 2, m.Value)
+	return b
+}
+
+func (m *Sample) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			m.Value = math.Float64frombits(v)
+			return n, nil
+		case num == 2 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			m.Timestamp = int64(v)
+			return n, nil
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *Sample) Marshal() []byte {
+	var b []byte
+	if m.Value != 0 || math.Signbit(m.Value) {
+		b = protowire.AppendTag(b, 1, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(m.Value))
+	}
+	if m.Timestamp != 0 {
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(m.Timestamp))
+	}
+	return b
+}
+
+// Unmarshal decodes a ReadRequest.
+func (m *ReadRequest) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			var q Query
+			if err := q.Unmarshal(v); err != nil {
+				return 0, fmt.Errorf("query: %w", err)
+			}
+			m.Queries = append(m.Queries, q)
+			return n, nil
+		case num == 2 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			m.AcceptedResponseTypes = append(m.AcceptedResponseTypes, ResponseType(v))
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			// Packed repeated enum.
+			v, n := protowire.ConsumeBytes(b)
+			for len(v) > 0 {
+				t, tn := protowire.ConsumeVarint(v)
+				if tn < 0 {
+					return tn, nil
+				}
+				m.AcceptedResponseTypes = append(m.AcceptedResponseTypes, ResponseType(t))
+				v = v[tn:]
+			}
+			return n, nil
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+// Marshal encodes a ReadRequest.
+func (m *ReadRequest) Marshal() []byte {
+	var b []byte
+	for i := range m.Queries {
+		b = protoutil.AppendMessage(b, 1, m.Queries[i].Marshal())
+	}
+	if len(m.AcceptedResponseTypes) > 0 {
+		var packed []byte
+		for _, t := range m.AcceptedResponseTypes {
+			packed = protowire.AppendVarint(packed, uint64(t))
+		}
+		b = protoutil.AppendMessage(b, 2, packed)
+	}
+	return b
+}
+
+func (m *Query) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case (num == 1 || num == 2) && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if num == 1 {
+				m.StartTimestampMs = int64(v)
+			} else {
+				m.EndTimestampMs = int64(v)
+			}
+			return n, nil
+		case num == 3 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			var lm LabelMatcher
+			if err := lm.Unmarshal(v); err != nil {
+				return 0, fmt.Errorf("matcher: %w", err)
+			}
+			m.Matchers = append(m.Matchers, lm)
+			return n, nil
+		}
+		// Read hints (4) are advisory and ignored.
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *Query) Marshal() []byte {
+	var b []byte
+	b = protoutil.AppendVarint(b, 1, uint64(m.StartTimestampMs))
+	b = protoutil.AppendVarint(b, 2, uint64(m.EndTimestampMs))
+	for i := range m.Matchers {
+		b = protoutil.AppendMessage(b, 3, m.Matchers[i].Marshal())
+	}
+	return b
+}
+
+func (m *LabelMatcher) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			m.Type = MatcherType(v)
+			return n, nil
+		case (num == 2 || num == 3) && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if num == 2 {
+				m.Name = string(v)
+			} else {
+				m.Value = string(v)
+			}
+			return n, nil
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+func (m *LabelMatcher) Marshal() []byte {
+	var b []byte
+	b = protoutil.AppendVarint(b, 1, uint64(m.Type))
+	b = protoutil.AppendString(b, 2, m.Name)
+	b = protoutil.AppendString(b, 3, m.Value)
+	return b
+}
+
+// Unmarshal decodes a ReadResponse.
+func (m *ReadResponse) Unmarshal(b []byte) error {
+	return protoutil.DecodeFields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			var r QueryResult
+			if err := r.Unmarshal(v); err != nil {
+				return 0, fmt.Errorf("result: %w", err)
+			}
+			m.Results = append(m.Results, r)
+			return n, nil
+		}
+		return protoutil.SkipField(num, typ, b)
+	})
+}
+
+// Marshal encodes a ReadResponse.
+func (m *ReadResponse) Marshal() []byte {
+	var b []byte
+	for i := range m.Results {
+		b = protoutil.AppendMessage(b, 1, m.Results[i].Marshal())
+	}
+	return b
+}
+
+// Unmarshal decodes a QueryResult, which shares its wire format with
+// WriteRequest.
+func (m *QueryResult) Unmarshal(b []byte) error {
+	var w WriteRequest
+	if err := w.Unmarshal(b); err != nil {
+		return err
+	}
+	m.Timeseries = w.Timeseries
+	return nil
+}
+
+func (m *QueryResult) Marshal() []byte {
+	w := WriteRequest{Timeseries: m.Timeseries}
+	return w.Marshal()
+}
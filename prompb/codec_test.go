@@ -0,0 +1,140 @@
+package prompb
+
+import (
+	"testing"
+
+	upstream "github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+)
+
+// The golden payloads are encoded and decoded by the generated types of the
+// Prometheus repository, so every field this package handles is checked
+// against the upstream wire format.
+
+func TestWriteRequestUpstream(t *testing.T) {
+	golden := upstream.WriteRequest{Timeseries: []upstream.TimeSeries{
+		{
+			Labels:  []upstream.Label{{Name: "__name__", Value: "http_requests_total"}, {Name: "job", Value: "api"}},
+			Samples: []upstream.Sample{{Value: 42.5, Timestamp: 1700000000000}, {Value: 0, Timestamp: -1}},
+		},
+		{
+			Labels:  []upstream.Label{{Name: "__name__", Value: "up"}},
+			Samples: []upstream.Sample{{Value: 1, Timestamp: 1700000015000}},
+		},
+	}}
+	want := WriteRequest{Timeseries: []TimeSeries{
+		{
+			Labels:  []Label{{Name: "__name__", Value: "http_requests_total"}, {Name: "job", Value: "api"}},
+			Samples: []Sample{{Value: 42.5, Timestamp: 1700000000000}, {Value: 0, Timestamp: -1}},
+		},
+		{
+			Labels:  []Label{{Name: "__name__", Value: "up"}},
+			Samples: []Sample{{Value: 1, Timestamp: 1700000015000}},
+		},
+	}}
+
+	b, err := golden.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, b, want.Marshal(), "Expected the bytes of the upstream encoder")
+
+	// Exemplars, native histograms and metadata are skipped.
+	withUnknown := golden
+	withUnknown.Timeseries = append([]upstream.TimeSeries{}, golden.Timeseries...)
+	withUnknown.Timeseries[0].Exemplars = []upstream.Exemplar{{Labels: []upstream.Label{{Name: "trace_id", Value: "abc"}}, Value: 1, Timestamp: 1}}
+	withUnknown.Timeseries[1].Histograms = []upstream.Histogram{{Count: &upstream.Histogram_CountInt{CountInt: 3}, Sum: 1.5, Schema: 1}}
+	withUnknown.Metadata = []upstream.MetricMetadata{{Type: upstream.MetricMetadata_COUNTER, MetricFamilyName: "http_requests_total"}}
+	b, err = withUnknown.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+	var got WriteRequest
+	assert.NoError(t, got.Unmarshal(b))
+	assert.Equal(t, want, got)
+}
+
+func TestReadRequestUpstream(t *testing.T) {
+	golden := upstream.ReadRequest{
+		Queries: []*upstream.Query{
+			{
+				StartTimestampMs: 1700000000000,
+				EndTimestampMs:   1700003600000,
+				Matchers: []*upstream.LabelMatcher{
+					{Type: upstream.LabelMatcher_EQ, Name: "__name__", Value: "up"},
+					{Type: upstream.LabelMatcher_NEQ, Name: "job", Value: "db"},
+					{Type: upstream.LabelMatcher_RE, Name: "instance", Value: "web-.*"},
+					{Type: upstream.LabelMatcher_NRE, Name: "env", Value: "dev|test"},
+				},
+			},
+			{EndTimestampMs: 1},
+		},
+		AcceptedResponseTypes: []upstream.ReadRequest_ResponseType{
+			upstream.ReadRequest_STREAMED_XOR_CHUNKS,
+			upstream.ReadRequest_SAMPLES,
+		},
+	}
+	want := ReadRequest{
+		Queries: []Query{
+			{
+				StartTimestampMs: 1700000000000,
+				EndTimestampMs:   1700003600000,
+				Matchers: []LabelMatcher{
+					{Type: MatchEqual, Name: "__name__", Value: "up"},
+					{Type: MatchNotEqual, Name: "job", Value: "db"},
+					{Type: MatchRegexp, Name: "instance", Value: "web-.*"},
+					{Type: MatchNotRegexp, Name: "env", Value: "dev|test"},
+				},
+			},
+			{EndTimestampMs: 1},
+		},
+		AcceptedResponseTypes: []ResponseType{ResponseStreamedXORChunks, ResponseSamples},
+	}
+
+	b, err := golden.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, b, want.Marshal(), "Expected the bytes of the upstream encoder")
+
+	// Read hints are skipped.
+	golden.Queries[0].Hints = &upstream.ReadHints{StepMs: 15000, Func: "rate", Grouping: []string{"job"}, By: true, RangeMs: 60000}
+	b, err = golden.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+	var got ReadRequest
+	assert.NoError(t, got.Unmarshal(b))
+	assert.Equal(t, want, got)
+}
+
+func TestReadResponseUpstream(t *testing.T) {
+	golden := upstream.ReadResponse{Results: []*upstream.QueryResult{
+		{Timeseries: []*upstream.TimeSeries{{
+			Labels:  []upstream.Label{{Name: "__name__", Value: "up"}, {Name: "host", Value: "web-01"}},
+			Samples: []upstream.Sample{{Value: 1, Timestamp: 1700000000000}, {Value: 0.5, Timestamp: 1700000015000}},
+		}}},
+		{},
+	}}
+	want := ReadResponse{Results: []QueryResult{
+		{Timeseries: []TimeSeries{{
+			Labels:  []Label{{Name: "__name__", Value: "up"}, {Name: "host", Value: "web-01"}},
+			Samples: []Sample{{Value: 1, Timestamp: 1700000000000}, {Value: 0.5, Timestamp: 1700000015000}},
+		}}},
+		{},
+	}}
+
+	b, err := golden.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, b, want.Marshal(), "Expected the bytes of the upstream encoder")
+
+	var got ReadResponse
+	assert.NoError(t, got.Unmarshal(b))
+	assert.Equal(t, want, got)
+
+	var decoded upstream.ReadResponse
+	assert.NoError(t, decoded.Unmarshal(want.Marshal()))
+	assert.Equal(t, golden, decoded)
+}
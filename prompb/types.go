@@ -0,0 +1,66 @@
+// Package prompb encodes and decodes the protobuf messages of the Prometheus
+// remote write and remote read protocols. Only the fields Metrics-Monitor
+// uses are kept; unknown fields are skipped when decoding.
+package prompb
+
+type Label struct {
+	Name  string
+	Value string
+}
+
+type Sample struct {
+	Value     float64
+	Timestamp int64 // milliseconds since the Unix epoch
+}
+
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+type WriteRequest struct {
+	Timeseries []TimeSeries
+}
+
+type MatcherType int32
+
+const (
+	MatchEqual MatcherType = iota
+	MatchNotEqual
+	MatchRegexp
+	MatchNotRegexp
+)
+
+type LabelMatcher struct {
+	Type  MatcherType
+	Name  string
+	Value string
+}
+
+type Query struct {
+	StartTimestampMs int64
+	EndTimestampMs   int64
+	Matchers         []LabelMatcher
+}
+
+type ResponseType int32
+
+const (
+	ResponseSamples ResponseType = iota
+	ResponseStreamedXORChunks
+)
+
+type ReadRequest struct {
+	Queries []Query
+	// AcceptedResponseTypes lists the response types the client understands;
+	// empty means samples only.
+	AcceptedResponseTypes []ResponseType
+}
+
+type QueryResult struct {
+	Timeseries []TimeSeries
+}
+
+type ReadResponse struct {
+	Results []QueryResult
+}
@@ -0,0 +1,136 @@
+package promql
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Expr is a node of a parsed query.
+type Expr interface {
+	Type() ValueType
+}
+
+type NumberLiteral struct {
+	Value float64
+}
+
+type ParenExpr struct {
+	Expr Expr
+}
+
+type UnaryExpr struct {
+	Expr Expr
+}
+
+// VectorSelector selects the latest sample of every matching series.
+type VectorSelector struct {
+	Name     string
+	Matchers []*Matcher
+
+	series []Series
+}
+
+// MatrixSelector selects the samples of the last Range of every matching
+// series.
+type MatrixSelector struct {
+	Vector *VectorSelector
+	Range  time.Duration
+}
+
+type Call struct {
+	Func *Function
+	Args []Expr
+}
+
+// AggregateExpr is sum, avg, min, max or count, optionally by or without
+// some labels.
+type AggregateExpr struct {
+	Op       string
+	Expr     Expr
+	Grouping []string
+	Without  bool
+}
+
+// VectorMatching is the on or ignoring clause of a binary operation between
+// two vectors.
+type VectorMatching struct {
+	On     bool
+	Labels []string
+}
+
+type BinaryExpr struct {
+	Op       string
+	LHS      Expr
+	RHS      Expr
+	Matching *VectorMatching
+}
+
+func (NumberLiteral) Type() ValueType   { return ValueTypeScalar }
+func (e *ParenExpr) Type() ValueType    { return e.Expr.Type() }
+func (e *UnaryExpr) Type() ValueType    { return e.Expr.Type() }
+func (*VectorSelector) Type() ValueType { return ValueTypeVector }
+func (*MatrixSelector) Type() ValueType { return ValueTypeMatrix }
+func (e *Call) Type() ValueType         { return e.Func.ReturnType }
+func (*AggregateExpr) Type() ValueType  { return ValueTypeVector }
+
+func (e *BinaryExpr) Type() ValueType {
+	if e.LHS.Type() == ValueTypeScalar && e.RHS.Type() == ValueTypeScalar {
+		return ValueTypeScalar
+	}
+	return ValueTypeVector
+}
+
+// MatchType is the operator of a label matcher.
+type MatchType string
+
+const (
+	MatchEqual     MatchType = "="
+	MatchNotEqual  MatchType = "!="
+	MatchRegexp    MatchType = "=~"
+	MatchNotRegexp MatchType = "!~"
+)
+
+// Matcher restricts a label of the selected series. A label a series does
+// not have counts as the empty string.
+type Matcher struct {
+	Type  MatchType
+	Name  string
+	Value string
+
+	re *regexp.Regexp
+}
+
+// NewMatcher returns a matcher, compiling the regular expression of the
+// regexp types. Regular expressions are anchored at both ends.
+func NewMatcher(t MatchType, name, value string) (*Matcher, error) {
+	m := &Matcher{Type: t, Name: name, Value: value}
+	if t == MatchRegexp || t == MatchNotRegexp {
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", value, err)
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+// Matches reports whether a label value satisfies the matcher.
+func (m *Matcher) Matches(value string) bool {
+	switch m.Type {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(value)
+	default:
+		return !m.re.MatchString(value)
+	}
+}
+
+// Anchored is the regular expression of a regexp matcher as it has to be
+// matched by a storage engine.
+func (m *Matcher) Anchored() string {
+	return "^(?:" + m.Value + ")$"
+}
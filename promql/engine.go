@@ -0,0 +1,401 @@
+package promql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultLookbackDelta is how far back an instant vector selector looks
+	// for the latest sample of a series.
+	DefaultLookbackDelta = 5 * time.Minute
+
+	// MaxPoints bounds the steps of a range query, like Prometheus.
+	MaxPoints = 11000
+)
+
+var ErrTooManyPoints = fmt.Errorf("exceeded maximum resolution of %d points per timeseries, try decreasing the query resolution (?step=XX)", MaxPoints)
+
+// Querier loads the series matching all matchers with their samples
+// between start and end.
+type Querier interface {
+	Select(ctx context.Context, matchers []*Matcher, start, end time.Time) ([]Series, error)
+}
+
+// Engine evaluates queries against a Querier.
+type Engine struct {
+	Querier       Querier
+	LookbackDelta time.Duration
+}
+
+// Instant evaluates query at ts. The result is a Scalar, a Vector, or a
+// Matrix when query is a range vector selector.
+func (e *Engine) Instant(ctx context.Context, query string, ts time.Time) (Value, error) {
+	expr, err := ParseExpr(query)
+	if err != nil {
+		return nil, err
+	}
+	ev, err := e.prepare(ctx, expr, ts, ts)
+	if err != nil {
+		return nil, err
+	}
+	return ev.eval(expr, ts.UnixMilli())
+}
+
+// Range evaluates query at every step from start to end.
+func (e *Engine) Range(ctx context.Context, query string, start, end time.Time, step time.Duration) (Matrix, error) {
+	if step <= 0 {
+		return nil, errors.New("zero or negative query resolution step widths are not accepted")
+	}
+	// Timestamps are in milliseconds, a shorter step would never advance.
+	if step < time.Millisecond {
+		return nil, errors.New("query resolution step widths under 1ms are not accepted")
+	}
+	if end.Before(start) {
+		return nil, errors.New("end timestamp must not be before start time")
+	}
+	if end.Sub(start)/step > MaxPoints {
+		return nil, ErrTooManyPoints
+	}
+
+	expr, err := ParseExpr(query)
+	if err != nil {
+		return nil, err
+	}
+	if expr.Type() != ValueTypeVector && expr.Type() != ValueTypeScalar {
+		return nil, fmt.Errorf("invalid expression type %q for range query, must be scalar or instant vector", expr.Type())
+	}
+	ev, err := e.prepare(ctx, expr, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int)
+	var result Matrix
+	for ts := start.UnixMilli(); ts <= end.UnixMilli(); ts += step.Milliseconds() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		value, err := ev.eval(expr, ts)
+		if err != nil {
+			return nil, err
+		}
+
+		var samples Vector
+		switch v := value.(type) {
+		case Scalar:
+			samples = Vector{{Labels: Labels{}, Point: Point(v)}}
+		case Vector:
+			samples = v
+		}
+		for _, s := range samples {
+			key := s.Labels.Fingerprint()
+			i, ok := index[key]
+			if !ok {
+				i = len(result)
+				index[key] = i
+				result = append(result, Series{Labels: s.Labels})
+			}
+			result[i].Points = append(result[i].Points, Point{T: ts, V: s.V})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Labels.Fingerprint() < result[j].Labels.Fingerprint() })
+	return result, nil
+}
+
+type evaluator struct {
+	lookback int64
+}
+
+// prepare loads the samples every selector of expr needs to be evaluated
+// between start and end.
+func (e *Engine) prepare(ctx context.Context, expr Expr, start, end time.Time) (*evaluator, error) {
+	lookback := e.LookbackDelta
+	if lookback <= 0 {
+		lookback = DefaultLookbackDelta
+	}
+
+	var walk func(Expr) error
+	load := func(vs *VectorSelector, window time.Duration) error {
+		series, err := e.Querier.Select(ctx, vs.Matchers, start.Add(-window), end)
+		if err != nil {
+			return err
+		}
+		for _, s := range series {
+			sort.Slice(s.Points, func(i, j int) bool { return s.Points[i].T < s.Points[j].T })
+		}
+		vs.series = series
+		return nil
+	}
+	walk = func(expr Expr) error {
+		switch n := expr.(type) {
+		case *VectorSelector:
+			return load(n, lookback)
+		case *MatrixSelector:
+			return load(n.Vector, n.Range)
+		case *ParenExpr:
+			return walk(n.Expr)
+		case *UnaryExpr:
+			return walk(n.Expr)
+		case *AggregateExpr:
+			return walk(n.Expr)
+		case *Call:
+			for _, arg := range n.Args {
+				if err := walk(arg); err != nil {
+					return err
+				}
+			}
+		case *BinaryExpr:
+			if err := walk(n.LHS); err != nil {
+				return err
+			}
+			return walk(n.RHS)
+		}
+		return nil
+	}
+	if err := walk(expr); err != nil {
+		return nil, err
+	}
+	return &evaluator{lookback: lookback.Milliseconds()}, nil
+}
+
+func (ev *evaluator) eval(expr Expr, ts int64) (Value, error) {
+	switch n := expr.(type) {
+	case NumberLiteral:
+		return Scalar{T: ts, V: n.Value}, nil
+
+	case *ParenExpr:
+		return ev.eval(n.Expr, ts)
+
+	case *UnaryExpr:
+		value, err := ev.eval(n.Expr, ts)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := value.(Scalar); ok {
+			return Scalar{T: ts, V: -s.V}, nil
+		}
+		var out Vector
+		for _, s := range value.(Vector) {
+			out = append(out, Sample{Labels: s.Labels.withoutName(), Point: Point{T: ts, V: -s.V}})
+		}
+		return out, nil
+
+	case *VectorSelector:
+		var out Vector
+		for _, s := range n.series {
+			points := window(s.Points, ts-ev.lookback, ts)
+			if len(points) > 0 {
+				out = append(out, Sample{Labels: s.Labels, Point: Point{T: ts, V: points[len(points)-1].V}})
+			}
+		}
+		return out, nil
+
+	case *MatrixSelector:
+		var out Matrix
+		for _, s := range n.Vector.series {
+			points := window(s.Points, ts-n.Range.Milliseconds(), ts)
+			if len(points) > 0 {
+				out = append(out, Series{Labels: s.Labels, Points: points})
+			}
+		}
+		return out, nil
+
+	case *Call:
+		args := make([]Value, len(n.Args))
+		for i, arg := range n.Args {
+			value, err := ev.eval(arg, ts)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = value
+		}
+		return n.Func.call(args, n, ts), nil
+
+	case *AggregateExpr:
+		value, err := ev.eval(n.Expr, ts)
+		if err != nil {
+			return nil, err
+		}
+		return aggregate(n, value.(Vector), ts), nil
+
+	case *BinaryExpr:
+		lhs, err := ev.eval(n.LHS, ts)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := ev.eval(n.RHS, ts)
+		if err != nil {
+			return nil, err
+		}
+		return binary(n, lhs, rhs, ts)
+	}
+	return nil, fmt.Errorf("unsupported expression %T", expr)
+}
+
+// window returns the points with a timestamp in (from, to].
+func window(points []Point, from, to int64) []Point {
+	i := sort.Search(len(points), func(i int) bool { return points[i].T > from })
+	j := sort.Search(len(points), func(i int) bool { return points[i].T > to })
+	return points[i:j]
+}
+
+func aggregate(n *AggregateExpr, in Vector, ts int64) Vector {
+	type group struct {
+		labels Labels
+		values []float64
+	}
+	var groups []*group
+	index := make(map[string]*group)
+
+	for _, s := range in {
+		labels := Labels{}
+		if n.Without {
+			labels = s.Labels.withoutName()
+			for _, name := range n.Grouping {
+				delete(labels, name)
+			}
+		} else {
+			for _, name := range n.Grouping {
+				if value, ok := s.Labels[name]; ok {
+					labels[name] = value
+				}
+			}
+		}
+
+		key := labels.Fingerprint()
+		g, ok := index[key]
+		if !ok {
+			g = &group{labels: labels}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.values = append(g.values, s.V)
+	}
+
+	out := make(Vector, 0, len(groups))
+	for _, g := range groups {
+		points := make([]Point, len(g.values))
+		for i, v := range g.values {
+			points[i].V = v
+		}
+		var v float64
+		switch n.Op {
+		case "sum":
+			v = sumOf(points)
+		case "avg":
+			v = avgOf(points)
+		case "min":
+			v = minOf(points)
+		case "max":
+			v = maxOf(points)
+		case "count":
+			v = float64(len(points))
+		}
+		out = append(out, Sample{Labels: g.labels, Point: Point{T: ts, V: v}})
+	}
+	return out
+}
+
+func binary(n *BinaryExpr, lhs, rhs Value, ts int64) (Value, error) {
+	ls, lScalar := lhs.(Scalar)
+	rs, rScalar := rhs.(Scalar)
+
+	switch {
+	case lScalar && rScalar:
+		return Scalar{T: ts, V: arithmetic(n.Op, ls.V, rs.V)}, nil
+
+	case rScalar:
+		var out Vector
+		for _, s := range lhs.(Vector) {
+			out = append(out, Sample{Labels: s.Labels.withoutName(), Point: Point{T: ts, V: arithmetic(n.Op, s.V, rs.V)}})
+		}
+		return out, nil
+
+	case lScalar:
+		var out Vector
+		for _, s := range rhs.(Vector) {
+			out = append(out, Sample{Labels: s.Labels.withoutName(), Point: Point{T: ts, V: arithmetic(n.Op, ls.V, s.V)}})
+		}
+		return out, nil
+	}
+
+	matching := n.Matching
+	if matching == nil {
+		matching = &VectorMatching{}
+	}
+
+	right := make(map[string]Sample)
+	for _, s := range rhs.(Vector) {
+		key := matchingLabels(s.Labels, matching).Fingerprint()
+		if _, dup := right[key]; dup {
+			return nil, fmt.Errorf("found duplicate series for the match group %s on the right hand-side of the operation, many-to-many matching not allowed", key)
+		}
+		right[key] = s
+	}
+
+	seen := make(map[string]bool)
+	var out Vector
+	for _, s := range lhs.(Vector) {
+		labels := matchingLabels(s.Labels, matching)
+		key := labels.Fingerprint()
+		r, ok := right[key]
+		if !ok {
+			continue
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("found duplicate series for the match group %s on the left hand-side of the operation, many-to-many matching not allowed", key)
+		}
+		seen[key] = true
+
+		if !matching.On {
+			labels = s.Labels.withoutName()
+			for _, name := range matching.Labels {
+				delete(labels, name)
+			}
+		}
+		out = append(out, Sample{Labels: labels, Point: Point{T: ts, V: arithmetic(n.Op, s.V, r.V)}})
+	}
+	return out, nil
+}
+
+// matchingLabels are the labels two series have to share to be matched.
+func matchingLabels(labels Labels, matching *VectorMatching) Labels {
+	if matching.On {
+		out := Labels{}
+		for _, name := range matching.Labels {
+			if value, ok := labels[name]; ok {
+				out[name] = value
+			}
+		}
+		return out
+	}
+	out := labels.withoutName()
+	for _, name := range matching.Labels {
+		delete(out, name)
+	}
+	return out
+}
+
+func arithmetic(op string, l, r float64) float64 {
+	switch op {
+	case "+":
+		return l + r
+	case "-":
+		return l - r
+	case "*":
+		return l * r
+	case "/":
+		return l / r
+	case "%":
+		return math.Mod(l, r)
+	case "^":
+		return math.Pow(l, r)
+	}
+	return math.NaN()
+}
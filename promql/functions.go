@@ -0,0 +1,194 @@
+package promql
+
+import (
+	"math"
+	"sort"
+)
+
+// Function is a supported PromQL function. call gets the evaluated
+// arguments at time ts; range vector arguments hold the samples of each
+// series within the range.
+type Function struct {
+	Name       string
+	ArgTypes   []ValueType
+	ReturnType ValueType
+
+	call func(args []Value, call *Call, ts int64) Vector
+}
+
+var functions = map[string]*Function{
+	"rate":               {Name: "rate", ArgTypes: []ValueType{ValueTypeMatrix}, ReturnType: ValueTypeVector, call: funcRate(true, true)},
+	"increase":           {Name: "increase", ArgTypes: []ValueType{ValueTypeMatrix}, ReturnType: ValueTypeVector, call: funcRate(true, false)},
+	"delta":              {Name: "delta", ArgTypes: []ValueType{ValueTypeMatrix}, ReturnType: ValueTypeVector, call: funcRate(false, false)},
+	"irate":              {Name: "irate", ArgTypes: []ValueType{ValueTypeMatrix}, ReturnType: ValueTypeVector, call: funcIrate},
+	"avg_over_time":      {Name: "avg_over_time", ArgTypes: []ValueType{ValueTypeMatrix}, ReturnType: ValueTypeVector, call: overTime(avgOf)},
+	"min_over_time":      {Name: "min_over_time", ArgTypes: []ValueType{ValueTypeMatrix}, ReturnType: ValueTypeVector, call: overTime(minOf)},
+	"max_over_time":      {Name: "max_over_time", ArgTypes: []ValueType{ValueTypeMatrix}, ReturnType: ValueTypeVector, call: overTime(maxOf)},
+	"sum_over_time":      {Name: "sum_over_time", ArgTypes: []ValueType{ValueTypeMatrix}, ReturnType: ValueTypeVector, call: overTime(sumOf)},
+	"count_over_time":    {Name: "count_over_time", ArgTypes: []ValueType{ValueTypeMatrix}, ReturnType: ValueTypeVector, call: overTime(func(points []Point) float64 { return float64(len(points)) })},
+	"last_over_time":     {Name: "last_over_time", ArgTypes: []ValueType{ValueTypeMatrix}, ReturnType: ValueTypeVector, call: overTime(func(points []Point) float64 { return points[len(points)-1].V })},
+	"quantile_over_time": {Name: "quantile_over_time", ArgTypes: []ValueType{ValueTypeScalar, ValueTypeMatrix}, ReturnType: ValueTypeVector, call: funcQuantileOverTime},
+}
+
+// funcRate implements rate, increase and delta with the extrapolation of
+// Prometheus: the change seen between the first and last sample of the
+// range is extended towards the range boundaries, but not past where a
+// counter would have been zero.
+func funcRate(isCounter, isRate bool) func([]Value, *Call, int64) Vector {
+	return func(args []Value, call *Call, ts int64) Vector {
+		rangeMs := call.Args[0].(*MatrixSelector).Range.Milliseconds()
+		rangeStart, rangeEnd := ts-rangeMs, ts
+
+		var out Vector
+		for _, series := range args[0].(Matrix) {
+			points := series.Points
+			if len(points) < 2 {
+				continue
+			}
+			first, last := points[0], points[len(points)-1]
+
+			result := last.V - first.V
+			if isCounter {
+				prev := first.V
+				for _, p := range points[1:] {
+					if p.V < prev {
+						result += prev
+					}
+					prev = p.V
+				}
+			}
+
+			durationToStart := float64(first.T-rangeStart) / 1000
+			durationToEnd := float64(rangeEnd-last.T) / 1000
+			sampledInterval := float64(last.T-first.T) / 1000
+			averageBetweenSamples := sampledInterval / float64(len(points)-1)
+
+			if isCounter && result > 0 && first.V >= 0 {
+				if durationToZero := sampledInterval * (first.V / result); durationToZero < durationToStart {
+					durationToStart = durationToZero
+				}
+			}
+
+			threshold := averageBetweenSamples * 1.1
+			extrapolateTo := sampledInterval
+			if durationToStart < threshold {
+				extrapolateTo += durationToStart
+			} else {
+				extrapolateTo += averageBetweenSamples / 2
+			}
+			if durationToEnd < threshold {
+				extrapolateTo += durationToEnd
+			} else {
+				extrapolateTo += averageBetweenSamples / 2
+			}
+
+			result *= extrapolateTo / sampledInterval
+			if isRate {
+				result /= float64(rangeMs) / 1000
+			}
+			out = append(out, Sample{Labels: series.Labels.withoutName(), Point: Point{T: ts, V: result}})
+		}
+		return out
+	}
+}
+
+func funcIrate(args []Value, _ *Call, ts int64) Vector {
+	var out Vector
+	for _, series := range args[0].(Matrix) {
+		points := series.Points
+		if len(points) < 2 {
+			continue
+		}
+		prev, last := points[len(points)-2], points[len(points)-1]
+		delta := last.V - prev.V
+		if last.V < prev.V {
+			delta = last.V // counter reset
+		}
+		dt := float64(last.T-prev.T) / 1000
+		if dt == 0 {
+			continue
+		}
+		out = append(out, Sample{Labels: series.Labels.withoutName(), Point: Point{T: ts, V: delta / dt}})
+	}
+	return out
+}
+
+func overTime(reduce func([]Point) float64) func([]Value, *Call, int64) Vector {
+	return func(args []Value, _ *Call, ts int64) Vector {
+		var out Vector
+		for _, series := range args[0].(Matrix) {
+			if len(series.Points) == 0 {
+				continue
+			}
+			out = append(out, Sample{Labels: series.Labels.withoutName(), Point: Point{T: ts, V: reduce(series.Points)}})
+		}
+		return out
+	}
+}
+
+func funcQuantileOverTime(args []Value, _ *Call, ts int64) Vector {
+	q := args[0].(Scalar).V
+	var out Vector
+	for _, series := range args[1].(Matrix) {
+		if len(series.Points) == 0 {
+			continue
+		}
+		values := make([]float64, len(series.Points))
+		for i, p := range series.Points {
+			values[i] = p.V
+		}
+		out = append(out, Sample{Labels: series.Labels.withoutName(), Point: Point{T: ts, V: quantile(q, values)}})
+	}
+	return out
+}
+
+// quantile interpolates the q-quantile of values like Prometheus.
+func quantile(q float64, values []float64) float64 {
+	switch {
+	case len(values) == 0 || math.IsNaN(q):
+		return math.NaN()
+	case q < 0:
+		return math.Inf(-1)
+	case q > 1:
+		return math.Inf(1)
+	}
+	sort.Float64s(values)
+
+	rank := q * float64(len(values)-1)
+	lower := math.Max(0, math.Floor(rank))
+	upper := math.Min(float64(len(values)-1), lower+1)
+	weight := rank - math.Floor(rank)
+	return values[int(lower)]*(1-weight) + values[int(upper)]*weight
+}
+
+func sumOf(points []Point) float64 {
+	var sum float64
+	for _, p := range points {
+		sum += p.V
+	}
+	return sum
+}
+
+func avgOf(points []Point) float64 {
+	return sumOf(points) / float64(len(points))
+}
+
+func minOf(points []Point) float64 {
+	m := points[0].V
+	for _, p := range points[1:] {
+		if p.V < m || math.IsNaN(m) {
+			m = p.V
+		}
+	}
+	return m
+}
+
+func maxOf(points []Point) float64 {
+	m := points[0].V
+	for _, p := range points[1:] {
+		if p.V > m || math.IsNaN(m) {
+			m = p.V
+		}
+	}
+	return m
+}
@@ -0,0 +1,150 @@
+package promql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokDuration
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBrace
+	tokRBrace
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of input"
+	}
+	return strconv.Quote(t.text)
+}
+
+// lex splits a query into tokens. The contents of [...] are read as one
+// duration token.
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(input) && input[i] != '\n' {
+				i++
+			}
+		case isIdentStart(c):
+			start := i
+			for i < len(input) && isIdentChar(input[i]) {
+				i++
+			}
+			word := input[start:i]
+			kind := tokIdent
+			if strings.EqualFold(word, "inf") || strings.EqualFold(word, "nan") {
+				kind = tokNumber
+			}
+			tokens = append(tokens, token{kind, word, start})
+		case isDigit(c) || (c == '.' && i+1 < len(input) && isDigit(input[i+1])):
+			start := i
+			for i < len(input) && (isDigit(input[i]) || input[i] == '.') {
+				i++
+			}
+			if i < len(input) && (input[i] == 'e' || input[i] == 'E') {
+				j := i + 1
+				if j < len(input) && (input[j] == '+' || input[j] == '-') {
+					j++
+				}
+				if j < len(input) && isDigit(input[j]) {
+					for i = j; i < len(input) && isDigit(input[i]); i++ {
+					}
+				}
+			}
+			tokens = append(tokens, token{tokNumber, input[start:i], start})
+		case c == '"' || c == '\'' || c == '`':
+			start := i
+			i++
+			for i < len(input) && input[i] != c {
+				if input[i] == '\\' && c != '`' {
+					i++
+				}
+				i++
+			}
+			if i >= len(input) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			text := input[start:i]
+			if c == '\'' {
+				text = `"` + strings.ReplaceAll(strings.ReplaceAll(text[1:len(text)-1], `\'`, `'`), `"`, `\"`) + `"`
+			}
+			value, err := strconv.Unquote(text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %w", start, err)
+			}
+			tokens = append(tokens, token{tokString, value, start})
+		case c == '[':
+			end := strings.IndexByte(input[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated range at position %d", i)
+			}
+			tokens = append(tokens, token{tokDuration, strings.TrimSpace(input[i+1 : i+end]), i})
+			i += end + 1
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == '{':
+			tokens = append(tokens, token{tokLBrace, "{", i})
+			i++
+		case c == '}':
+			tokens = append(tokens, token{tokRBrace, "}", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+		default:
+			op := ""
+			for _, candidate := range []string{"=~", "!~", "!=", "==", "=", "+", "-", "*", "/", "%", "^"} {
+				if strings.HasPrefix(input[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			tokens = append(tokens, token{tokOp, op, i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(input)}), nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,413 @@
+package promql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/utils"
+)
+
+// aggregations are the supported aggregation operators.
+var aggregations = map[string]bool{
+	"sum":   true,
+	"avg":   true,
+	"min":   true,
+	"max":   true,
+	"count": true,
+}
+
+// binaryPrecedence orders the arithmetic operators; ^ is handled apart
+// since it is right associative and binds tighter than unary minus.
+var binaryPrecedence = map[string]int{
+	"+": 1,
+	"-": 1,
+	"*": 2,
+	"/": 2,
+	"%": 2,
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// ParseExpr parses a query of the supported PromQL subset.
+func ParseExpr(input string) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, p.errorf(t, "expected %s, got %s", what, t)
+	}
+	return t, nil
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("parse error at position %d: %s", t.pos+1, fmt.Sprintf(format, args...))
+}
+
+// parseBinary parses operators of at least minPrec by precedence climbing.
+func (p *parser) parseBinary(minPrec int) (Expr, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		prec, ok := binaryPrecedence[t.text]
+		if t.kind != tokOp || !ok || prec < minPrec {
+			return lhs, nil
+		}
+		p.next()
+
+		matching, err := p.parseMatching()
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		if lhs, err = newBinaryExpr(t, t.text, lhs, rhs, matching, p); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if t := p.peek(); t.kind == tokOp && (t.text == "-" || t.text == "+") {
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if expr.Type() == ValueTypeMatrix {
+			return nil, p.errorf(t, "unary %s is not allowed on a range vector", t.text)
+		}
+		if t.text == "+" {
+			return expr, nil
+		}
+		if n, ok := expr.(NumberLiteral); ok {
+			return NumberLiteral{Value: -n.Value}, nil
+		}
+		return &UnaryExpr{Expr: expr}, nil
+	}
+	return p.parsePower()
+}
+
+func (p *parser) parsePower() (Expr, error) {
+	lhs, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokOp && t.text == "^" {
+		p.next()
+		matching, err := p.parseMatching()
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return newBinaryExpr(t, "^", lhs, rhs, matching, p)
+	}
+	return lhs, nil
+}
+
+// parseMatching parses an optional on(...) or ignoring(...) after a binary
+// operator.
+func (p *parser) parseMatching() (*VectorMatching, error) {
+	t := p.peek()
+	if t.kind != tokIdent || (t.text != "on" && t.text != "ignoring") {
+		return nil, nil
+	}
+	p.next()
+	labels, err := p.parseLabelList()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind == tokIdent && (next.text == "group_left" || next.text == "group_right") {
+		return nil, p.errorf(next, "%s is not supported", next.text)
+	}
+	return &VectorMatching{On: t.text == "on", Labels: labels}, nil
+}
+
+func newBinaryExpr(t token, op string, lhs, rhs Expr, matching *VectorMatching, p *parser) (Expr, error) {
+	if lhs.Type() == ValueTypeMatrix || rhs.Type() == ValueTypeMatrix {
+		return nil, p.errorf(t, "binary %s is not allowed on a range vector", op)
+	}
+	if matching != nil && (lhs.Type() != ValueTypeVector || rhs.Type() != ValueTypeVector) {
+		return nil, p.errorf(t, "vector matching only applies between two vectors")
+	}
+	return &BinaryExpr{Op: op, LHS: lhs, RHS: rhs, Matching: matching}, nil
+}
+
+// parsePostfix parses a primary expression and an optional [range].
+func (p *parser) parsePostfix() (Expr, error) {
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	if t.kind != tokDuration {
+		return expr, nil
+	}
+	p.next()
+	vs, ok := expr.(*VectorSelector)
+	if !ok {
+		return nil, p.errorf(t, "ranges are only allowed on vector selectors")
+	}
+	d, err := parseDuration(t.text)
+	if err != nil {
+		return nil, p.errorf(t, "%v", err)
+	}
+	return &MatrixSelector{Vector: vs, Range: d}, nil
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, p.errorf(t, "invalid number %s", t)
+		}
+		return NumberLiteral{Value: v}, nil
+
+	case tokLParen:
+		expr, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, `")"`); err != nil {
+			return nil, err
+		}
+		return &ParenExpr{Expr: expr}, nil
+
+	case tokLBrace:
+		p.pos--
+		return p.parseSelector("")
+
+	case tokIdent:
+		if aggregations[t.text] {
+			if next := p.peek(); next.kind == tokLParen || (next.kind == tokIdent && (next.text == "by" || next.text == "without")) {
+				return p.parseAggregation(t)
+			}
+		}
+		if p.peek().kind == tokLParen {
+			return p.parseCall(t)
+		}
+		return p.parseSelector(t.text)
+	}
+	return nil, p.errorf(t, "unexpected %s", t)
+}
+
+func (p *parser) parseSelector(name string) (Expr, error) {
+	vs := &VectorSelector{Name: name}
+	if name != "" {
+		m, _ := NewMatcher(MatchEqual, MetricNameLabel, name)
+		vs.Matchers = append(vs.Matchers, m)
+	}
+
+	if p.peek().kind == tokLBrace {
+		p.next()
+		for p.peek().kind != tokRBrace {
+			label, err := p.expect(tokIdent, "label name")
+			if err != nil {
+				return nil, err
+			}
+			op := p.next()
+			if op.kind != tokOp || (op.text != "=" && op.text != "!=" && op.text != "=~" && op.text != "!~") {
+				return nil, p.errorf(op, "expected label matching operator, got %s", op)
+			}
+			value, err := p.expect(tokString, "label value string")
+			if err != nil {
+				return nil, err
+			}
+			m, err := NewMatcher(MatchType(op.text), label.text, value.text)
+			if err != nil {
+				return nil, p.errorf(value, "%v", err)
+			}
+			if m.Name == MetricNameLabel && m.Type == MatchEqual && vs.Name == "" {
+				vs.Name = m.Value
+			}
+			vs.Matchers = append(vs.Matchers, m)
+
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+		if _, err := p.expect(tokRBrace, `"}"`); err != nil {
+			return nil, err
+		}
+	}
+
+	// Like Prometheus, refuse selectors that would match every series.
+	for _, m := range vs.Matchers {
+		if !m.Matches("") {
+			return vs, nil
+		}
+	}
+	return nil, fmt.Errorf("vector selector must contain at least one non-empty matcher")
+}
+
+func (p *parser) parseAggregation(op token) (Expr, error) {
+	agg := &AggregateExpr{Op: op.text}
+
+	parseGrouping := func() error {
+		t := p.peek()
+		if t.kind != tokIdent || (t.text != "by" && t.text != "without") {
+			return nil
+		}
+		p.next()
+		labels, err := p.parseLabelList()
+		if err != nil {
+			return err
+		}
+		agg.Grouping, agg.Without = labels, t.text == "without"
+		return nil
+	}
+
+	if err := parseGrouping(); err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(tokLParen, `"("`); err != nil {
+		return nil, err
+	}
+	expr, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	if expr.Type() != ValueTypeVector {
+		return nil, p.errorf(op, "%s expects an instant vector, got %s", op.text, expr.Type())
+	}
+	agg.Expr = expr
+	if _, err := p.expect(tokRParen, `")"`); err != nil {
+		return nil, err
+	}
+	if agg.Grouping == nil {
+		if err := parseGrouping(); err != nil {
+			return nil, err
+		}
+	}
+	return agg, nil
+}
+
+func (p *parser) parseCall(name token) (Expr, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, p.errorf(name, "unknown function %s", name)
+	}
+	p.next() // (
+
+	call := &Call{Func: fn}
+	for p.peek().kind != tokRParen {
+		arg, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(tokRParen, `")"`); err != nil {
+		return nil, err
+	}
+
+	if len(call.Args) != len(fn.ArgTypes) {
+		return nil, p.errorf(name, "%s expects %d argument(s), got %d", fn.Name, len(fn.ArgTypes), len(call.Args))
+	}
+	for i, arg := range call.Args {
+		if arg.Type() != fn.ArgTypes[i] {
+			return nil, p.errorf(name, "argument %d of %s must be a %s, got %s", i+1, fn.Name, fn.ArgTypes[i], arg.Type())
+		}
+		// Range functions read the range of their selector, so a
+		// parenthesized selector such as rate((foo[5m])) is unwrapped.
+		for paren, ok := arg.(*ParenExpr); ok && arg.Type() == ValueTypeMatrix; paren, ok = arg.(*ParenExpr) {
+			arg = paren.Expr
+		}
+		call.Args[i] = arg
+	}
+	return call, nil
+}
+
+func (p *parser) parseLabelList() ([]string, error) {
+	if _, err := p.expect(tokLParen, `"("`); err != nil {
+		return nil, err
+	}
+	labels := []string{}
+	for p.peek().kind != tokRParen {
+		label, err := p.expect(tokIdent, "label name")
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, label.text)
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(tokRParen, `")"`); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// parseDuration reads a PromQL duration such as 5m or 1h30m.
+func parseDuration(s string) (time.Duration, error) {
+	d, err := utils.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// ParseDuration reads a duration query parameter, either a PromQL duration
+// or a number of seconds.
+func ParseDuration(s string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		d := time.Duration(seconds * float64(time.Second))
+		if d < time.Millisecond {
+			return 0, fmt.Errorf("invalid duration %q, must be at least 1ms", s)
+		}
+		return d, nil
+	}
+	d, err := parseDuration(strings.TrimSpace(s))
+	if err == nil && d < time.Millisecond {
+		return 0, fmt.Errorf("invalid duration %q, must be at least 1ms", s)
+	}
+	return d, err
+}
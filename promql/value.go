@@ -0,0 +1,102 @@
+package promql
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MetricNameLabel holds the metric name among the labels of a series.
+const MetricNameLabel = "__name__"
+
+// Labels are the labels of a series, including its name.
+type Labels map[string]string
+
+// Fingerprint identifies a label set.
+func (l Labels) Fingerprint() string {
+	keys := make([]string, 0, len(l))
+	for key := range l {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(l[key]))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+func (l Labels) copy() Labels {
+	c := make(Labels, len(l))
+	for key, value := range l {
+		c[key] = value
+	}
+	return c
+}
+
+func (l Labels) withoutName() Labels {
+	c := l.copy()
+	delete(c, MetricNameLabel)
+	return c
+}
+
+// Point is one sample, T being a Unix timestamp in milliseconds.
+type Point struct {
+	T int64
+	V float64
+}
+
+// Series is a label set with its samples in time order.
+type Series struct {
+	Labels Labels
+	Points []Point
+}
+
+// Sample is a value of one series at one time.
+type Sample struct {
+	Labels Labels
+	Point
+}
+
+// ValueType names the type of an expression result, as in the Prometheus
+// HTTP API.
+type ValueType string
+
+const (
+	ValueTypeScalar ValueType = "scalar"
+	ValueTypeVector ValueType = "vector"
+	ValueTypeMatrix ValueType = "matrix"
+)
+
+// Value is a Scalar, Vector or Matrix.
+type Value interface {
+	Type() ValueType
+}
+
+type Scalar Point
+
+type Vector []Sample
+
+type Matrix []Series
+
+func (Scalar) Type() ValueType { return ValueTypeScalar }
+func (Vector) Type() ValueType { return ValueTypeVector }
+func (Matrix) Type() ValueType { return ValueTypeMatrix }
+
+// FormatValue formats a sample value the way Prometheus does.
+func FormatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -1,6 +1,10 @@
 package router
 
 import (
+	"net/http"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/config"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/dashboard"
 	_ "github.com/ROHITHSAKTHIVEL/Metrics-Monitor/docs"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/handler"
 	"github.com/gin-gonic/gin"
@@ -15,8 +19,41 @@ func SetRouter(apiRouter *gin.Engine) {
 		metrics.GET("/", handler.GetAllMetrics)
 		metrics.GET("", handler.GetMetricsByTimeRange)
 		metrics.GET("/average", handler.GetAverageMetrics)
+		metrics.GET("/stats", handler.GetMetricsStats)
+		metrics.GET("/aggregate", handler.AggregateMetrics)
+		metrics.GET("/stream", handler.StreamMetrics)
+		metrics.GET("/ws", handler.MetricsWebSocket)
+		metrics.GET("/chart", handler.RenderChart)
+	}
+	hosts := apiRouter.Group("/hosts")
+	{
+		hosts.GET("", handler.GetHosts)
+		hosts.GET("/:id", handler.GetHost)
+		hosts.PUT("/:id/labels", handler.RequireIngestToken(), handler.SetHostLabels)
+		hosts.DELETE("/:id/labels/:key", handler.RequireIngestToken(), handler.DeleteHostLabel)
+	}
+	prom := apiRouter.Group("/api/v1")
+	{
+		prom.GET("/query", handler.PromQuery)
+		prom.POST("/query", handler.PromQuery)
+		prom.GET("/query_range", handler.PromQueryRange)
+		prom.POST("/query_range", handler.PromQueryRange)
+		prom.GET("/labels", handler.PromLabels)
+		prom.GET("/label/:name/values", handler.PromLabelValues)
+	}
+	if path := config.Cfg.PrometheusPath; path != "" {
+		apiRouter.GET(path, handler.PrometheusExposition)
 	}
+	apiRouter.POST("/ingest", handler.RequireIngestToken(), handler.IngestMetrics)
+	apiRouter.POST("/api/v1/write", handler.RequireIngestToken(), handler.RemoteWrite)
+	apiRouter.POST("/api/v1/read", handler.RemoteRead)
+	apiRouter.POST("/v1/metrics", handler.RequireIngestToken(), handler.OTLPMetrics)
+	apiRouter.POST("/write", handler.RequireInfluxToken(), handler.InfluxWrite)
+	apiRouter.POST("/api/v2/write", handler.RequireInfluxToken(), handler.InfluxWrite)
+	apiRouter.GET("/retention", handler.GetRetentionStatus)
 	apiRouter.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	apiRouter.GET("/health", handler.HealthCheck)
+	apiRouter.StaticFS("/dashboard", dashboard.FS())
+	apiRouter.GET("/", func(c *gin.Context) { c.Redirect(http.StatusFound, "/dashboard/") })
 
 }
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"go.uber.org/zap"
+)
+
+// agentPushBatch is the number of samples sent per request.
+const agentPushBatch = 500
+
+var agentClient = &http.Client{Timeout: 10 * time.Second}
+
+// agentMetrics and agentSeries hold samples collected in agent mode until
+// they are pushed.
+var (
+	agentMetrics = &sampleBuffer[models.Metrics]{name: "agent"}
+	agentSeries  = &sampleBuffer[models.SeriesSample]{name: "agent series"}
+)
+
+// agentHostInfo is sent along with every push.
+var agentHostInfo struct {
+	sync.Mutex
+	info models.HostInfo
+}
+
+// agentSink sends collected samples to the push buffers. When the server is
+// unreachable for long each buffer keeps the newest cfg.AgentBufferSize
+// samples.
+type agentSink struct{}
+
+func newAgentSink(cfg *models.Config) agentSink {
+	agentHostInfo.Lock()
+	agentHostInfo.info = CollectHostInfo()
+	agentHostInfo.Unlock()
+	agentMetrics.Lock()
+	agentMetrics.size = cfg.AgentBufferSize
+	agentMetrics.Unlock()
+	agentSeries.Lock()
+	agentSeries.size = cfg.AgentBufferSize
+	agentSeries.Unlock()
+	return agentSink{}
+}
+
+func (agentSink) Name() string { return "agent" }
+
+func (agentSink) Write(_ context.Context, batch models.SampleBatch) error {
+	agentMetrics.add(batch.Metrics)
+	agentSeries.add(batch.Samples)
+	return nil
+}
+
+// AgentPusher pushes buffered samples to the server every
+// cfg.AgentPushInterval and flushes once more when ctx is cancelled.
+func AgentPusher(ctx context.Context, cfg *models.Config, errChan chan error) {
+	ticker := time.NewTicker(cfg.AgentPushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := FlushAgentBuffer(ctx, cfg); err != nil {
+				errChan <- err
+			}
+		case <-ctx.Done():
+			logger.Log.Info("Stopping Agent Pusher...")
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := FlushAgentBuffer(flushCtx, cfg); err != nil {
+				logger.Log.Error("Final agent flush failed", zap.Error(err))
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// FlushAgentBuffer pushes everything buffered so far, or a heartbeat when
+// nothing is buffered. Samples of a failed push stay buffered and are
+// retried on the next flush.
+func FlushAgentBuffer(ctx context.Context, cfg *models.Config) error {
+	for pushed := false; ; pushed = true {
+		batch := agentMetrics.peek(agentPushBatch)
+		series := agentSeries.peek(agentPushBatch)
+		agentHostInfo.Lock()
+		info := agentHostInfo.info
+		agentHostInfo.Unlock()
+
+		if len(batch) == 0 && len(series) == 0 && pushed {
+			return nil
+		}
+		labels := cfg.HostLabels
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		if err := PushMetrics(ctx, cfg, models.IngestBatch{Host: Hostname(), HostInfo: &info, Labels: labels, Metrics: batch, Samples: series}); err != nil {
+			return err
+		}
+		if len(batch) == 0 && len(series) == 0 {
+			return nil
+		}
+		agentMetrics.remove(len(batch))
+		agentSeries.remove(len(series))
+	}
+}
+
+// PushMetrics POSTs one batch to the server's ingest endpoint.
+func PushMetrics(ctx context.Context, cfg *models.Config, batch models.IngestBatch) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.AgentServerURL+"/ingest", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.AgentToken != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.AgentToken)
+	}
+
+	resp, err := agentClient.Do(req)
+	if err != nil {
+		logger.Log.Error("Failed to push metrics", zap.String("server", cfg.AgentServerURL), zap.Error(err))
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		err := fmt.Errorf("push to %s failed: %s", cfg.AgentServerURL, resp.Status)
+		logger.Log.Error("Failed to push metrics", zap.Error(err))
+		return err
+	}
+
+	logger.Log.Debug("Pushed metrics", zap.Int("samples", len(batch.Metrics)), zap.Int("series_samples", len(batch.Samples)))
+	return nil
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/database"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxAggregatePoints bounds the buckets one query may ask for.
+const maxAggregatePoints = 11000
+
+var (
+	ErrUnknownMetric    = errors.New("unknown metric")
+	ErrUnknownAggregate = errors.New("unknown aggregation function, expected avg, min, max, sum, count, last, rate, irate or increase")
+	ErrNotCounter       = errors.New("rate, irate and increase only apply to counters")
+	ErrInvalidStep      = errors.New("step must be a positive number of seconds")
+	ErrTooManyPoints    = fmt.Errorf("query would return more than %d points, increase step", maxAggregatePoints)
+)
+
+// aggregateColumns maps the metric names of the query API to metrics
+// columns; only these are ever put into SQL. Any other valid name refers to
+// a series of the sample store.
+var aggregateColumns = map[string]string{
+	"cpu_percent": "cpu_percent",
+	"mem_percent": "mem_percent",
+}
+
+var seriesName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+var aggregateFuncs = map[string]string{
+	"avg":   "AVG(%s)",
+	"min":   "MIN(%s)",
+	"max":   "MAX(%s)",
+	"sum":   "SUM(%s)",
+	"count": "COUNT(%s)",
+	"last":  "",
+}
+
+// counterFuncs are evaluated in Go per series since they need every sample
+// in order to detect counter resets.
+var counterFuncs = map[string]bool{
+	"rate":     true,
+	"irate":    true,
+	"increase": true,
+}
+
+// aggregateSource is the table a query reads its values from. host is the
+// column naming the host of a row, reachable after join.
+type aggregateSource struct {
+	table  string
+	join   string
+	time   string
+	value  string
+	host   string
+	filter func(*gorm.DB) *gorm.DB
+}
+
+// ValidateAggregateQuery checks q before it is turned into SQL.
+func ValidateAggregateQuery(q models.AggregateQuery) error {
+	_, gauge := aggregateColumns[q.Metric]
+	if !gauge && !seriesName.MatchString(q.Metric) {
+		return fmt.Errorf("%w %q", ErrUnknownMetric, q.Metric)
+	}
+	if _, ok := aggregateFuncs[q.Func]; !ok && !counterFuncs[q.Func] {
+		return ErrUnknownAggregate
+	}
+	if gauge && counterFuncs[q.Func] {
+		return ErrNotCounter
+	}
+	if q.Step < time.Second || q.Step%time.Second != 0 {
+		return ErrInvalidStep
+	}
+	if q.End.Sub(q.Start)/q.Step > maxAggregatePoints {
+		return ErrTooManyPoints
+	}
+	return nil
+}
+
+// AggregateMetrics returns one point per non-empty bucket, buckets being
+// aligned on q.Start. The bucketing is done by the database: time_bucket on
+// TimescaleDB, date_bin on Postgres and epoch arithmetic on SQLite. Values
+// of several series or hosts falling in one bucket are aggregated together,
+// and counter functions are summed over the matching series.
+func AggregateMetrics(ctx context.Context, q models.AggregateQuery) ([]models.Point, error) {
+	res, err := aggregate(ctx, q, false)
+	if err != nil || len(res) == 0 {
+		return nil, err
+	}
+	return res[0].Points, nil
+}
+
+// aggregate runs q and returns its points in host name order, one entry per
+// host with points when byHost is set and a single entry otherwise.
+func aggregate(ctx context.Context, q models.AggregateQuery, byHost bool) ([]models.HostPoints, error) {
+	if database.DB == nil {
+		logger.Log.Error("Database is not initialized")
+		return nil, gorm.ErrInvalidDB
+	}
+	if err := ValidateAggregateQuery(q); err != nil {
+		return nil, err
+	}
+
+	source := aggregateSource{table: "metrics", time: "created_at", value: aggregateColumns[q.Metric], host: "host", filter: filterMetrics(q.Filter)}
+	if source.value == "" {
+		exists, err := SeriesExists(ctx, q.Metric)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("%w %q", ErrUnknownMetric, q.Metric)
+		}
+		source = aggregateSource{table: "samples", join: "JOIN series ON series.id = samples.series_id", time: "ts", value: "value", host: "series.host", filter: func(db *gorm.DB) *gorm.DB {
+			return db.Where("series_id IN (?)", seriesIDs(ctx, q.Metric, q.Filter))
+		}}
+	}
+
+	if counterFuncs[q.Func] {
+		return aggregateCounters(ctx, q, source, byHost)
+	}
+
+	base := database.DB.WithContext(ctx).
+		Table(source.table).
+		Where(fmt.Sprintf("%[1]s >= ? AND %[1]s <= ?", source.time), q.Start, q.End).
+		Scopes(source.filter)
+	host, group := "'' AS host", "bucket"
+	if byHost {
+		if source.join != "" {
+			base = base.Joins(source.join)
+		}
+		host, group = source.host+" AS host", "host, bucket"
+	}
+
+	var points []hostPoint
+
+	if database.DB.Dialector.Name() == "postgres" {
+		bucket := fmt.Sprintf("date_bin(?::interval, %s, ?::timestamptz)", source.time)
+		if database.Timescale {
+			bucket = fmt.Sprintf("time_bucket(?::interval, %s, ?::timestamptz)", source.time)
+		}
+		value := fmt.Sprintf(aggregateFuncs[q.Func], source.value)
+		if q.Func == "last" {
+			value = fmt.Sprintf("(array_agg(%s ORDER BY %s DESC))[1]", source.value, source.time)
+		}
+
+		var rows []struct {
+			Host   string
+			Bucket time.Time
+			Value  float64
+		}
+		if err := base.
+			Select(fmt.Sprintf("%s, %s AS bucket, %s AS value", host, bucket, value), fmt.Sprintf("%d seconds", int64(q.Step.Seconds())), q.Start).
+			Group(group).
+			Order(group).
+			Scan(&rows).Error; err != nil {
+			logger.Log.Error("Error aggregating metrics:", zap.Error(err))
+			return nil, err
+		}
+		for _, row := range rows {
+			points = append(points, hostPoint{row.Host, models.Point{Time: row.Bucket.UTC(), Value: row.Value}})
+		}
+		return groupByHost(points), nil
+	}
+
+	// SQLite has no interval type, so buckets are numbered from q.Start.
+	bucket := fmt.Sprintf("CAST((CAST(strftime('%%s', %s) AS INTEGER) - ?) / ? AS INTEGER)", source.time)
+	value := fmt.Sprintf(aggregateFuncs[q.Func], source.value) + " AS value"
+	if q.Func == "last" {
+		// With MAX() SQLite takes the bare column from the row holding the maximum.
+		value = fmt.Sprintf("MAX(%s) AS last_at, %s AS value", source.time, source.value)
+	}
+
+	var rows []struct {
+		Host   string
+		Bucket int64
+		Value  float64
+	}
+	if err := base.
+		Select(fmt.Sprintf("%s, %s AS bucket, %s", host, bucket, value), q.Start.Unix(), int64(q.Step.Seconds())).
+		Group(group).
+		Order(group).
+		Scan(&rows).Error; err != nil {
+		logger.Log.Error("Error aggregating metrics:", zap.Error(err))
+		return nil, err
+	}
+	for _, row := range rows {
+		points = append(points, hostPoint{row.Host, models.Point{Time: q.Start.Add(time.Duration(row.Bucket) * q.Step).UTC(), Value: row.Value}})
+	}
+	return groupByHost(points), nil
+}
+
+// hostPoint is a point of one host, as scanned from a query grouped by host.
+type hostPoint struct {
+	host  string
+	point models.Point
+}
+
+// groupByHost collects points, which must be ordered by host, per host.
+func groupByHost(points []hostPoint) []models.HostPoints {
+	var res []models.HostPoints
+	for _, p := range points {
+		if len(res) == 0 || res[len(res)-1].Host != p.host {
+			res = append(res, models.HostPoints{Host: p.host})
+		}
+		last := &res[len(res)-1]
+		last.Points = append(last.Points, p.point)
+	}
+	return res
+}
+
+// aggregateCounters evaluates rate, irate or increase per series and bucket
+// and sums the results over the series, or over the series of each host when
+// byHost is set. The last sample before a bucket counts as its baseline, so
+// the first bucket looks back one step.
+func aggregateCounters(ctx context.Context, q models.AggregateQuery, source aggregateSource, byHost bool) ([]models.HostPoints, error) {
+	var samples []struct {
+		models.Sample
+		Host string
+	}
+	if err := database.DB.WithContext(ctx).
+		Table("samples").
+		Select("samples.series_id, samples.ts, samples.value, series.host").
+		Joins(source.join).
+		Where("ts >= ? AND ts <= ?", q.Start.Add(-q.Step), q.End).
+		Scopes(source.filter).
+		Order("series_id ASC, ts ASC").
+		Scan(&samples).Error; err != nil {
+		logger.Log.Error("Error loading counter samples:", zap.Error(err))
+		return nil, err
+	}
+
+	type key struct {
+		host   string
+		bucket int64
+	}
+	values := make(map[key]float64)
+	var keys []key
+	for i := 1; i < len(samples); i++ {
+		prev, cur := samples[i-1], samples[i]
+		if prev.SeriesID != cur.SeriesID || cur.Timestamp.Before(q.Start) {
+			continue
+		}
+		k := key{bucket: int64(cur.Timestamp.Sub(q.Start) / q.Step)}
+		if byHost {
+			k.host = cur.Host
+		}
+
+		var value float64
+		switch q.Func {
+		case "irate":
+			// Only the last pair of samples of the bucket counts.
+			if i+1 < len(samples) && samples[i+1].SeriesID == cur.SeriesID &&
+				int64(samples[i+1].Timestamp.Sub(q.Start)/q.Step) == k.bucket {
+				continue
+			}
+			if dt := cur.Timestamp.Sub(prev.Timestamp).Seconds(); dt > 0 {
+				value = CounterDelta(prev.Value, cur.Value) / dt
+			}
+		case "rate":
+			value = CounterDelta(prev.Value, cur.Value) / q.Step.Seconds()
+		default:
+			value = CounterDelta(prev.Value, cur.Value)
+		}
+
+		if _, ok := values[k]; !ok {
+			keys = append(keys, k)
+		}
+		values[k] += value
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].host != keys[j].host {
+			return keys[i].host < keys[j].host
+		}
+		return keys[i].bucket < keys[j].bucket
+	})
+	points := make([]hostPoint, 0, len(keys))
+	for _, k := range keys {
+		points = append(points, hostPoint{k.host, models.Point{Time: q.Start.Add(time.Duration(k.bucket) * q.Step).UTC(), Value: values[k]}})
+	}
+	return groupByHost(points), nil
+}
+
+// CounterDelta is how much a counter grew between two samples. A smaller
+// current value means the counter was reset, e.g. by a reboot, and started
+// again from zero.
+func CounterDelta(prev, cur float64) float64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+// AggregateByHost runs q grouped by host, or only for q.Filter.Host when it
+// is set, and returns the hosts that have points, at most limit of them in
+// host name order. Series without a host are left out.
+func AggregateByHost(ctx context.Context, q models.AggregateQuery, limit int) ([]models.HostPoints, error) {
+	hosts, err := aggregate(ctx, q, true)
+	if err != nil {
+		return nil, err
+	}
+
+	res := hosts[:0]
+	for _, h := range hosts {
+		if h.Host == "" {
+			continue
+		}
+		res = append(res, h)
+		if len(res) == limit {
+			break
+		}
+	}
+	return res, nil
+}
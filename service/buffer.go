@@ -0,0 +1,69 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"go.uber.org/zap"
+)
+
+// sampleBuffer holds samples for an output until they are sent. It keeps the
+// newest size samples while the output is unreachable.
+type sampleBuffer[T any] struct {
+	sync.Mutex
+	name    string
+	size    int
+	samples []T
+	dropped int64
+	// trimmed counts the oldest samples dropped since the last peek, which
+	// are no longer in the buffer when the peeked ones are removed.
+	trimmed int
+}
+
+func (b *sampleBuffer[T]) add(samples []T) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.samples = append(b.samples, samples...)
+	if over := len(b.samples) - b.size; over > 0 {
+		b.samples = b.samples[over:]
+		b.trimmed += over
+		b.dropped += int64(over)
+		logger.Log.Warn("Buffer full, dropping oldest samples", zap.String("buffer", b.name), zap.Int("dropped", over))
+	}
+}
+
+// peek returns a copy of the oldest n buffered samples, or fewer when less
+// are buffered.
+func (b *sampleBuffer[T]) peek(n int) []T {
+	b.Lock()
+	defer b.Unlock()
+	b.trimmed = 0
+	return append([]T(nil), b.samples[:min(n, len(b.samples))]...)
+}
+
+// remove drops the n samples returned by the last peek once they have been
+// sent, minus those trimmed in the meantime.
+func (b *sampleBuffer[T]) remove(n int) {
+	b.Lock()
+	defer b.Unlock()
+	b.samples = b.samples[max(n-b.trimmed, 0):]
+}
+
+// batchSamples returns the samples of batch as series samples. Rows of the
+// metrics table become cpu_percent and mem_percent samples.
+func batchSamples(batch models.SampleBatch) []models.SeriesSample {
+	if len(batch.Metrics) == 0 {
+		return batch.Samples
+	}
+	samples := make([]models.SeriesSample, 0, 2*len(batch.Metrics)+len(batch.Samples))
+	for _, metrics := range batch.Metrics {
+		labels := map[string]string{HostLabel: metrics.Host}
+		samples = append(samples,
+			models.SeriesSample{Name: "cpu_percent", Labels: labels, Timestamp: metrics.CreatedAt, Value: metrics.CPUPercent},
+			models.SeriesSample{Name: "mem_percent", Labels: labels, Timestamp: metrics.CreatedAt, Value: metrics.MemPercent},
+		)
+	}
+	return append(samples, batch.Samples...)
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/load"
+	"github.com/shirou/gopsutil/net"
+	"go.uber.org/zap"
+)
+
+// Counter series collected from the host. They only ever grow, apart from
+// resets on reboot, and are meant to be read through rate(), irate() or
+// increase().
+const (
+	CounterNetworkReceiveBytes  = "node_network_receive_bytes_total"
+	CounterNetworkTransmitBytes = "node_network_transmit_bytes_total"
+	CounterDiskReadBytes        = "node_disk_read_bytes_total"
+	CounterDiskWrittenBytes     = "node_disk_written_bytes_total"
+	CounterContextSwitches      = "node_context_switches_total"
+)
+
+// CollectAndSaveCounters collects the network, disk and context switch
+// counters. A counter source that is unavailable on this platform is
+// skipped.
+func CollectAndSaveCounters(errChan chan error) {
+	now := time.Now().UTC()
+	host := Hostname()
+	var samples []models.SeriesSample
+
+	add := func(name string, value uint64, labels map[string]string) {
+		labels[HostLabel] = host
+		samples = append(samples, models.SeriesSample{Name: name, Labels: labels, Timestamp: now, Value: float64(value)})
+	}
+
+	if counters, err := net.IOCounters(true); err != nil {
+		logger.Log.Warn("Failed to get network counters", zap.Error(err))
+	} else {
+		for _, c := range counters {
+			add(CounterNetworkReceiveBytes, c.BytesRecv, map[string]string{"device": c.Name})
+			add(CounterNetworkTransmitBytes, c.BytesSent, map[string]string{"device": c.Name})
+		}
+	}
+
+	if counters, err := disk.IOCounters(); err != nil {
+		logger.Log.Warn("Failed to get disk counters", zap.Error(err))
+	} else {
+		for name, c := range counters {
+			add(CounterDiskReadBytes, c.ReadBytes, map[string]string{"device": name})
+			add(CounterDiskWrittenBytes, c.WriteBytes, map[string]string{"device": name})
+		}
+	}
+
+	if misc, err := load.Misc(); err != nil {
+		logger.Log.Warn("Failed to get context switches", zap.Error(err))
+	} else if misc.Ctxt > 0 {
+		add(CounterContextSwitches, uint64(misc.Ctxt), map[string]string{})
+	}
+
+	if err := StoreSamples(context.Background(), samples); err != nil {
+		logger.Log.Error("Failed to store counters", zap.Error(err))
+		collectionFailed(errChan, err)
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/config"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/database"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/promql"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Internal counters exposed as Metrics-Monitor's own health metrics.
+var (
+	startTime        = time.Now()
+	ingestedSamples  atomic.Int64
+	collectionErrors atomic.Int64
+)
+
+// collectionFailed counts a failed collection and reports it on errChan.
+func collectionFailed(errChan chan error, err error) {
+	collectionErrors.Add(1)
+	errChan <- err
+}
+
+// Exposition returns the latest value of every series that reported within
+// the PromQL lookback window, followed by Metrics-Monitor's own metrics.
+// Stored series named like a built-in or internal family are not exposed.
+func Exposition(ctx context.Context) ([]models.MetricFamily, error) {
+	if database.DB == nil {
+		logger.Log.Error("Database is not initialized")
+		return nil, gorm.ErrInvalidDB
+	}
+	since := time.Now().Add(-promql.DefaultLookbackDelta).UTC()
+
+	gauges, err := latestGauges(ctx, since)
+	if err != nil {
+		logger.Log.Error("Error loading latest metrics", zap.Error(err))
+		return nil, err
+	}
+	stored, err := latestStoredSeries(ctx, since)
+	if err != nil {
+		logger.Log.Error("Error loading latest series samples", zap.Error(err))
+		return nil, err
+	}
+
+	// A family may appear only once in a scrape, so stored series that
+	// reuse the name of a built-in or internal family are left out.
+	internal := internalMetrics(ctx)
+	reserved := make(map[string]bool)
+	for name := range aggregateColumns {
+		reserved[name] = true
+	}
+	for _, f := range internal {
+		reserved[f.Name] = true
+	}
+	families := gauges
+	for _, f := range stored {
+		if !reserved[f.Name] {
+			families = append(families, f)
+		}
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	return append(families, internal...), nil
+}
+
+func latestGauges(ctx context.Context, since time.Time) ([]models.MetricFamily, error) {
+	var rows []models.Metrics
+	if err := database.DB.WithContext(ctx).
+		Table("metrics m").
+		Select("m.host, m.created_at, m.cpu_percent, m.mem_percent").
+		Joins(`JOIN (SELECT host, MAX(created_at) AS created_at FROM metrics WHERE created_at > ? GROUP BY host) latest
+			ON latest.host = m.host AND latest.created_at = m.created_at`, since).
+		Order("m.host ASC").
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	cpu := models.MetricFamily{Name: "cpu_percent", Help: "CPU usage in percent.", Type: models.MetricTypeGauge}
+	memory := models.MetricFamily{Name: "mem_percent", Help: "Memory usage in percent.", Type: models.MetricTypeGauge}
+	for i, row := range rows {
+		if i > 0 && row.Host == rows[i-1].Host {
+			continue
+		}
+		ts := row.CreatedAt.UTC()
+		labels := map[string]string{}
+		if row.Host != "" {
+			labels[HostLabel] = row.Host
+		}
+		cpu.Samples = append(cpu.Samples, models.ExposedSample{Labels: labels, Value: row.CPUPercent, Timestamp: &ts})
+		memory.Samples = append(memory.Samples, models.ExposedSample{Labels: labels, Value: row.MemPercent, Timestamp: &ts})
+	}
+	return []models.MetricFamily{cpu, memory}, nil
+}
+
+func latestStoredSeries(ctx context.Context, since time.Time) ([]models.MetricFamily, error) {
+	var samples []models.Sample
+	if err := database.DB.WithContext(ctx).
+		Table("samples s").
+		Select("s.series_id, s.ts, s.value").
+		Joins(`JOIN (SELECT series_id, MAX(ts) AS ts FROM samples WHERE ts > ? GROUP BY series_id) latest
+			ON latest.series_id = s.series_id AND latest.ts = s.ts`, since).
+		Find(&samples).Error; err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint64, len(samples))
+	for i, s := range samples {
+		ids[i] = s.SeriesID
+	}
+	var series []models.Series
+	if err := database.DB.WithContext(ctx).Where("id IN ?", ids).Find(&series).Error; err != nil {
+		return nil, err
+	}
+	labels, err := loadSeriesLabels(ctx, series)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int)
+	var families []models.MetricFamily
+	for _, s := range samples {
+		l, ok := labels[s.SeriesID]
+		if !ok {
+			continue
+		}
+		name := l[promql.MetricNameLabel]
+		delete(l, promql.MetricNameLabel)
+
+		i, ok := index[name]
+		if !ok {
+			i = len(families)
+			index[name] = i
+			families = append(families, models.MetricFamily{Name: name, Type: metricType(name)})
+		}
+		ts := s.Timestamp.UTC()
+		families[i].Samples = append(families[i].Samples, models.ExposedSample{Labels: l, Value: s.Value, Timestamp: &ts})
+	}
+	for _, f := range families {
+		sort.Slice(f.Samples, func(i, j int) bool {
+			return promql.Labels(f.Samples[i].Labels).Fingerprint() < promql.Labels(f.Samples[j].Labels).Fingerprint()
+		})
+	}
+	return families, nil
+}
+
+// metricType guesses the type of a stored series from the naming
+// conventions, since series do not record it.
+func metricType(name string) string {
+	if strings.HasSuffix(name, "_total") {
+		return models.MetricTypeCounter
+	}
+	return models.MetricTypeUnknown
+}
+
+func internalMetrics(ctx context.Context) []models.MetricFamily {
+	gauge := func(name, help string, value float64, labels map[string]string) models.MetricFamily {
+		return models.MetricFamily{Name: name, Help: help, Type: models.MetricTypeGauge,
+			Samples: []models.ExposedSample{{Labels: labels, Value: value}}}
+	}
+	counter := func(name, help string, value float64) models.MetricFamily {
+		return models.MetricFamily{Name: name, Help: help, Type: models.MetricTypeCounter,
+			Samples: []models.ExposedSample{{Value: value}}}
+	}
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	families := []models.MetricFamily{
+		gauge("metrics_monitor_build_info", "Metrics-Monitor version.", 1, map[string]string{"version": config.Version, "mode": config.Cfg.Mode}),
+		gauge("metrics_monitor_start_time_seconds", "Start time of the process since the Unix epoch in seconds.", float64(startTime.Unix()), nil),
+		counter("metrics_monitor_ingested_samples_total", "Samples received from agents and other senders.", float64(ingestedSamples.Load())),
+		counter("metrics_monitor_collection_errors_total", "Failed local metric collections.", float64(collectionErrors.Load())),
+		counter("metrics_monitor_retention_deleted_rows_total", "Rows deleted by retention pruning.", float64(GetRetentionStatus().TotalDeleted)),
+		gauge("go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine()), nil),
+		gauge("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", float64(memStats.Alloc), nil),
+	}
+
+	up := 0.0
+	if sqlDB, err := database.DB.DB(); err == nil && sqlDB.PingContext(ctx) == nil {
+		up = 1
+	}
+	families = append(families, gauge("metrics_monitor_database_up", "Whether the database answers pings.", up, nil))
+
+	var series int64
+	if err := database.DB.WithContext(ctx).Model(&models.Series{}).Count(&series).Error; err == nil {
+		families = append(families, gauge("metrics_monitor_series", "Number of stored series.", float64(series), nil))
+	}
+
+	if hosts, err := GetHosts(ctx); err == nil {
+		stale := 0
+		for _, h := range hosts {
+			if h.Stale {
+				stale++
+			}
+		}
+		families = append(families,
+			gauge("metrics_monitor_hosts", "Number of registered hosts.", float64(len(hosts)), nil),
+			gauge("metrics_monitor_stale_hosts", "Number of registered hosts that stopped reporting.", float64(stale), nil))
+	}
+	return families
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/graphite"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// graphiteBatchSize and graphiteBatchWait bound how many received lines
+	// are stored per write and how long they wait for it.
+	graphiteBatchSize = 500
+	graphiteBatchWait = time.Second
+	// graphiteBufferSize is the number of samples the output keeps while
+	// the Graphite server is unreachable.
+	graphiteBufferSize = 10000
+)
+
+var graphiteDialer = &net.Dialer{Timeout: 5 * time.Second}
+
+// StartGraphite starts the Graphite plaintext listener on cfg.GraphiteAddr
+// until ctx is cancelled and returns the address it listens on. Paths are
+// mapped to metric names and labels with cfg.GraphiteTemplates.
+func StartGraphite(ctx context.Context, cfg *models.Config, errChan chan error) (net.Addr, error) {
+	templates, err := graphite.ParseTemplates(cfg.GraphiteTemplates)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", cfg.GraphiteAddr)
+	if err != nil {
+		return nil, err
+	}
+	context.AfterFunc(ctx, func() { ln.Close() })
+
+	received := make(chan models.SeriesSample, graphiteBatchSize)
+	go storeGraphiteSamples(ctx, received, errChan)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					logger.Log.Error("Graphite listener failed", zap.Error(err))
+				}
+				return
+			}
+			go serveGraphiteConn(ctx, conn, templates, received)
+		}
+	}()
+
+	logger.Log.Info("Graphite listening", zap.Stringer("addr", ln.Addr()))
+	return ln.Addr(), nil
+}
+
+func serveGraphiteConn(ctx context.Context, conn net.Conn, templates graphite.Templates, received chan<- models.SeriesSample) {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		m, err := graphite.ParseLine(line, time.Now().UTC())
+		if err != nil {
+			logger.Log.Debug("Invalid Graphite line", zap.Error(err))
+			continue
+		}
+
+		name, labels := templates.Apply(m.Path)
+		for k, v := range m.Tags {
+			labels[k] = v
+		}
+		sample := models.SeriesSample{Name: sanitizeMetricName(name), Labels: make(map[string]string, len(labels)), Timestamp: m.Time, Value: m.Value}
+		for k, v := range labels {
+			if key := sanitizeLabelName(k); key != "" && v != "" {
+				sample.Labels[key] = v
+			}
+		}
+		select {
+		case received <- sample:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// storeGraphiteSamples stores received samples through StoreSamples in
+// batches of up to graphiteBatchSize, waiting at most graphiteBatchWait.
+func storeGraphiteSamples(ctx context.Context, received <-chan models.SeriesSample, errChan chan error) {
+	ticker := time.NewTicker(graphiteBatchWait)
+	defer ticker.Stop()
+
+	var batch []models.SeriesSample
+	store := func(ctx context.Context, final bool) {
+		if len(batch) == 0 {
+			return
+		}
+		if err := StoreSamples(ctx, batch); err != nil {
+			logger.Log.Error("Failed to store Graphite samples", zap.Error(err))
+			if !final {
+				errChan <- err
+			}
+		} else {
+			ingestedSamples.Add(int64(len(batch)))
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case s := <-received:
+			batch = append(batch, s)
+			if len(batch) >= graphiteBatchSize {
+				store(ctx, false)
+			}
+		case <-ticker.C:
+			store(ctx, false)
+		case <-ctx.Done():
+			store(context.Background(), true)
+			return
+		}
+	}
+}
+
+// graphiteBuffer holds collected samples until they are sent to the Graphite
+// output.
+var graphiteBuffer = &sampleBuffer[models.SeriesSample]{name: "graphite", size: graphiteBufferSize}
+
+// graphiteSink sends collected samples to the Graphite output buffer.
+type graphiteSink struct{}
+
+func (graphiteSink) Name() string { return "graphite" }
+
+func (graphiteSink) Write(_ context.Context, batch models.SampleBatch) error {
+	graphiteBuffer.add(batchSamples(batch))
+	return nil
+}
+
+// GraphiteOutput sends buffered samples to cfg.GraphiteOutputAddr every
+// collector interval and once more when ctx is cancelled.
+func GraphiteOutput(ctx context.Context, cfg *models.Config, errChan chan error) {
+	ticker := time.NewTicker(time.Duration(cfg.MetricsInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := FlushGraphiteOutput(ctx, cfg); err != nil {
+				errChan <- err
+			}
+		case <-ctx.Done():
+			logger.Log.Info("Stopping Graphite Output...")
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := FlushGraphiteOutput(flushCtx, cfg); err != nil {
+				logger.Log.Error("Final Graphite flush failed", zap.Error(err))
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// FlushGraphiteOutput writes everything buffered so far to the Graphite
+// server as plaintext lines. Samples stay buffered when it is unreachable.
+func FlushGraphiteOutput(ctx context.Context, cfg *models.Config) error {
+	samples := graphiteBuffer.peek(graphiteBufferSize)
+	if len(samples) == 0 {
+		return nil
+	}
+
+	conn, err := graphiteDialer.DialContext(ctx, "tcp", cfg.GraphiteOutputAddr)
+	if err != nil {
+		logger.Log.Error("Failed to connect to Graphite", zap.String("addr", cfg.GraphiteOutputAddr), zap.Error(err))
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
+	}
+
+	w := bufio.NewWriter(conn)
+	for _, s := range samples {
+		fmt.Fprintf(w, "%s %s %d\n", GraphitePath(cfg.GraphiteOutputPrefix, s), strconv.FormatFloat(s.Value, 'f', -1, 64), s.Timestamp.Unix())
+	}
+	if err := w.Flush(); err != nil {
+		logger.Log.Error("Failed to write to Graphite", zap.String("addr", cfg.GraphiteOutputAddr), zap.Error(err))
+		return err
+	}
+
+	graphiteBuffer.remove(len(samples))
+	logger.Log.Debug("Sent samples to Graphite", zap.Int("samples", len(samples)))
+	return nil
+}
+
+// GraphitePath is the path a sample is sent as: the prefix, host, metric
+// name and the values of the other labels ordered by label name, e.g.
+// metrics_monitor.web-01.node_network_receive_bytes_total.eth0. Dots and
+// spaces in host names and label values are replaced by underscores.
+func GraphitePath(prefix string, s models.SeriesSample) string {
+	var parts []string
+	if prefix != "" {
+		parts = append(parts, prefix)
+	}
+	if host := s.Labels[HostLabel]; host != "" {
+		parts = append(parts, graphiteComponent(host))
+	}
+	parts = append(parts, s.Name)
+
+	keys := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		if k != HostLabel {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		parts = append(parts, graphiteComponent(s.Labels[k]))
+	}
+	return strings.Join(parts, ".")
+}
+
+func graphiteComponent(s string) string {
+	return strings.NewReplacer(".", "_", " ", "_").Replace(s)
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/config"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/database"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/google/uuid"
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/host"
+	"github.com/shirou/gopsutil/mem"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrHostNotFound = errors.New("host not found")
+
+// CollectHostInfo reads the inventory of the machine we run on. Parts that
+// cannot be read are left empty.
+func CollectHostInfo() models.HostInfo {
+	info := models.HostInfo{AgentVersion: config.Version}
+
+	if h, err := host.Info(); err == nil {
+		info.OS = h.OS
+		info.Platform = h.Platform
+		info.PlatformVersion = h.PlatformVersion
+		info.Kernel = h.KernelVersion
+		info.Arch = h.KernelArch
+	} else {
+		logger.Log.Warn("Failed to read host info", zap.Error(err))
+	}
+	if cpus, err := cpu.Info(); err == nil && len(cpus) > 0 {
+		info.CPUModel = cpus[0].ModelName
+	}
+	if count, err := cpu.Counts(true); err == nil {
+		info.CPUCount = count
+	}
+	if vm, err := mem.VirtualMemory(); err == nil {
+		info.TotalMemory = int64(vm.Total)
+	}
+	return info
+}
+
+// TouchHost records that hostname reported at seen, creating the host on its
+// first report. A nil info only moves last_seen.
+func TouchHost(ctx context.Context, hostname string, info *models.HostInfo, seen time.Time) error {
+	if database.DB == nil {
+		logger.Log.Error("Database is not initialized")
+		return gorm.ErrInvalidDB
+	}
+
+	row := models.Host{ID: uuid.New(), Hostname: hostname, FirstSeen: seen, LastSeen: seen}
+	updates := []string{"last_seen"}
+	if info != nil {
+		row.HostInfo = *info
+		updates = append(updates, "os", "platform", "platform_version", "kernel", "arch",
+			"cpu_model", "cpu_count", "total_memory", "agent_version")
+	}
+
+	if err := database.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "hostname"}},
+			DoUpdates: clause.AssignmentColumns(updates),
+		}).
+		Create(&row).Error; err != nil {
+		logger.Log.Error("Error updating host registry", zap.String("host", hostname), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// RegisterLocalHost adds the machine we run on, with its HOST_LABELS, to
+// the host registry.
+func RegisterLocalHost(ctx context.Context) error {
+	info := CollectHostInfo()
+	if err := TouchHost(ctx, Hostname(), &info, time.Now().UTC()); err != nil {
+		return err
+	}
+	return SyncAgentLabels(ctx, Hostname(), config.Cfg.HostLabels)
+}
+
+// GetHosts lists every known host, flagging those that stopped reporting.
+func GetHosts(ctx context.Context) ([]models.Host, error) {
+	var res []models.Host
+
+	if database.DB == nil {
+		logger.Log.Error("Database is not initialized")
+		return nil, gorm.ErrInvalidDB
+	}
+
+	if err := database.DB.WithContext(ctx).Order("hostname ASC").Find(&res).Error; err != nil {
+		logger.Log.Error("Error fetching hosts:", zap.Error(err))
+		return nil, err
+	}
+	if err := loadHostLabels(ctx, res); err != nil {
+		logger.Log.Error("Error fetching host labels:", zap.Error(err))
+		return nil, err
+	}
+	for i := range res {
+		markStale(&res[i])
+	}
+	return res, nil
+}
+
+// GetHost finds a host by id or, failing that, by host name.
+func GetHost(ctx context.Context, idOrName string) (models.Host, error) {
+	var res []models.Host
+
+	if database.DB == nil {
+		logger.Log.Error("Database is not initialized")
+		return models.Host{}, gorm.ErrInvalidDB
+	}
+
+	query := database.DB.WithContext(ctx).Limit(1)
+	if id, err := uuid.Parse(idOrName); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("hostname = ?", idOrName)
+	}
+	if err := query.Find(&res).Error; err != nil {
+		logger.Log.Error("Error fetching host:", zap.Error(err))
+		return models.Host{}, err
+	}
+	if len(res) == 0 {
+		return models.Host{}, ErrHostNotFound
+	}
+
+	if err := loadHostLabels(ctx, res); err != nil {
+		logger.Log.Error("Error fetching host labels:", zap.Error(err))
+		return models.Host{}, err
+	}
+	markStale(&res[0])
+	return res[0], nil
+}
+
+func markStale(h *models.Host) {
+	staleAfter := config.Cfg.HostStaleAfter
+	if staleAfter <= 0 {
+		staleAfter = 2 * time.Minute
+	}
+	h.Stale = time.Since(h.LastSeen) > staleAfter
+}
+
+// SyncAgentLabels replaces the labels an agent configured for its host.
+// Labels set through the API are left alone.
+func SyncAgentLabels(ctx context.Context, hostname string, labels map[string]string) error {
+	host, err := GetHost(ctx, hostname)
+	if err != nil {
+		return err
+	}
+
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stale := tx.Where("host_id = ? AND source = ?", host.ID, models.LabelSourceAgent)
+		if len(labels) > 0 {
+			keys := make([]string, 0, len(labels))
+			for key := range labels {
+				keys = append(keys, key)
+			}
+			stale = stale.Where("key NOT IN ?", keys)
+		}
+		if err := stale.Delete(&models.HostLabel{}).Error; err != nil {
+			return err
+		}
+
+		for key, value := range labels {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "host_id"}, {Name: "key"}},
+				DoUpdates: clause.AssignmentColumns([]string{"value"}),
+				Where: clause.Where{Exprs: []clause.Expression{
+					clause.Expr{SQL: "host_labels.source = ?", Vars: []interface{}{models.LabelSourceAgent}},
+				}},
+			}).Create(&models.HostLabel{HostID: host.ID, Key: key, Value: value, Source: models.LabelSourceAgent}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SetHostLabels replaces the API managed labels of a host. They override
+// agent labels with the same key.
+func SetHostLabels(ctx context.Context, idOrName string, labels map[string]string) (models.Host, error) {
+	host, err := GetHost(ctx, idOrName)
+	if err != nil {
+		return models.Host{}, err
+	}
+
+	err = database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("host_id = ? AND source = ?", host.ID, models.LabelSourceAPI).
+			Delete(&models.HostLabel{}).Error; err != nil {
+			return err
+		}
+		for key, value := range labels {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "host_id"}, {Name: "key"}},
+				DoUpdates: clause.AssignmentColumns([]string{"value", "source"}),
+			}).Create(&models.HostLabel{HostID: host.ID, Key: key, Value: value, Source: models.LabelSourceAPI}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Log.Error("Error setting host labels", zap.String("host", host.Hostname), zap.Error(err))
+		return models.Host{}, err
+	}
+
+	return GetHost(ctx, host.ID.String())
+}
+
+// DeleteHostLabel removes one label of a host whatever its source. An agent
+// label comes back with the agent's next push.
+func DeleteHostLabel(ctx context.Context, idOrName, key string) error {
+	host, err := GetHost(ctx, idOrName)
+	if err != nil {
+		return err
+	}
+	return database.DB.WithContext(ctx).
+		Where("host_id = ? AND key = ?", host.ID, key).
+		Delete(&models.HostLabel{}).Error
+}
+
+// loadHostLabels fills in the Labels of hosts.
+func loadHostLabels(ctx context.Context, hosts []models.Host) error {
+	ids := make([]uuid.UUID, len(hosts))
+	byID := make(map[uuid.UUID]*models.Host, len(hosts))
+	for i := range hosts {
+		ids[i] = hosts[i].ID
+		byID[hosts[i].ID] = &hosts[i]
+		hosts[i].Labels = map[string]string{}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var labels []models.HostLabel
+	if err := database.DB.WithContext(ctx).Where("host_id IN ?", ids).Find(&labels).Error; err != nil {
+		return err
+	}
+	for _, label := range labels {
+		if h, ok := byID[label.HostID]; ok {
+			h.Labels[label.Key] = label.Value
+		}
+	}
+	return nil
+}
+
+// GetAverageMetricsByLabel averages the metrics of each value of a host
+// label, e.g. per team. Hosts without the label are grouped under "".
+func GetAverageMetricsByLabel(ctx context.Context, start, end time.Time, filter models.MetricsFilter, label string) ([]models.LabelAvgMetrics, error) {
+	var res []models.LabelAvgMetrics
+
+	if database.DB == nil {
+		logger.Log.Error("Database is not initialized")
+		return nil, gorm.ErrInvalidDB
+	}
+
+	if err := database.DB.WithContext(ctx).
+		Model(&models.Metrics{}).
+		Select(`? AS label, COALESCE(l.value, '') AS value,
+			AVG(cpu_percent) AS cpu_percent, AVG(mem_percent) AS mem_percent`, label).
+		Joins("LEFT JOIN hosts h ON h.hostname = metrics.host").
+		Joins("LEFT JOIN host_labels l ON l.host_id = h.id AND l.key = ?", label).
+		Where("created_at BETWEEN ? AND ?", start, end).
+		Scopes(filterMetrics(filter)).
+		Group("COALESCE(l.value, '')").
+		Order("value").
+		Scan(&res).Error; err != nil {
+		logger.Log.Error("Error fetching average metrics by label:", zap.Error(err))
+		return nil, err
+	}
+
+	return res, nil
+}
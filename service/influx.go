@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/influx"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"go.uber.org/zap"
+)
+
+// IngestInflux stores every numeric and boolean field of points as a series
+// named <measurement>_<field>, or just <measurement> for a field called
+// value, labelled with the point's tags. Booleans are stored as 1 and 0,
+// string fields are skipped. It returns how many samples were stored.
+func IngestInflux(ctx context.Context, points []influx.Point) (int, error) {
+	var samples []models.SeriesSample
+	for _, p := range points {
+		labels := make(map[string]string, len(p.Tags))
+		for k, v := range p.Tags {
+			if key := sanitizeLabelName(k); key != "" && v != "" {
+				labels[key] = v
+			}
+		}
+
+		for _, f := range p.Fields {
+			var value float64
+			switch v := f.Value.(type) {
+			case float64:
+				value = v
+			case int64:
+				value = float64(v)
+			case uint64:
+				value = float64(v)
+			case bool:
+				if v {
+					value = 1
+				}
+			default:
+				continue
+			}
+
+			name := p.Measurement
+			if f.Key != "value" {
+				name += "_" + f.Key
+			}
+			samples = append(samples, models.SeriesSample{
+				Name:      sanitizeMetricName(name),
+				Labels:    labels,
+				Timestamp: p.Time,
+				Value:     value,
+			})
+		}
+	}
+
+	if err := WriteSamples(ctx, samples); err != nil {
+		logger.Log.Error("Error storing line protocol samples", zap.Error(err))
+		return 0, err
+	}
+	ingestedSamples.Add(int64(len(samples)))
+	logger.Log.Debug("Stored line protocol samples", zap.Int("points", len(points)), zap.Int("samples", len(samples)))
+	return len(samples), nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/database"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxIngestBatch is the largest number of samples accepted in one request.
+const maxIngestBatch = 5000
+
+var ErrBatchTooLarge = errors.New("batch too large")
+
+// IngestMetrics registers the pushing host and stores its batch, labelling
+// every metric and series sample with the batch host. Samples already stored by an earlier,
+// retried push are skipped.
+func IngestMetrics(ctx context.Context, batch models.IngestBatch) (int, error) {
+	if database.DB == nil {
+		logger.Log.Error("Database is not initialized")
+		return 0, gorm.ErrInvalidDB
+	}
+	if len(batch.Metrics)+len(batch.Samples) > maxIngestBatch {
+		return 0, ErrBatchTooLarge
+	}
+
+	now := time.Now().UTC()
+	if err := TouchHost(ctx, batch.Host, batch.HostInfo, now); err != nil {
+		return 0, err
+	}
+	if batch.Labels != nil {
+		if err := SyncAgentLabels(ctx, batch.Host, batch.Labels); err != nil {
+			logger.Log.Error("Error syncing agent labels", zap.String("host", batch.Host), zap.Error(err))
+			return 0, err
+		}
+	}
+
+	for i := range batch.Samples {
+		s := &batch.Samples[i]
+		if s.Labels == nil {
+			s.Labels = make(map[string]string)
+		}
+		s.Labels[HostLabel] = batch.Host
+		if s.Timestamp.IsZero() {
+			s.Timestamp = now
+		}
+	}
+	if err := WriteSamples(ctx, batch.Samples); err != nil {
+		return 0, err
+	}
+	ingestedSamples.Add(int64(len(batch.Samples)))
+
+	if len(batch.Metrics) == 0 {
+		return len(batch.Samples), nil
+	}
+
+	for i := range batch.Metrics {
+		m := &batch.Metrics[i]
+		m.Host = batch.Host
+		if m.ID == uuid.Nil {
+			m.ID = uuid.New()
+		}
+		if m.CreatedAt.IsZero() {
+			m.CreatedAt = now
+		}
+	}
+
+	res := database.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(&batch.Metrics, 500)
+	if res.Error != nil {
+		logger.Log.Error("Error storing ingested metrics", zap.String("host", batch.Host), zap.Error(res.Error))
+		return 0, res.Error
+	}
+
+	oldest := batch.Metrics[0].CreatedAt
+	for _, m := range batch.Metrics[1:] {
+		if m.CreatedAt.Before(oldest) {
+			oldest = m.CreatedAt
+		}
+	}
+	noteIngested(oldest)
+	publishSamples(batchSamples(models.SampleBatch{Metrics: batch.Metrics}))
+	ingestedSamples.Add(int64(len(batch.Metrics)))
+	logger.Log.Debug("Ingested metrics", zap.String("host", batch.Host), zap.Int64("stored", res.RowsAffected))
+	return len(batch.Metrics) + len(batch.Samples), nil
+}
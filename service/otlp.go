@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/otlp"
+	"go.uber.org/zap"
+)
+
+// OTLPHostAttribute is the resource attribute stored as the host label, so
+// that application metrics line up with the host's CPU and memory samples.
+const OTLPHostAttribute = "host.name"
+
+// IngestOTLP stores the gauge, sum and histogram data points of an OTLP export
+// request and returns how many data points were stored and rejected.
+// Resource attributes become labels of every series of the resource, data
+// point attributes win over them. Names are made Prometheus compatible:
+// "http.server.duration" is stored as http_server_duration, monotonic
+// cumulative sums get a _total suffix and histograms are stored as _bucket,
+// _count and _sum series. Sums and histograms with delta temporality are
+// rejected, as stored series are read as cumulative, and so are histogram
+// points without exactly one bucket count more than bounds.
+func IngestOTLP(ctx context.Context, req *otlp.ExportMetricsServiceRequest) (accepted, rejected int, err error) {
+	var samples []models.SeriesSample
+	now := time.Now().UTC()
+
+	for _, rm := range req.ResourceMetrics {
+		resource := otlpLabels(nil, rm.Resource.Attributes)
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				name := sanitizeMetricName(m.Name)
+				switch {
+				case name == "", otlpDelta(m):
+					rejected += otlpPointCount(m)
+				case m.Gauge != nil:
+					samples = appendNumberPoints(samples, name, resource, m.Gauge.DataPoints, now)
+				case m.Sum != nil:
+					if m.Sum.IsMonotonic && m.Sum.AggregationTemporality == otlp.AggregationTemporalityCumulative && !strings.HasSuffix(name, "_total") {
+						name += "_total"
+					}
+					samples = appendNumberPoints(samples, name, resource, m.Sum.DataPoints, now)
+				case m.Histogram != nil:
+					var malformed int
+					samples, malformed = appendHistogramPoints(samples, name, resource, m.Histogram.DataPoints, now)
+					rejected += malformed
+				default:
+					rejected += otlpPointCount(m)
+				}
+			}
+		}
+	}
+
+	if err := WriteSamples(ctx, samples); err != nil {
+		logger.Log.Error("Error storing OTLP samples", zap.Error(err))
+		return 0, 0, err
+	}
+	ingestedSamples.Add(int64(len(samples)))
+	logger.Log.Debug("Stored OTLP samples", zap.Int("samples", len(samples)), zap.Int("rejected", rejected))
+	return len(samples), rejected, nil
+}
+
+func appendNumberPoints(samples []models.SeriesSample, name string, resource map[string]string, points []otlp.NumberDataPoint, now time.Time) []models.SeriesSample {
+	for _, p := range points {
+		v := p.Value()
+		if p.Flags&otlp.FlagNoRecordedValue != 0 || math.IsNaN(v) {
+			continue
+		}
+		samples = append(samples, models.SeriesSample{
+			Name:      name,
+			Labels:    otlpLabels(resource, p.Attributes),
+			Timestamp: otlpTime(p.TimeUnixNano, now),
+			Value:     v,
+		})
+	}
+	return samples
+}
+
+// appendHistogramPoints stores each point as cumulative le buckets, ending
+// with +Inf, plus its count and, when known, its sum. Points whose bucket
+// counts do not match their bounds are skipped and counted as malformed.
+func appendHistogramPoints(samples []models.SeriesSample, name string, resource map[string]string, points []otlp.HistogramDataPoint, now time.Time) ([]models.SeriesSample, int) {
+	malformed := 0
+	for _, p := range points {
+		if p.Flags&otlp.FlagNoRecordedValue != 0 {
+			continue
+		}
+		// The last bucket counts everything above the last bound, so there
+		// is exactly one more count than bounds.
+		if len(p.BucketCounts) != len(p.ExplicitBounds)+1 {
+			malformed++
+			continue
+		}
+		labels := otlpLabels(resource, p.Attributes)
+		ts := otlpTime(p.TimeUnixNano, now)
+
+		var cumulative uint64
+		for i, c := range p.BucketCounts {
+			cumulative += uint64(c)
+			le := "+Inf"
+			if i < len(p.ExplicitBounds) {
+				le = strconv.FormatFloat(p.ExplicitBounds[i], 'g', -1, 64)
+			}
+			bucket := otlpLabels(labels, nil)
+			bucket["le"] = le
+			samples = append(samples, models.SeriesSample{Name: name + "_bucket", Labels: bucket, Timestamp: ts, Value: float64(cumulative)})
+		}
+		samples = append(samples, models.SeriesSample{Name: name + "_count", Labels: labels, Timestamp: ts, Value: float64(p.Count)})
+		if p.Sum != nil {
+			samples = append(samples, models.SeriesSample{Name: name + "_sum", Labels: labels, Timestamp: ts, Value: *p.Sum})
+		}
+	}
+	return samples, malformed
+}
+
+// otlpLabels returns a copy of base with attrs added under sanitized names.
+// The host.name attribute becomes the host label.
+func otlpLabels(base map[string]string, attrs []otlp.KeyValue) map[string]string {
+	labels := make(map[string]string, len(base)+len(attrs))
+	for k, v := range base {
+		labels[k] = v
+	}
+	for _, kv := range attrs {
+		key := sanitizeLabelName(kv.Key)
+		if kv.Key == OTLPHostAttribute {
+			key = HostLabel
+		}
+		if key == "" {
+			continue
+		}
+		if value := kv.Value.String(); value != "" {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+func otlpTime(unixNano otlp.Uint64, now time.Time) time.Time {
+	if unixNano == 0 {
+		return now
+	}
+	return time.Unix(0, int64(unixNano)).UTC()
+}
+
+// otlpDelta reports whether m is a sum or histogram with delta temporality.
+func otlpDelta(m otlp.Metric) bool {
+	switch {
+	case m.Sum != nil:
+		return m.Sum.AggregationTemporality == otlp.AggregationTemporalityDelta
+	case m.Histogram != nil:
+		return m.Histogram.AggregationTemporality == otlp.AggregationTemporalityDelta
+	}
+	return false
+}
+
+func otlpPointCount(m otlp.Metric) int {
+	switch {
+	case m.Gauge != nil:
+		return len(m.Gauge.DataPoints)
+	case m.Sum != nil:
+		return len(m.Sum.DataPoints)
+	case m.Histogram != nil:
+		return len(m.Histogram.DataPoints)
+	case m.ExponentialHistogram != nil:
+		return len(m.ExponentialHistogram.DataPoints)
+	case m.Summary != nil:
+		return len(m.Summary.DataPoints)
+	}
+	return 0
+}
+
+// sanitizeMetricName replaces the characters not allowed in a Prometheus
+// metric name with underscores.
+func sanitizeMetricName(name string) string {
+	return sanitizeName(name, true)
+}
+
+// sanitizeLabelName is sanitizeMetricName for label names, which may not
+// contain colons.
+func sanitizeLabelName(name string) string {
+	return sanitizeName(name, false)
+}
+
+func sanitizeName(name string, colons bool) string {
+	if name == "" {
+		return ""
+	}
+	b := []byte(name)
+	for i, c := range b {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || (colons && c == ':')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		return "_" + string(b)
+	}
+	return string(b)
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/config"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/otlp"
+	"go.uber.org/zap"
+)
+
+var otlpClient = &http.Client{Timeout: 10 * time.Second}
+
+// OTLPRetryBackoff is the delay before the first retry of a failed export;
+// it doubles with every further attempt.
+var OTLPRetryBackoff = time.Second
+
+// errOTLPPermanent marks export failures that retrying cannot fix.
+var errOTLPPermanent = errors.New("export rejected")
+
+// otlpBuffer holds collected samples until they are exported.
+var otlpBuffer = &sampleBuffer[models.SeriesSample]{name: "otlp"}
+
+// otlpSink sends collected samples to the OTLP export buffer, which keeps the
+// newest cfg.OTLPBufferSize samples while the endpoint is unreachable.
+type otlpSink struct{}
+
+func newOTLPSink(cfg *models.Config) otlpSink {
+	otlpBuffer.Lock()
+	otlpBuffer.size = cfg.OTLPBufferSize
+	otlpBuffer.Unlock()
+	return otlpSink{}
+}
+
+func (otlpSink) Name() string { return "otlp" }
+
+func (otlpSink) Write(_ context.Context, batch models.SampleBatch) error {
+	otlpBuffer.add(batchSamples(batch))
+	return nil
+}
+
+// OTLPExporter exports buffered samples every cfg.OTLPExportInterval and
+// flushes once more when ctx is cancelled.
+func OTLPExporter(ctx context.Context, cfg *models.Config, errChan chan error) {
+	ticker := time.NewTicker(cfg.OTLPExportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := FlushOTLPExport(ctx, cfg); err != nil {
+				errChan <- err
+			}
+		case <-ctx.Done():
+			logger.Log.Info("Stopping OTLP Exporter...")
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := FlushOTLPExport(flushCtx, cfg); err != nil {
+				logger.Log.Error("Final OTLP export failed", zap.Error(err))
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// FlushOTLPExport exports everything buffered so far in batches of
+// cfg.OTLPBatchSize samples. A batch the endpoint could not take after
+// cfg.OTLPMaxRetries retries stays buffered for the next flush; a batch it
+// rejected as invalid is dropped.
+func FlushOTLPExport(ctx context.Context, cfg *models.Config) error {
+	for {
+		batch := otlpBuffer.peek(cfg.OTLPBatchSize)
+		if len(batch) == 0 {
+			return nil
+		}
+		err := exportOTLPWithRetry(ctx, cfg, otlpExportRequest(batch))
+		if err != nil && !errors.Is(err, errOTLPPermanent) {
+			return err
+		}
+
+		otlpBuffer.remove(len(batch))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func exportOTLPWithRetry(ctx context.Context, cfg *models.Config, req *otlp.ExportMetricsServiceRequest) error {
+	body := req.Marshal()
+	backoff := OTLPRetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := ExportOTLP(ctx, cfg, body)
+		if err == nil || errors.Is(err, errOTLPPermanent) || attempt >= cfg.OTLPMaxRetries {
+			return err
+		}
+
+		logger.Log.Warn("OTLP export failed, retrying", zap.Duration("backoff", backoff), zap.Error(err))
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// ExportOTLP POSTs one protobuf encoded ExportMetricsServiceRequest to
+// cfg.OTLPEndpoint. Responses that the OTLP specification does not allow to
+// be retried are reported as permanent failures.
+func ExportOTLP(ctx context.Context, cfg *models.Config, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.OTLPEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range cfg.OTLPHeaders {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := otlpClient.Do(req)
+	if err != nil {
+		logger.Log.Error("Failed to export OTLP metrics", zap.String("endpoint", cfg.OTLPEndpoint), zap.Error(err))
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode/100 == 2:
+		var result otlp.ExportMetricsServiceResponse
+		if err := result.Unmarshal(respBody); err == nil && result.PartialSuccess != nil && result.PartialSuccess.RejectedDataPoints > 0 {
+			logger.Log.Warn("OTLP endpoint rejected data points",
+				zap.Int64("rejected", int64(result.PartialSuccess.RejectedDataPoints)),
+				zap.String("message", result.PartialSuccess.ErrorMessage))
+		}
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusBadGateway,
+		resp.StatusCode == http.StatusServiceUnavailable, resp.StatusCode == http.StatusGatewayTimeout:
+		return fmt.Errorf("export to %s failed: %s", cfg.OTLPEndpoint, resp.Status)
+	default:
+		err := fmt.Errorf("%w by %s: %s", errOTLPPermanent, cfg.OTLPEndpoint, resp.Status)
+		logger.Log.Error("Failed to export OTLP metrics", zap.Error(err))
+		return err
+	}
+}
+
+// otlpExportRequest groups samples into one resource per host. Series named
+// with a _total suffix are exported as monotonic cumulative sums without the
+// suffix, everything else as gauges.
+func otlpExportRequest(samples []models.SeriesSample) *otlp.ExportMetricsServiceRequest {
+	req := &otlp.ExportMetricsServiceRequest{}
+	resources := make(map[string]int)
+	metrics := make(map[[2]string]int)
+
+	for _, s := range samples {
+		host := s.Labels[HostLabel]
+		r, ok := resources[host]
+		if !ok {
+			r = len(req.ResourceMetrics)
+			resources[host] = r
+			rm := otlp.ResourceMetrics{ScopeMetrics: []otlp.ScopeMetrics{{
+				Scope: otlp.InstrumentationScope{Name: "metrics-monitor", Version: config.Version},
+			}}}
+			if host != "" {
+				rm.Resource.Attributes = []otlp.KeyValue{{Key: OTLPHostAttribute, Value: otlp.StringValue(host)}}
+			}
+			req.ResourceMetrics = append(req.ResourceMetrics, rm)
+		}
+		scope := &req.ResourceMetrics[r].ScopeMetrics[0]
+
+		m, ok := metrics[[2]string{host, s.Name}]
+		if !ok {
+			m = len(scope.Metrics)
+			metrics[[2]string{host, s.Name}] = m
+			if name, found := strings.CutSuffix(s.Name, "_total"); found {
+				scope.Metrics = append(scope.Metrics, otlp.Metric{Name: name, Sum: &otlp.Sum{
+					AggregationTemporality: otlp.AggregationTemporalityCumulative,
+					IsMonotonic:            true,
+				}})
+			} else {
+				scope.Metrics = append(scope.Metrics, otlp.Metric{Name: s.Name, Gauge: &otlp.Gauge{}})
+			}
+		}
+
+		value := s.Value
+		point := otlp.NumberDataPoint{TimeUnixNano: otlp.Uint64(s.Timestamp.UnixNano()), AsDouble: &value}
+		keys := make([]string, 0, len(s.Labels))
+		for k := range s.Labels {
+			if k != HostLabel {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			point.Attributes = append(point.Attributes, otlp.KeyValue{Key: k, Value: otlp.StringValue(s.Labels[k])})
+		}
+
+		metric := &scope.Metrics[m]
+		if metric.Sum != nil {
+			point.StartTimeUnixNano = otlp.Uint64(startTime.UnixNano())
+			metric.Sum.DataPoints = append(metric.Sum.DataPoints, point)
+		} else {
+			metric.Gauge.DataPoints = append(metric.Gauge.DataPoints, point)
+		}
+	}
+	return req
+}
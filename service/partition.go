@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/database"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"go.uber.org/zap"
+)
+
+// PartitionMaintainer pre-creates upcoming metrics partitions every hour so
+// inserts never fall outside a partition. Expired partitions are dropped by
+// the RetentionPruner.
+func PartitionMaintainer(ctx context.Context, cfg *models.Config, errChan chan error) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := database.MaintainPartitions(ctx, cfg, time.Now()); err != nil {
+				logger.Log.Error("Error creating upcoming partitions", zap.Error(err))
+				errChan <- err
+			}
+		case <-ctx.Done():
+			logger.Log.Info("Stopping Partition Maintainer...")
+			return
+		}
+	}
+}
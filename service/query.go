@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/database"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/promql"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QueryEngine evaluates PromQL queries over the series store and the CPU and
+// memory columns of the metrics table.
+var QueryEngine = &promql.Engine{Querier: SeriesQuerier{}}
+
+// SeriesQuerier serves PromQL selectors from storage. The metrics table is
+// exposed as the series cpu_percent and mem_percent with a host label.
+type SeriesQuerier struct{}
+
+// Select implements promql.Querier. Matchers are translated into SQL; on
+// databases without regular expressions, i.e. SQLite, regexp matchers are
+// resolved against the distinct stored values first.
+func (SeriesQuerier) Select(ctx context.Context, matchers []*promql.Matcher, start, end time.Time) ([]promql.Series, error) {
+	if database.DB == nil {
+		logger.Log.Error("Database is not initialized")
+		return nil, gorm.ErrInvalidDB
+	}
+
+	series, err := selectGaugeSeries(ctx, matchers, start, end)
+	if err != nil {
+		logger.Log.Error("Error selecting metrics series", zap.Error(err))
+		return nil, err
+	}
+	stored, err := selectStoredSeries(ctx, matchers, start, end)
+	if err != nil {
+		logger.Log.Error("Error selecting stored series", zap.Error(err))
+		return nil, err
+	}
+	return append(series, stored...), nil
+}
+
+// SelectSeriesIDs returns the ids and labels of the stored series matching
+// every matcher.
+func SelectSeriesIDs(ctx context.Context, matchers []*promql.Matcher) (map[uint64]promql.Labels, error) {
+	query := database.DB.WithContext(ctx).Model(&models.Series{}).Select("id, name")
+	for _, m := range matchers {
+		if m.Name == promql.MetricNameLabel {
+			cond, err := matcherCondition(ctx, "name", m, func(db *gorm.DB) *gorm.DB { return db.Model(&models.Series{}) })
+			if err != nil {
+				return nil, err
+			}
+			query = query.Where(cond)
+			continue
+		}
+
+		// A series without the label matches whatever matches "".
+		labelRows := func(db *gorm.DB) *gorm.DB {
+			return db.Model(&models.SeriesLabel{}).Where("key = ?", m.Name)
+		}
+		if m.Matches("") {
+			negated, _ := promql.NewMatcher(negateMatchType(m.Type), m.Name, m.Value)
+			cond, err := matcherCondition(ctx, "value", negated, labelRows)
+			if err != nil {
+				return nil, err
+			}
+			query = query.Where("id NOT IN (?)", labelRows(database.DB.WithContext(ctx)).Select("series_id").Where(cond))
+		} else {
+			cond, err := matcherCondition(ctx, "value", m, labelRows)
+			if err != nil {
+				return nil, err
+			}
+			query = query.Where("id IN (?)", labelRows(database.DB.WithContext(ctx)).Select("series_id").Where(cond))
+		}
+	}
+
+	var rows []models.Series
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	return loadSeriesLabels(ctx, rows)
+}
+
+// loadSeriesLabels returns the labels of series, including their name.
+func loadSeriesLabels(ctx context.Context, series []models.Series) (map[uint64]promql.Labels, error) {
+	ids := make([]uint64, len(series))
+	labels := make(map[uint64]promql.Labels, len(series))
+	for i, s := range series {
+		ids[i] = s.ID
+		labels[s.ID] = promql.Labels{promql.MetricNameLabel: s.Name}
+	}
+
+	var rows []models.SeriesLabel
+	if err := database.DB.WithContext(ctx).Where("series_id IN ?", ids).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if row.Value != "" {
+			labels[row.SeriesID][row.Key] = row.Value
+		}
+	}
+	return labels, nil
+}
+
+func selectStoredSeries(ctx context.Context, matchers []*promql.Matcher, start, end time.Time) ([]promql.Series, error) {
+	labels, err := SelectSeriesIDs(ctx, matchers)
+	if err != nil || len(labels) == 0 {
+		return nil, err
+	}
+	ids := make([]uint64, 0, len(labels))
+	for id := range labels {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var samples []models.Sample
+	if err := database.DB.WithContext(ctx).
+		Where("series_id IN ? AND ts >= ? AND ts <= ?", ids, start.UTC(), end.UTC()).
+		Order("series_id ASC, ts ASC").
+		Find(&samples).Error; err != nil {
+		return nil, err
+	}
+
+	index := make(map[uint64]int)
+	var series []promql.Series
+	for _, s := range samples {
+		i, ok := index[s.SeriesID]
+		if !ok {
+			i = len(series)
+			index[s.SeriesID] = i
+			series = append(series, promql.Series{Labels: labels[s.SeriesID]})
+		}
+		series[i].Points = append(series[i].Points, promql.Point{T: s.Timestamp.UnixMilli(), V: s.Value})
+	}
+	return series, nil
+}
+
+// selectGaugeSeries serves the cpu_percent and mem_percent series, which
+// only carry a host label, from the metrics table.
+func selectGaugeSeries(ctx context.Context, matchers []*promql.Matcher, start, end time.Time) ([]promql.Series, error) {
+	var series []promql.Series
+
+	for _, name := range []string{"cpu_percent", "mem_percent"} {
+		query := database.DB.WithContext(ctx).
+			Model(&models.Metrics{}).
+			Select("host, created_at, "+aggregateColumns[name]).
+			Where("created_at >= ? AND created_at <= ?", start.UTC(), end.UTC())
+
+		matches := true
+		for _, m := range matchers {
+			switch m.Name {
+			case promql.MetricNameLabel:
+				matches = matches && m.Matches(name)
+			case HostLabel:
+				cond, err := matcherCondition(ctx, "host", m, func(db *gorm.DB) *gorm.DB { return db.Model(&models.Metrics{}) })
+				if err != nil {
+					return nil, err
+				}
+				query = query.Where(cond)
+			default:
+				matches = matches && m.Matches("")
+			}
+		}
+		if !matches {
+			continue
+		}
+
+		var rows []models.Metrics
+		if err := query.Order("host ASC, created_at ASC").Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for i, row := range rows {
+			if i == 0 || row.Host != rows[i-1].Host {
+				labels := promql.Labels{promql.MetricNameLabel: name}
+				if row.Host != "" {
+					labels[HostLabel] = row.Host
+				}
+				series = append(series, promql.Series{Labels: labels})
+			}
+			value := row.CPUPercent
+			if name == "mem_percent" {
+				value = row.MemPercent
+			}
+			s := &series[len(series)-1]
+			s.Points = append(s.Points, promql.Point{T: row.CreatedAt.UnixMilli(), V: value})
+		}
+	}
+	return series, nil
+}
+
+// matcherCondition is the SQL condition of column satisfying m. Without
+// regular expression support in the database, the distinct values of column
+// in the rows of scope are matched here instead.
+func matcherCondition(ctx context.Context, column string, m *promql.Matcher, scope func(*gorm.DB) *gorm.DB) (clause.Expr, error) {
+	switch m.Type {
+	case promql.MatchEqual:
+		return gorm.Expr(column+" = ?", m.Value), nil
+	case promql.MatchNotEqual:
+		return gorm.Expr(column+" <> ?", m.Value), nil
+	}
+
+	if database.DB.Dialector.Name() == "postgres" {
+		op := " ~ ?"
+		if m.Type == promql.MatchNotRegexp {
+			op = " !~ ?"
+		}
+		return gorm.Expr(column+op, m.Anchored()), nil
+	}
+
+	var values []string
+	if err := scope(database.DB.WithContext(ctx)).Distinct(column).Pluck(column, &values).Error; err != nil {
+		return clause.Expr{}, err
+	}
+	matching := []string{}
+	for _, v := range values {
+		if m.Matches(v) {
+			matching = append(matching, v)
+		}
+	}
+	if len(matching) == 0 {
+		return gorm.Expr("1 = 0"), nil
+	}
+	return gorm.Expr(column+" IN ?", matching), nil
+}
+
+func negateMatchType(t promql.MatchType) promql.MatchType {
+	switch t {
+	case promql.MatchEqual:
+		return promql.MatchNotEqual
+	case promql.MatchNotEqual:
+		return promql.MatchEqual
+	case promql.MatchRegexp:
+		return promql.MatchNotRegexp
+	}
+	return promql.MatchRegexp
+}
+
+// LabelNames lists every label name known to the query engine.
+func LabelNames(ctx context.Context) ([]string, error) {
+	var names []string
+	if err := database.DB.WithContext(ctx).Model(&models.SeriesLabel{}).Distinct("key").Pluck("key", &names).Error; err != nil {
+		return nil, err
+	}
+	return sortedUnique(append(names, promql.MetricNameLabel, HostLabel)), nil
+}
+
+// LabelValues lists the values of label name known to the query engine.
+func LabelValues(ctx context.Context, name string) ([]string, error) {
+	var values []string
+	var err error
+	switch name {
+	case promql.MetricNameLabel:
+		err = database.DB.WithContext(ctx).Model(&models.Series{}).Distinct("name").Pluck("name", &values).Error
+		values = append(values, "cpu_percent", "mem_percent")
+	case HostLabel:
+		err = database.DB.WithContext(ctx).Model(&models.Host{}).Pluck("hostname", &values).Error
+	default:
+		err = database.DB.WithContext(ctx).Model(&models.SeriesLabel{}).Where("key = ?", name).Distinct("value").Pluck("value", &values).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sortedUnique(values), nil
+}
+
+func sortedUnique(values []string) []string {
+	sort.Strings(values)
+	out := make([]string, 0, len(values))
+	for i, v := range values {
+		if v != "" && (i == 0 || v != values[i-1]) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/prompb"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/promql"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrMissingMetricName = errors.New("series without a metric name")
+	ErrInvalidMatcher    = errors.New("invalid label matcher")
+)
+
+var remoteMatchTypes = map[prompb.MatcherType]promql.MatchType{
+	prompb.MatchEqual:     promql.MatchEqual,
+	prompb.MatchNotEqual:  promql.MatchNotEqual,
+	prompb.MatchRegexp:    promql.MatchRegexp,
+	prompb.MatchNotRegexp: promql.MatchNotRegexp,
+}
+
+// IngestRemoteWrite stores the samples of a Prometheus remote write request
+// with their labels and returns how many were stored. NaN values, which
+// include Prometheus' staleness markers, are skipped.
+func IngestRemoteWrite(ctx context.Context, req *prompb.WriteRequest) (int, error) {
+	var samples []models.SeriesSample
+	for _, ts := range req.Timeseries {
+		labels := make(map[string]string, len(ts.Labels))
+		for _, l := range ts.Labels {
+			labels[l.Name] = l.Value
+		}
+		name := labels[promql.MetricNameLabel]
+		if name == "" {
+			return 0, ErrMissingMetricName
+		}
+		delete(labels, promql.MetricNameLabel)
+
+		for _, s := range ts.Samples {
+			if math.IsNaN(s.Value) {
+				continue
+			}
+			samples = append(samples, models.SeriesSample{
+				Name:      name,
+				Labels:    labels,
+				Timestamp: time.UnixMilli(s.Timestamp).UTC(),
+				Value:     s.Value,
+			})
+		}
+	}
+
+	if err := WriteSamples(ctx, samples); err != nil {
+		logger.Log.Error("Error storing remote write samples", zap.Error(err))
+		return 0, err
+	}
+	ingestedSamples.Add(int64(len(samples)))
+	logger.Log.Debug("Stored remote write samples", zap.Int("series", len(req.Timeseries)), zap.Int("samples", len(samples)))
+	return len(samples), nil
+}
+
+// RemoteRead answers the queries of a Prometheus remote read request with the
+// matching raw samples. Matchers are translated into storage queries by
+// SeriesQuerier, so both the metrics table and the series store are served.
+func RemoteRead(ctx context.Context, req *prompb.ReadRequest) (*prompb.ReadResponse, error) {
+	resp := &prompb.ReadResponse{Results: make([]prompb.QueryResult, len(req.Queries))}
+	for i, q := range req.Queries {
+		matchers := make([]*promql.Matcher, len(q.Matchers))
+		for j, lm := range q.Matchers {
+			t, ok := remoteMatchTypes[lm.Type]
+			if !ok {
+				return nil, fmt.Errorf("%w: unknown type %d", ErrInvalidMatcher, lm.Type)
+			}
+			m, err := promql.NewMatcher(t, lm.Name, lm.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidMatcher, err)
+			}
+			matchers[j] = m
+		}
+
+		series, err := SeriesQuerier{}.Select(ctx, matchers, time.UnixMilli(q.StartTimestampMs), time.UnixMilli(q.EndTimestampMs))
+		if err != nil {
+			return nil, err
+		}
+		resp.Results[i].Timeseries = remoteTimeSeries(series)
+	}
+	return resp, nil
+}
+
+// remoteTimeSeries converts series into remote read results sorted by labels,
+// with each series' labels sorted by name as Prometheus expects.
+func remoteTimeSeries(series []promql.Series) []prompb.TimeSeries {
+	res := make([]prompb.TimeSeries, 0, len(series))
+	for _, s := range series {
+		ts := prompb.TimeSeries{
+			Labels:  make([]prompb.Label, 0, len(s.Labels)),
+			Samples: make([]prompb.Sample, len(s.Points)),
+		}
+		for name, value := range s.Labels {
+			ts.Labels = append(ts.Labels, prompb.Label{Name: name, Value: value})
+		}
+		sort.Slice(ts.Labels, func(i, j int) bool { return ts.Labels[i].Name < ts.Labels[j].Name })
+		for i, p := range s.Points {
+			ts.Samples[i] = prompb.Sample{Value: p.V, Timestamp: p.T}
+		}
+		res = append(res, ts)
+	}
+	sort.Slice(res, func(i, j int) bool { return labelsLess(res[i].Labels, res[j].Labels) })
+	return res
+}
+
+func labelsLess(a, b []prompb.Label) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i].Name != b[i].Name {
+			return a[i].Name < b[i].Name
+		}
+		if a[i].Value != b[i].Value {
+			return a[i].Value < b[i].Value
+		}
+	}
+	return len(a) < len(b)
+}
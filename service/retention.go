@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/database"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// RetentionTarget is a table whose rows expire once they are older than the
+// retention configured for Name (RETENTION_OVERRIDES), its own Default, or
+// the global RETENTION_PERIOD, in that order. KeyColumns lists the primary
+// key, so that a batch deletes exactly the rows it selected.
+type RetentionTarget struct {
+	Name       string
+	Table      string
+	KeyColumns string
+	TimeColumn string
+	Default    time.Duration
+}
+
+var retentionTargets = []RetentionTarget{
+	{Name: "metrics", Table: "metrics", KeyColumns: "id", TimeColumn: "created_at"},
+}
+
+var retentionState struct {
+	sync.Mutex
+	cfg          *models.Config
+	totalDeleted int64
+	lastRuns     map[string]models.RetentionRun
+}
+
+// RetentionPruner deletes expired rows once on start and then every
+// cfg.RetentionInterval until ctx is cancelled.
+func RetentionPruner(ctx context.Context, cfg *models.Config, errChan chan error) {
+	retentionState.Lock()
+	retentionState.cfg = cfg
+	retentionState.Unlock()
+
+	for name := range cfg.RetentionOverrides {
+		if _, ok := findRetentionTarget(name); !ok {
+			logger.Log.Warn("Retention override for unknown metric", zap.String("name", name))
+		}
+	}
+
+	ticker := time.NewTicker(cfg.RetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, run := range PruneExpiredMetrics(ctx, cfg) {
+			if run.Error != "" {
+				errChan <- fmt.Errorf("retention %s: %s", run.Target, run.Error)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			logger.Log.Info("Stopping Retention Pruner...")
+			return
+		}
+	}
+}
+
+// PruneExpiredMetrics applies the retention policy to every target once.
+func PruneExpiredMetrics(ctx context.Context, cfg *models.Config) []models.RetentionRun {
+	var runs []models.RetentionRun
+	for _, target := range retentionTargets {
+		retention := RetentionFor(cfg, target)
+		if retention <= 0 {
+			continue
+		}
+		run, err := PruneTarget(ctx, target, retention, cfg.RetentionBatchSize)
+		if err != nil {
+			run.Error = err.Error()
+		}
+		recordRetentionRun(run)
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// RetentionFor returns the retention for a target, 0 meaning keep forever.
+func RetentionFor(cfg *models.Config, target RetentionTarget) time.Duration {
+	if d, ok := cfg.RetentionOverrides[target.Name]; ok {
+		return d
+	}
+	if target.Default > 0 {
+		return target.Default
+	}
+	return cfg.RetentionPeriod
+}
+
+// RetentionEnabled reports whether any target has a finite retention.
+func RetentionEnabled(cfg *models.Config) bool {
+	for _, target := range retentionTargets {
+		if RetentionFor(cfg, target) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// PruneTarget deletes rows older than retention in batches of batchSize so a
+// large backlog never holds one long-running transaction.
+func PruneTarget(ctx context.Context, target RetentionTarget, retention time.Duration, batchSize int) (models.RetentionRun, error) {
+	started := time.Now()
+	run := models.RetentionRun{
+		Target:    target.Name,
+		Retention: retention.String(),
+		Cutoff:    started.UTC().Add(-retention),
+		StartedAt: started.UTC(),
+	}
+
+	if database.DB == nil {
+		logger.Log.Error("Database is not initialized")
+		return run, gorm.ErrInvalidDB
+	}
+
+	if target.Table == "metrics" && database.Timescale {
+		// Hypertables expire whole chunks, which also covers compressed data.
+		dropped, err := database.DropTimescaleChunks(ctx, target.Table, run.Cutoff)
+		run.Dropped = dropped
+		run.DurationMs = time.Since(started).Milliseconds()
+		if err != nil {
+			logger.Log.Error("Error dropping expired chunks", zap.String("target", target.Name), zap.Error(err))
+			return run, err
+		}
+		logger.Log.Info("Retention pruning completed",
+			zap.String("target", target.Name),
+			zap.Time("cutoff", run.Cutoff),
+			zap.Int("dropped_chunks", run.Dropped),
+			zap.Int64("duration_ms", run.DurationMs))
+		return run, nil
+	}
+
+	if target.Table == "metrics" && database.Partitioned {
+		// Expired days or weeks are dropped as whole partitions.
+		dropped, err := database.DropExpiredPartitions(ctx, target.Table, run.Cutoff)
+		run.Dropped = dropped
+		run.DurationMs = time.Since(started).Milliseconds()
+		if err != nil {
+			logger.Log.Error("Error dropping expired partitions", zap.String("target", target.Name), zap.Error(err))
+			return run, err
+		}
+		logger.Log.Info("Retention pruning completed",
+			zap.String("target", target.Name),
+			zap.Time("cutoff", run.Cutoff),
+			zap.Int("dropped_partitions", run.Dropped),
+			zap.Int64("duration_ms", run.DurationMs))
+		return run, nil
+	}
+
+	query := fmt.Sprintf("DELETE FROM %[1]s WHERE (%[2]s) IN (SELECT %[2]s FROM %[1]s WHERE %[3]s < ? LIMIT ?)",
+		target.Table, target.KeyColumns, target.TimeColumn)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			run.DurationMs = time.Since(started).Milliseconds()
+			return run, err
+		}
+
+		res := database.DB.WithContext(ctx).Exec(query, run.Cutoff, batchSize)
+		if res.Error != nil {
+			logger.Log.Error("Error pruning expired metrics", zap.String("target", target.Name), zap.Error(res.Error))
+			run.DurationMs = time.Since(started).Milliseconds()
+			return run, res.Error
+		}
+		run.Batches++
+		run.Deleted += res.RowsAffected
+		if res.RowsAffected < int64(batchSize) {
+			break
+		}
+	}
+
+	run.DurationMs = time.Since(started).Milliseconds()
+	logger.Log.Info("Retention pruning completed",
+		zap.String("target", target.Name),
+		zap.Time("cutoff", run.Cutoff),
+		zap.Int64("deleted", run.Deleted),
+		zap.Int("batches", run.Batches),
+		zap.Int64("duration_ms", run.DurationMs))
+
+	return run, nil
+}
+
+// GetRetentionStatus reports the active policy and the outcome of the last
+// pruning run of every target.
+func GetRetentionStatus() models.RetentionStatus {
+	retentionState.Lock()
+	defer retentionState.Unlock()
+
+	status := models.RetentionStatus{TotalDeleted: retentionState.totalDeleted}
+	if cfg := retentionState.cfg; cfg != nil {
+		status.Enabled = true
+		status.Default = cfg.RetentionPeriod.String()
+		status.Interval = cfg.RetentionInterval.String()
+		status.BatchSize = cfg.RetentionBatchSize
+		status.Overrides = make(map[string]string, len(cfg.RetentionOverrides))
+		for name, d := range cfg.RetentionOverrides {
+			status.Overrides[name] = d.String()
+		}
+	}
+	for _, target := range retentionTargets {
+		if run, ok := retentionState.lastRuns[target.Name]; ok {
+			status.LastRuns = append(status.LastRuns, run)
+		}
+	}
+	return status
+}
+
+func recordRetentionRun(run models.RetentionRun) {
+	retentionState.Lock()
+	defer retentionState.Unlock()
+
+	if retentionState.lastRuns == nil {
+		retentionState.lastRuns = make(map[string]models.RetentionRun)
+	}
+	retentionState.lastRuns[run.Target] = run
+	retentionState.totalDeleted += run.Deleted
+}
+
+func findRetentionTarget(name string) (RetentionTarget, bool) {
+	for _, target := range retentionTargets {
+		if target.Name == name {
+			return target, true
+		}
+	}
+	return RetentionTarget{}, false
+}
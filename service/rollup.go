@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/config"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/database"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// RollupTier is one downsampling level. Each tier is built from the next
+// finer one (Source), the 1 minute tier from the raw metrics table.
+type RollupTier struct {
+	Name      string
+	Width     time.Duration
+	Source    string
+	Retention time.Duration
+}
+
+// RollupTiers are ordered from finest to coarsest.
+var RollupTiers = []RollupTier{
+	{Name: models.RollupTable1m, Width: time.Minute, Retention: 30 * 24 * time.Hour},
+	{Name: models.RollupTable1h, Width: time.Hour, Source: models.RollupTable1m, Retention: 365 * 24 * time.Hour},
+	{Name: models.RollupTable1d, Width: 24 * time.Hour, Source: models.RollupTable1h, Retention: 5 * 365 * 24 * time.Hour},
+}
+
+// maxRollupBuckets bounds how many buckets one pass reads from its source.
+const maxRollupBuckets = 1440
+
+// rollupDelay holds a bucket back a little after it ends so samples pushed
+// by agents have time to arrive.
+const rollupDelay = 30 * time.Second
+
+// lateSamples holds the time of the oldest sample ingested since the last
+// rollup pass. Agents that were cut off push samples for buckets that are
+// already rolled up, and those buckets are rebuilt on the next pass.
+var lateSamples struct {
+	sync.Mutex
+	since time.Time
+}
+
+// noteIngested records that samples from ts on were stored.
+func noteIngested(ts time.Time) {
+	lateSamples.Lock()
+	defer lateSamples.Unlock()
+	if lateSamples.since.IsZero() || ts.Before(lateSamples.since) {
+		lateSamples.since = ts
+	}
+}
+
+func init() {
+	for _, tier := range RollupTiers {
+		retentionTargets = append(retentionTargets, RetentionTarget{
+			Name:       tier.Name,
+			Table:      tier.Name,
+			KeyColumns: "host, bucket",
+			TimeColumn: "bucket",
+			Default:    tier.Retention,
+		})
+	}
+}
+
+// RollupScheduler refreshes every rollup tier each cfg.RollupInterval until
+// ctx is cancelled.
+func RollupScheduler(ctx context.Context, cfg *models.Config, errChan chan error) {
+	ticker := time.NewTicker(cfg.RollupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := RunRollups(ctx, time.Now()); err != nil {
+				errChan <- err
+			}
+		case <-ctx.Done():
+			logger.Log.Info("Stopping Rollup Scheduler...")
+			return
+		}
+	}
+}
+
+// RunRollups aggregates every complete bucket up to now into each tier.
+func RunRollups(ctx context.Context, now time.Time) error {
+	if database.DB == nil {
+		logger.Log.Error("Database is not initialized")
+		return gorm.ErrInvalidDB
+	}
+
+	lateSamples.Lock()
+	since := lateSamples.since
+	lateSamples.since = time.Time{}
+	lateSamples.Unlock()
+
+	for _, tier := range RollupTiers {
+		if err := rollupTier(ctx, tier, now.UTC(), since); err != nil {
+			logger.Log.Error("Error building rollup", zap.String("tier", tier.Name), zap.Error(err))
+			if !since.IsZero() {
+				noteIngested(since)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// rollupTier aggregates the buckets of tier from its watermark, or from the
+// bucket holding since when samples arrived for buckets already written, up
+// to now.
+func rollupTier(ctx context.Context, tier RollupTier, now, since time.Time) error {
+	end := now.Add(-rollupDelay).Truncate(tier.Width)
+
+	from, ok, err := rollupWatermark(ctx, tier)
+	if err != nil || !ok {
+		return err
+	}
+	if !since.IsZero() && since.Before(from) {
+		from = since.UTC().Truncate(tier.Width)
+	}
+
+	for from.Before(end) {
+		to := from.Add(tier.Width * maxRollupBuckets)
+		if to.After(end) {
+			to = end
+		}
+
+		buckets, err := rollupBuckets(ctx, tier, from, to)
+		if err != nil {
+			return err
+		}
+		logger.Log.Debug("Rollup pass completed",
+			zap.String("tier", tier.Name),
+			zap.Time("from", from),
+			zap.Time("to", to),
+			zap.Int64("buckets", buckets))
+
+		from = to
+		if buckets == 0 {
+			// Jump over a gap in the source data in one step.
+			next, ok, err := firstSourceTime(ctx, tier, to)
+			if err != nil || !ok {
+				return err
+			}
+			from = next.Truncate(tier.Width)
+		}
+	}
+	return nil
+}
+
+// rollupWatermark returns where the next pass starts: the latest bucket
+// already written, so late samples still land in it, or the first source
+// sample when the tier is empty.
+func rollupWatermark(ctx context.Context, tier RollupTier) (time.Time, bool, error) {
+	var latest []models.MetricsRollup
+	if err := database.DB.WithContext(ctx).
+		Table(tier.Name).
+		Order("bucket DESC").
+		Limit(1).
+		Find(&latest).Error; err != nil {
+		return time.Time{}, false, err
+	}
+	if len(latest) > 0 {
+		return latest[0].Bucket.UTC(), true, nil
+	}
+
+	first, ok, err := firstSourceTime(ctx, tier, time.Time{})
+	return first.Truncate(tier.Width), ok, err
+}
+
+func firstSourceTime(ctx context.Context, tier RollupTier, after time.Time) (time.Time, bool, error) {
+	if tier.Source == "" {
+		var first []models.Metrics
+		err := database.DB.WithContext(ctx).
+			Where("created_at >= ?", after).
+			Order("created_at ASC").
+			Limit(1).
+			Find(&first).Error
+		if err != nil || len(first) == 0 {
+			return time.Time{}, false, err
+		}
+		return first[0].CreatedAt.UTC(), true, nil
+	}
+
+	var first []models.MetricsRollup
+	err := database.DB.WithContext(ctx).
+		Table(tier.Source).
+		Where("bucket >= ?", after).
+		Order("bucket ASC").
+		Limit(1).
+		Find(&first).Error
+	if err != nil || len(first) == 0 {
+		return time.Time{}, false, err
+	}
+	return first[0].Bucket.UTC(), true, nil
+}
+
+// rollupBuckets aggregates the finer grained rows in [from, to) into the
+// buckets of tier, replacing buckets that were written before, and returns
+// how many buckets it wrote. Raw samples count as single-sample rollups.
+func rollupBuckets(ctx context.Context, tier RollupTier, from, to time.Time) (int64, error) {
+	source, timeColumn := tier.Source, "bucket"
+	cpu, mem, count, lastAt := [4]string{"cpu_min", "cpu_max", "cpu_avg", "cpu_last"}, [4]string{"mem_min", "mem_max", "mem_avg", "mem_last"}, "sample_count", "last_at"
+	if source == "" {
+		source, timeColumn = "metrics", "created_at"
+		cpu, mem, count, lastAt = [4]string{"cpu_percent", "cpu_percent", "cpu_percent", "cpu_percent"}, [4]string{"mem_percent", "mem_percent", "mem_percent", "mem_percent"}, "1", "created_at"
+	}
+
+	width := int64(tier.Width.Seconds())
+	bucket := fmt.Sprintf("date_bin(INTERVAL '%d seconds', %s, TIMESTAMPTZ 'epoch')", width, timeColumn)
+	if database.DB.Dialector.Name() != "postgres" {
+		// Formatted like the timestamps the driver writes, so buckets compare
+		// as text.
+		bucket = fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:%%M:%%S+00:00', CAST(strftime('%%s', %s) AS INTEGER) / %[2]d * %[2]d, 'unixepoch')", timeColumn, width)
+	}
+
+	// The last values are taken from the newest row of each bucket; the
+	// window gives every row of the bucket the same value.
+	res := database.DB.WithContext(ctx).Exec(fmt.Sprintf(`INSERT INTO %[1]s
+			(host, bucket, cpu_min, cpu_max, cpu_avg, cpu_last, mem_min, mem_max, mem_avg, mem_last, sample_count, last_at)
+		SELECT host, bucket,
+			MIN(cpu_min), MAX(cpu_max), ROUND(CAST(SUM(cpu_avg * sample_count) / SUM(sample_count) AS NUMERIC), 2), MAX(cpu_last),
+			MIN(mem_min), MAX(mem_max), ROUND(CAST(SUM(mem_avg * sample_count) / SUM(sample_count) AS NUMERIC), 2), MAX(mem_last),
+			SUM(sample_count), MAX(last_at)
+		FROM (
+			SELECT host, %[3]s AS bucket,
+				%[5]s AS cpu_min, %[6]s AS cpu_max, %[7]s AS cpu_avg, FIRST_VALUE(%[8]s) OVER w AS cpu_last,
+				%[9]s AS mem_min, %[10]s AS mem_max, %[11]s AS mem_avg, FIRST_VALUE(%[12]s) OVER w AS mem_last,
+				%[13]s AS sample_count, %[14]s AS last_at
+			FROM %[2]s
+			WHERE %[4]s >= ? AND %[4]s < ?
+			WINDOW w AS (PARTITION BY host, %[3]s ORDER BY %[14]s DESC)
+		) AS points
+		GROUP BY host, bucket
+		ON CONFLICT (host, bucket) DO UPDATE SET
+			cpu_min = excluded.cpu_min, cpu_max = excluded.cpu_max, cpu_avg = excluded.cpu_avg, cpu_last = excluded.cpu_last,
+			mem_min = excluded.mem_min, mem_max = excluded.mem_max, mem_avg = excluded.mem_avg, mem_last = excluded.mem_last,
+			sample_count = excluded.sample_count, last_at = excluded.last_at`,
+		tier.Name, source, bucket, timeColumn,
+		cpu[0], cpu[1], cpu[2], cpu[3], mem[0], mem[1], mem[2], mem[3], count, lastAt),
+		from, to)
+	return res.RowsAffected, res.Error
+}
+
+// SelectRollupTier picks the coarsest tier whose buckets are no wider than
+// resolution. A zero resolution is derived from the range so that roughly
+// cfg.RollupMaxPoints points come back. ok is false when no tier is fine
+// enough and raw samples should be served instead.
+func SelectRollupTier(start, end time.Time, resolution time.Duration) (RollupTier, bool) {
+	if resolution <= 0 {
+		maxPoints := config.Cfg.RollupMaxPoints
+		if maxPoints <= 0 {
+			maxPoints = 1000
+		}
+		resolution = end.Sub(start) / time.Duration(maxPoints)
+	}
+
+	for i := len(RollupTiers) - 1; i >= 0; i-- {
+		if RollupTiers[i].Width <= resolution {
+			return RollupTiers[i], true
+		}
+	}
+	return RollupTier{}, false
+}
+
+// GetRollupMetrics returns the rollup rows of tier between start and end.
+func GetRollupMetrics(ctx context.Context, tier RollupTier, start, end time.Time, filter models.MetricsFilter) ([]models.MetricsRollup, error) {
+	var res []models.MetricsRollup
+
+	if database.DB == nil {
+		logger.Log.Error("Database is not initialized")
+		return nil, gorm.ErrInvalidDB
+	}
+
+	if err := database.DB.WithContext(ctx).
+		Table(tier.Name).
+		Where("bucket BETWEEN ? AND ?", start, end).
+		Scopes(filterMetrics(filter)).
+		Order("bucket ASC, host ASC").
+		Find(&res).Error; err != nil {
+		logger.Log.Error("Error fetching rollup metrics:", zap.String("tier", tier.Name), zap.Error(err))
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// RollupsAsMetrics returns every rollup row as a metric of its bucket, holding
+// the bucket averages.
+func RollupsAsMetrics(rollups []models.MetricsRollup) []models.Metrics {
+	res := make([]models.Metrics, 0, len(rollups))
+	for _, rollup := range rollups {
+		res = append(res, models.Metrics{
+			Host:       rollup.Host,
+			CPUPercent: rollup.CPUAvg,
+			MemPercent: rollup.MemAvg,
+			CreatedAt:  rollup.Bucket,
+		})
+	}
+	return res
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/database"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HostLabel is the series label holding the name of the reporting host.
+const HostLabel = "host"
+
+// seriesCache maps series fingerprints to their ids so that writing a sample
+// of a known series costs a single insert.
+var seriesCache = struct {
+	sync.Mutex
+	ids map[string]uint64
+}{ids: make(map[string]uint64)}
+
+// ResetSeriesCache forgets the cached series ids. Call it after pointing
+// database.DB at another database.
+func ResetSeriesCache() {
+	seriesCache.Lock()
+	seriesCache.ids = make(map[string]uint64)
+	seriesCache.Unlock()
+}
+
+func init() {
+	retentionTargets = append(retentionTargets, RetentionTarget{
+		Name: "samples", Table: "samples", KeyColumns: "series_id, ts", TimeColumn: "ts",
+	})
+}
+
+// StoreSamples persists collected series samples. StartSinks replaces it,
+// like StoreMetrics.
+var StoreSamples = WriteSamples
+
+// WriteSamples stores samples in the series store, creating their series on
+// first sight. Samples already stored are skipped.
+func WriteSamples(ctx context.Context, samples []models.SeriesSample) error {
+	if database.DB == nil {
+		logger.Log.Error("Database is not initialized")
+		return gorm.ErrInvalidDB
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+
+	rows := make([]models.Sample, 0, len(samples))
+	for _, s := range samples {
+		id, err := seriesID(ctx, s.Name, s.Labels)
+		if err != nil {
+			logger.Log.Error("Error resolving series", zap.String("name", s.Name), zap.Error(err))
+			return err
+		}
+		ts := s.Timestamp
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		rows = append(rows, models.Sample{SeriesID: id, Timestamp: ts.UTC(), Value: s.Value})
+	}
+
+	if err := database.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(&rows, 500).Error; err != nil {
+		logger.Log.Error("Error storing samples", zap.Error(err))
+		return err
+	}
+	publishSamples(samples)
+	return nil
+}
+
+// seriesID returns the id of the series, creating it and its labels when it
+// does not exist yet.
+func seriesID(ctx context.Context, name string, labels map[string]string) (uint64, error) {
+	fingerprint := SeriesFingerprint(name, labels)
+
+	seriesCache.Lock()
+	id, ok := seriesCache.ids[fingerprint]
+	seriesCache.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	err := database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		series := models.Series{Name: name, Host: labels[HostLabel], Fingerprint: fingerprint}
+		if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "fingerprint"}}, DoNothing: true}).
+			Create(&series).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("fingerprint = ?", fingerprint).First(&series).Error; err != nil {
+			return err
+		}
+		id = series.ID
+
+		if len(labels) == 0 {
+			return nil
+		}
+		rows := make([]models.SeriesLabel, 0, len(labels))
+		for key, value := range labels {
+			rows = append(rows, models.SeriesLabel{SeriesID: id, Key: key, Value: value})
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	seriesCache.Lock()
+	seriesCache.ids[fingerprint] = id
+	seriesCache.Unlock()
+	return id, nil
+}
+
+// SeriesFingerprint identifies a series by its name and sorted labels.
+func SeriesFingerprint(name string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[key]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// seriesIDs is a subquery of the ids of the series called name whose hosts
+// match filter.
+func seriesIDs(ctx context.Context, name string, filter models.MetricsFilter) *gorm.DB {
+	return database.DB.WithContext(ctx).
+		Model(&models.Series{}).
+		Select("id").
+		Where("name = ?", name).
+		Scopes(filterMetrics(filter))
+}
+
+// SeriesExists reports whether any series is called name.
+func SeriesExists(ctx context.Context, name string) (bool, error) {
+	var count int64
+	err := database.DB.WithContext(ctx).Model(&models.Series{}).Where("name = ?", name).Limit(1).Count(&count).Error
+	return count > 0, err
+}
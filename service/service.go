@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"os"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/config"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/database"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
@@ -13,20 +17,34 @@ import (
 	"github.com/shirou/gopsutil/mem"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// MetricsCollector collects every interval seconds until ctx is done. It
+// returns once the last collection has finished, so errChan may be closed
+// after it.
 func MetricsCollector(ctx context.Context, interval int, errChan chan error) {
 	ticker := time.NewTicker(time.Duration(interval) * time.Second)
 	defer ticker.Stop()
 
+	var collections sync.WaitGroup
 	for {
 		select {
 		case <-ticker.C:
 			logger.Log.Info("Collecting system metrics...")
-			go CollectAndSaveMetrics(errChan)
+			collections.Add(2)
+			go func() {
+				defer collections.Done()
+				CollectAndSaveMetrics(errChan)
+			}()
+			go func() {
+				defer collections.Done()
+				CollectAndSaveCounters(errChan)
+			}()
 
 		case <-ctx.Done():
 			logger.Log.Info("Stopping Metrics Collector...")
+			collections.Wait()
 			return
 
 		case err := <-errChan:
@@ -36,15 +54,39 @@ func MetricsCollector(ctx context.Context, interval int, errChan chan error) {
 	}
 }
 
+// StoreMetrics persists one collected sample. StartSinks replaces it so the
+// sample goes to the configured sinks instead.
+var StoreMetrics = writeMetrics
+
+// writeMetrics stores one collected sample in the database.
+func writeMetrics(metrics models.Metrics) error {
+	if err := database.DB.Create(&metrics).Error; err != nil {
+		return err
+	}
+	publishSamples(batchSamples(models.SampleBatch{Metrics: []models.Metrics{metrics}}))
+	return TouchHost(context.Background(), metrics.Host, nil, metrics.CreatedAt)
+}
+
+// Hostname is the host name stored with locally collected samples.
+func Hostname() string {
+	if config.Cfg.Hostname != "" {
+		return config.Cfg.Hostname
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
 // CollectAndSaveMetrics collects CPU and memory metrics and saves them to DB
 func CollectAndSaveMetrics(errChan chan error) {
 	var metrics models.Metrics
 	metrics.ID = uuid.New()
+	metrics.Host = Hostname()
+	metrics.CreatedAt = time.Now().UTC()
 
 	cpuPercent, err := cpu.Percent(0, false)
 	if err != nil {
 		logger.Log.Error("Failed to get CPU usage", zap.Error(err))
-		errChan <- err
+		collectionFailed(errChan, err)
 		return
 	}
 	metrics.CPUPercent = utils.RoundToTwoDecimal(cpuPercent[0])
@@ -54,20 +96,53 @@ func CollectAndSaveMetrics(errChan chan error) {
 	memStats, err := mem.VirtualMemory()
 	if err != nil {
 		logger.Log.Error("Failed to get Memory usage", zap.Error(err))
-		errChan <- err
+		collectionFailed(errChan, err)
 		return
 	}
 	metrics.MemPercent = utils.RoundToTwoDecimal(memStats.UsedPercent)
 
 	logger.Log.Info("Memory Percent", zap.Float64("value", memStats.UsedPercent))
 
-	if err := database.DB.Create(&metrics).Error; err != nil {
+	if err := StoreMetrics(metrics); err != nil {
 		logger.Log.Error("Failed to insert metrics into database", zap.Error(err))
-		errChan <- err
+		collectionFailed(errChan, err)
+	}
+}
+
+// filterMetrics restricts a metrics query to the rows matching filter.
+func filterMetrics(filter models.MetricsFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, cond := range filterConditions(filter) {
+			db = db.Where(cond.SQL, cond.Vars...)
+		}
+		return db
 	}
 }
 
-func GetAllMetrics(ctx context.Context, pageSize, offset int) ([]models.Metrics, int64, error) {
+// filterConditions turns filter into SQL conditions on a host column, for
+// queries that cannot use filterMetrics.
+func filterConditions(filter models.MetricsFilter) []clause.Expr {
+	var conds []clause.Expr
+	if filter.Host != "" {
+		conds = append(conds, clause.Expr{SQL: "host = ?", Vars: []interface{}{filter.Host}})
+	}
+
+	keys := make([]string, 0, len(filter.Labels))
+	for key := range filter.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		conds = append(conds, clause.Expr{
+			SQL: `host IN (SELECT h.hostname FROM hosts h JOIN host_labels l ON l.host_id = h.id
+				WHERE l.key = ? AND l.value = ?)`,
+			Vars: []interface{}{key, filter.Labels[key]},
+		})
+	}
+	return conds
+}
+
+func GetAllMetrics(ctx context.Context, pageSize, offset int, filter models.MetricsFilter) ([]models.Metrics, int64, error) {
 	var res []models.Metrics
 	var totalRecords int64
 
@@ -76,12 +151,13 @@ func GetAllMetrics(ctx context.Context, pageSize, offset int) ([]models.Metrics,
 		return nil, 0, gorm.ErrInvalidDB
 	}
 
-	if err := database.DB.Model(&models.Metrics{}).Count(&totalRecords).Error; err != nil {
+	if err := database.DB.Model(&models.Metrics{}).Scopes(filterMetrics(filter)).Count(&totalRecords).Error; err != nil {
 		logger.Log.Error("Error counting metrics:", zap.Error(err))
 		return nil, 0, err
 	}
 
 	if err := database.DB.WithContext(ctx).
+		Scopes(filterMetrics(filter)).
 		Limit(pageSize).
 		Offset(offset).
 		Order("created_at DESC").
@@ -93,12 +169,13 @@ func GetAllMetrics(ctx context.Context, pageSize, offset int) ([]models.Metrics,
 	return res, totalRecords, nil
 }
 
-func GetMetricsByTimeRange(ctx context.Context, start, end time.Time) ([]models.Metrics, error) {
+func GetMetricsByTimeRange(ctx context.Context, start, end time.Time, filter models.MetricsFilter) ([]models.Metrics, error) {
 
 	var res []models.Metrics
 
 	err := database.DB.WithContext(ctx).
 		Where("created_at BETWEEN ? AND ?", start, end).
+		Scopes(filterMetrics(filter)).
 		Find(&res).Error
 	if err != nil {
 		logger.Log.Error("Error fetching metrics by time range:", zap.Error(err))
@@ -108,7 +185,7 @@ func GetMetricsByTimeRange(ctx context.Context, start, end time.Time) ([]models.
 	return res, nil
 }
 
-func GetAverageMetrics(ctx context.Context, start, end time.Time) (models.AvgMetrics, error) {
+func GetAverageMetrics(ctx context.Context, start, end time.Time, filter models.MetricsFilter) (models.AvgMetrics, error) {
 	var res models.AvgMetrics
 
 	if database.DB == nil {
@@ -116,12 +193,15 @@ func GetAverageMetrics(ctx context.Context, start, end time.Time) (models.AvgMet
 		return models.AvgMetrics{}, gorm.ErrInvalidDB
 	}
 
+	if database.Timescale {
+		return getAverageMetricsTimescale(ctx, start, end, filter)
+	}
+
 	if err := database.DB.WithContext(ctx).
-		Raw(`SELECT 
-                AVG(cpu_percent) AS cpu_percent, 
-                AVG(mem_percent) AS mem_percent 
-             FROM metrics 
-             WHERE created_at BETWEEN ? AND ?`, start, end).
+		Model(&models.Metrics{}).
+		Select("AVG(cpu_percent) AS cpu_percent, AVG(mem_percent) AS mem_percent").
+		Where("created_at BETWEEN ? AND ?", start, end).
+		Scopes(filterMetrics(filter)).
 		Scan(&res).Error; err != nil {
 		logger.Log.Error("Error fetching average metrics:", zap.Error(err))
 		return models.AvgMetrics{}, err
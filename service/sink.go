@@ -0,0 +1,420 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/config"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"go.uber.org/zap"
+)
+
+// sinkMaxAttempts is how often a sink may fail to write a batch before the
+// batch is dropped.
+const sinkMaxAttempts = 3
+
+// sinkWriteTimeout bounds a single attempt to write a batch.
+const sinkWriteTimeout = 30 * time.Second
+
+// SinkRetryBackoff is the delay before the first retry of a failed sink
+// write; it doubles with every further attempt.
+var SinkRetryBackoff = time.Second
+
+// Sink is an output for collected samples. Each sink is fed from its own
+// queue and goroutine, so a slow or failing sink does not hold up the others.
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, batch models.SampleBatch) error
+}
+
+// sinkQueue feeds one sink. When the queue is full the oldest batch is
+// dropped. Once ctx is cancelled by StopSinks, pending writes are abandoned
+// and the remaining batches dropped.
+type sinkQueue struct {
+	sink    Sink
+	batches chan models.SampleBatch
+	done    chan struct{}
+	errChan chan error
+	ctx     context.Context
+	dropped int
+
+	mu      sync.Mutex
+	idle    *sync.Cond
+	pending int
+}
+
+// sinks is the fan-out that StoreMetrics and StoreSamples send to once
+// StartSinks has run.
+var sinks struct {
+	sync.RWMutex
+	queues  []*sinkQueue
+	stopped bool
+	cancel  context.CancelFunc
+}
+
+// StartSinks creates the sinks listed in cfg.Sinks, plus the agent, OTLP and
+// Graphite outputs when they are configured, and routes StoreMetrics and
+// StoreSamples to all of them. Batches a sink fails to write are reported on
+// errChan like failed collections.
+func StartSinks(cfg *models.Config, errChan chan error) error {
+	var outputs []Sink
+	for _, name := range cfg.Sinks {
+		switch name {
+		case config.SinkDatabase:
+			outputs = append(outputs, databaseSink{})
+		case config.SinkFile:
+			file, err := newFileSink(cfg.SinkFilePath, cfg.SinkFileMaxSize, cfg.SinkFileMaxBackups)
+			if err != nil {
+				return err
+			}
+			outputs = append(outputs, file)
+		case config.SinkStdout:
+			outputs = append(outputs, &ndjsonSink{name: "stdout", w: os.Stdout})
+		case config.SinkWebhook:
+			outputs = append(outputs, &webhookSink{url: cfg.SinkWebhookURL, headers: cfg.SinkWebhookHeaders})
+		default:
+			return fmt.Errorf("unknown sink %q", name)
+		}
+	}
+	if cfg.Mode == config.ModeAgent && cfg.AgentServerURL != "" {
+		outputs = append(outputs, newAgentSink(cfg))
+	}
+	if cfg.OTLPEndpoint != "" {
+		outputs = append(outputs, newOTLPSink(cfg))
+	}
+	if cfg.GraphiteOutputAddr != "" {
+		outputs = append(outputs, graphiteSink{})
+	}
+
+	queueSize := cfg.SinkQueueSize
+	if queueSize <= 0 {
+		queueSize = 1000
+	}
+
+	sinks.Lock()
+	defer sinks.Unlock()
+	sinks.queues = nil
+	sinks.stopped = false
+	ctx, cancel := context.WithCancel(context.Background())
+	sinks.cancel = cancel
+	for _, sink := range outputs {
+		q := &sinkQueue{sink: sink, batches: make(chan models.SampleBatch, queueSize), done: make(chan struct{}), errChan: errChan, ctx: ctx}
+		q.idle = sync.NewCond(&q.mu)
+		sinks.queues = append(sinks.queues, q)
+		go q.run()
+		logger.Log.Info("Started sink", zap.String("sink", sink.Name()))
+	}
+
+	StoreMetrics = func(metrics models.Metrics) error {
+		fanOut(models.SampleBatch{Metrics: []models.Metrics{metrics}})
+		return nil
+	}
+	StoreSamples = func(_ context.Context, samples []models.SeriesSample) error {
+		fanOut(models.SampleBatch{Samples: samples})
+		return nil
+	}
+	return nil
+}
+
+// DrainSinks waits until every batch queued so far has been written or
+// dropped.
+func DrainSinks() {
+	sinks.RLock()
+	queues := sinks.queues
+	sinks.RUnlock()
+
+	for _, q := range queues {
+		q.mu.Lock()
+		for q.pending > 0 {
+			q.idle.Wait()
+		}
+		q.mu.Unlock()
+	}
+}
+
+// StopSinks stops accepting batches and waits for the queued ones to be
+// written until ctx is done; the batches still queued then are dropped.
+// Batches arriving afterwards are discarded.
+func StopSinks(ctx context.Context) {
+	sinks.Lock()
+	queues, cancel := sinks.queues, sinks.cancel
+	if !sinks.stopped {
+		sinks.stopped = true
+		for _, q := range queues {
+			close(q.batches)
+		}
+	}
+	sinks.Unlock()
+	if cancel == nil {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for _, q := range queues {
+			<-q.done
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Log.Warn("Sinks did not finish in time, dropping queued batches", zap.Error(ctx.Err()))
+		cancel()
+		<-done
+	}
+	cancel()
+}
+
+func fanOut(batch models.SampleBatch) {
+	sinks.RLock()
+	defer sinks.RUnlock()
+	if sinks.stopped {
+		return
+	}
+	for _, q := range sinks.queues {
+		q.enqueue(batch)
+	}
+}
+
+func (q *sinkQueue) enqueue(batch models.SampleBatch) {
+	q.mu.Lock()
+	q.pending++
+	q.mu.Unlock()
+	for {
+		select {
+		case q.batches <- batch:
+			return
+		default:
+		}
+		select {
+		case <-q.batches:
+			q.finish()
+			logger.Log.Warn("Sink queue full, dropping oldest batch", zap.String("sink", q.sink.Name()))
+		default:
+		}
+	}
+}
+
+func (q *sinkQueue) run() {
+	defer close(q.done)
+	for batch := range q.batches {
+		if q.ctx.Err() != nil {
+			q.dropped++
+		} else {
+			q.write(batch)
+		}
+		q.finish()
+	}
+	if q.dropped > 0 {
+		logger.Log.Warn("Sink stopped, dropped queued batches", zap.String("sink", q.sink.Name()), zap.Int("batches", q.dropped))
+	}
+	if closer, ok := q.sink.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Log.Error("Failed to close sink", zap.String("sink", q.sink.Name()), zap.Error(err))
+		}
+	}
+}
+
+// finish marks one queued batch as written or dropped.
+func (q *sinkQueue) finish() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending--; q.pending == 0 {
+		q.idle.Broadcast()
+	}
+}
+
+// write retries a failed batch with exponential backoff and drops it after
+// sinkMaxAttempts failures, reporting the failure on errChan. A batch being
+// written when StopSinks gives up is dropped without a report.
+func (q *sinkQueue) write(batch models.SampleBatch) {
+	backoff := SinkRetryBackoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(q.ctx, sinkWriteTimeout)
+		err := q.sink.Write(ctx, batch)
+		cancel()
+		if err == nil {
+			return
+		}
+		if q.ctx.Err() != nil {
+			q.dropped++
+			return
+		}
+		if attempt == sinkMaxAttempts {
+			logger.Log.Error("Sink write failed, dropping batch", zap.String("sink", q.sink.Name()), zap.Int("attempts", attempt), zap.Error(err))
+			collectionFailed(q.errChan, fmt.Errorf("sink %s: %w", q.sink.Name(), err))
+			return
+		}
+		logger.Log.Warn("Sink write failed, retrying", zap.String("sink", q.sink.Name()), zap.Duration("backoff", backoff), zap.Error(err))
+		select {
+		case <-time.After(backoff):
+		case <-q.ctx.Done():
+			q.dropped++
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// databaseSink stores samples in the local database.
+type databaseSink struct{}
+
+func (databaseSink) Name() string { return config.SinkDatabase }
+
+func (databaseSink) Write(ctx context.Context, batch models.SampleBatch) error {
+	for _, metrics := range batch.Metrics {
+		if err := writeMetrics(metrics); err != nil {
+			return err
+		}
+	}
+	if len(batch.Samples) == 0 {
+		return nil
+	}
+	return WriteSamples(ctx, batch.Samples)
+}
+
+// ndjsonSink writes one JSON series sample per line.
+type ndjsonSink struct {
+	name string
+	w    io.Writer
+}
+
+func (s *ndjsonSink) Name() string { return s.name }
+
+// fileSink is an ndjsonSink writing to a rotating file.
+type fileSink struct {
+	ndjsonSink
+	file *rotatingFile
+}
+
+func (s *fileSink) Close() error { return s.file.file.Close() }
+
+func (s *ndjsonSink) Write(_ context.Context, batch models.SampleBatch) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, sample := range batchSamples(batch) {
+		if err := enc.Encode(sample); err != nil {
+			return err
+		}
+	}
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+// rotatingFile appends to path and renames it to path.1, path.2, ... once it
+// would grow beyond maxSize, keeping at most maxBackups old files.
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newFileSink(path string, maxSize int64, maxBackups int) (*fileSink, error) {
+	f := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return &fileSink{ndjsonSink: ndjsonSink{name: config.SinkFile, w: f}, file: f}, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// Write rotates before a write that would exceed maxSize, so every write,
+// which the ndjson sink makes whole lines, stays in one file.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	if f.maxBackups == 0 {
+		if err := os.Remove(f.path); err != nil {
+			return err
+		}
+		return f.open()
+	}
+	os.Remove(f.backup(f.maxBackups))
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(f.backup(i), f.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(f.path, f.backup(1)); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+func (f *rotatingFile) backup(i int) string {
+	return f.path + "." + strconv.Itoa(i)
+}
+
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// webhookSink POSTs every batch as JSON series samples.
+type webhookSink struct {
+	url     string
+	headers map[string]string
+}
+
+func (s *webhookSink) Name() string { return config.SinkWebhook }
+
+func (s *webhookSink) Write(ctx context.Context, batch models.SampleBatch) error {
+	body, err := json.Marshal(models.SampleBatch{Samples: batchSamples(batch)})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook %s failed: %s", s.url, resp.Status)
+	}
+	return nil
+}
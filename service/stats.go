@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/database"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var statsPercentiles = []struct {
+	name     string
+	fraction float64
+}{{"p50", 0.5}, {"p90", 0.9}, {"p95", 0.95}, {"p99", 0.99}}
+
+// GetMetricsStats summarises CPU and memory usage between start and end.
+// Postgres computes everything in one pass with percentile_cont; other
+// databases, i.e. SQLite in tests, have neither stddev nor percentiles so
+// the samples are summarised in Go.
+func GetMetricsStats(ctx context.Context, start, end time.Time, filter models.MetricsFilter) (models.MetricsStats, error) {
+	if database.DB == nil {
+		logger.Log.Error("Database is not initialized")
+		return models.MetricsStats{}, gorm.ErrInvalidDB
+	}
+
+	if database.DB.Dialector.Name() == "postgres" {
+		return getMetricsStatsPostgres(ctx, start, end, filter)
+	}
+
+	var samples []models.Metrics
+	if err := database.DB.WithContext(ctx).
+		Select("cpu_percent, mem_percent, created_at").
+		Where("created_at BETWEEN ? AND ?", start, end).
+		Scopes(filterMetrics(filter)).
+		Order("created_at ASC").
+		Find(&samples).Error; err != nil {
+		logger.Log.Error("Error fetching metrics statistics:", zap.Error(err))
+		return models.MetricsStats{}, err
+	}
+
+	res := models.MetricsStats{Count: int64(len(samples))}
+	if len(samples) == 0 {
+		return res, nil
+	}
+	first, last := samples[0].CreatedAt.UTC(), samples[len(samples)-1].CreatedAt.UTC()
+	res.FirstAt, res.LastAt = &first, &last
+
+	cpu := make([]float64, len(samples))
+	memory := make([]float64, len(samples))
+	for i, s := range samples {
+		cpu[i], memory[i] = s.CPUPercent, s.MemPercent
+	}
+	res.CPU = summarize(cpu)
+	res.Mem = summarize(memory)
+	return res, nil
+}
+
+func getMetricsStatsPostgres(ctx context.Context, start, end time.Time, filter models.MetricsFilter) (models.MetricsStats, error) {
+	columns := []string{
+		"COUNT(*) AS count",
+		"MIN(created_at) AS first_at",
+		"MAX(created_at) AS last_at",
+	}
+	for _, prefix := range []string{"cpu", "mem"} {
+		column := prefix + "_percent"
+		columns = append(columns,
+			fmt.Sprintf("COALESCE(MIN(%s), 0) AS %s_min", column, prefix),
+			fmt.Sprintf("COALESCE(MAX(%s), 0) AS %s_max", column, prefix),
+			fmt.Sprintf("COALESCE(AVG(%s), 0) AS %s_mean", column, prefix),
+			fmt.Sprintf("COALESCE(STDDEV_SAMP(%s), 0) AS %s_stddev", column, prefix))
+		for _, p := range statsPercentiles {
+			columns = append(columns, fmt.Sprintf("COALESCE(percentile_cont(%g) WITHIN GROUP (ORDER BY %s), 0) AS %s_%s",
+				p.fraction, column, prefix, p.name))
+		}
+	}
+
+	var row struct {
+		Count     int64
+		FirstAt   *time.Time
+		LastAt    *time.Time
+		CPUMin    float64 `gorm:"column:cpu_min"`
+		CPUMax    float64 `gorm:"column:cpu_max"`
+		CPUMean   float64 `gorm:"column:cpu_mean"`
+		CPUStdDev float64 `gorm:"column:cpu_stddev"`
+		CPUP50    float64 `gorm:"column:cpu_p50"`
+		CPUP90    float64 `gorm:"column:cpu_p90"`
+		CPUP95    float64 `gorm:"column:cpu_p95"`
+		CPUP99    float64 `gorm:"column:cpu_p99"`
+		MemMin    float64 `gorm:"column:mem_min"`
+		MemMax    float64 `gorm:"column:mem_max"`
+		MemMean   float64 `gorm:"column:mem_mean"`
+		MemStdDev float64 `gorm:"column:mem_stddev"`
+		MemP50    float64 `gorm:"column:mem_p50"`
+		MemP90    float64 `gorm:"column:mem_p90"`
+		MemP95    float64 `gorm:"column:mem_p95"`
+		MemP99    float64 `gorm:"column:mem_p99"`
+	}
+	if err := database.DB.WithContext(ctx).
+		Model(&models.Metrics{}).
+		Select(strings.Join(columns, ", ")).
+		Where("created_at BETWEEN ? AND ?", start, end).
+		Scopes(filterMetrics(filter)).
+		Scan(&row).Error; err != nil {
+		logger.Log.Error("Error fetching metrics statistics:", zap.Error(err))
+		return models.MetricsStats{}, err
+	}
+
+	res := models.MetricsStats{
+		Count:   row.Count,
+		FirstAt: row.FirstAt,
+		LastAt:  row.LastAt,
+		CPU:     models.SeriesStats{Min: row.CPUMin, Max: row.CPUMax, Mean: row.CPUMean, StdDev: row.CPUStdDev, P50: row.CPUP50, P90: row.CPUP90, P95: row.CPUP95, P99: row.CPUP99},
+		Mem:     models.SeriesStats{Min: row.MemMin, Max: row.MemMax, Mean: row.MemMean, StdDev: row.MemStdDev, P50: row.MemP50, P90: row.MemP90, P95: row.MemP95, P99: row.MemP99},
+	}
+	res.CPU = roundStats(res.CPU)
+	res.Mem = roundStats(res.Mem)
+	return res, nil
+}
+
+// summarize computes the statistics of values the way Postgres does:
+// sample standard deviation and continuous percentiles.
+func summarize(values []float64) models.SeriesStats {
+	if len(values) == 0 {
+		return models.SeriesStats{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(len(sorted))
+
+	var stddev float64
+	if len(sorted) > 1 {
+		var squares float64
+		for _, v := range sorted {
+			squares += (v - mean) * (v - mean)
+		}
+		stddev = math.Sqrt(squares / float64(len(sorted)-1))
+	}
+
+	return roundStats(models.SeriesStats{
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		Mean:   mean,
+		StdDev: stddev,
+		P50:    percentileCont(sorted, statsPercentiles[0].fraction),
+		P90:    percentileCont(sorted, statsPercentiles[1].fraction),
+		P95:    percentileCont(sorted, statsPercentiles[2].fraction),
+		P99:    percentileCont(sorted, statsPercentiles[3].fraction),
+	})
+}
+
+// percentileCont interpolates the p-th percentile of sorted values.
+func percentileCont(sorted []float64, p float64) float64 {
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+func roundStats(s models.SeriesStats) models.SeriesStats {
+	for _, v := range []*float64{&s.Min, &s.Max, &s.Mean, &s.StdDev, &s.P50, &s.P90, &s.P95, &s.P99} {
+		*v = utils.RoundToTwoDecimal(*v)
+	}
+	return s
+}
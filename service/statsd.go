@@ -0,0 +1,279 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/statsd"
+	"go.uber.org/zap"
+)
+
+// StatsDIdleFlushes is how many flushes a counter or gauge is still stored
+// after its last update. It is forgotten afterwards, so senders with ever
+// changing tags do not grow the state and the store without bound.
+const StatsDIdleFlushes = 10
+
+// statsdSeries identifies an aggregated StatsD metric.
+type statsdSeries struct {
+	name   string
+	labels map[string]string
+	// idle counts the flushes since the last update.
+	idle int
+}
+
+// statsdState aggregates StatsD metrics in memory between flushes. Counters
+// and gauges keep their value across flushes until they expire; timers and
+// sets are reset.
+var statsdState = struct {
+	sync.Mutex
+	series   map[string]statsdSeries
+	counters map[string]float64
+	gauges   map[string]float64
+	timers   map[string]*statsdTimer
+	sets     map[string]map[string]struct{}
+}{
+	series:   make(map[string]statsdSeries),
+	counters: make(map[string]float64),
+	gauges:   make(map[string]float64),
+	timers:   make(map[string]*statsdTimer),
+	sets:     make(map[string]map[string]struct{}),
+}
+
+type statsdTimer struct {
+	values []float64
+	count  float64
+}
+
+// StartStatsD starts the StatsD listeners on cfg.StatsDUDPAddr and
+// cfg.StatsDTCPAddr, whichever are set, until ctx is cancelled and returns
+// the addresses they listen on.
+func StartStatsD(ctx context.Context, cfg *models.Config) (udpAddr, tcpAddr net.Addr, err error) {
+	if cfg.StatsDUDPAddr != "" {
+		conn, err := net.ListenPacket("udp", cfg.StatsDUDPAddr)
+		if err != nil {
+			return nil, nil, err
+		}
+		context.AfterFunc(ctx, func() { conn.Close() })
+		go serveStatsDUDP(conn)
+		udpAddr = conn.LocalAddr()
+	}
+	if cfg.StatsDTCPAddr != "" {
+		ln, err := net.Listen("tcp", cfg.StatsDTCPAddr)
+		if err != nil {
+			return nil, nil, err
+		}
+		context.AfterFunc(ctx, func() { ln.Close() })
+		go serveStatsDTCP(ctx, ln)
+		tcpAddr = ln.Addr()
+	}
+	logger.Log.Info("StatsD listening", zap.Any("udp", udpAddr), zap.Any("tcp", tcpAddr))
+	return udpAddr, tcpAddr, nil
+}
+
+func serveStatsDUDP(conn net.PacketConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Log.Error("StatsD UDP listener failed", zap.Error(err))
+			}
+			return
+		}
+		HandleStatsDPacket(string(buf[:n]))
+	}
+}
+
+func serveStatsDTCP(ctx context.Context, ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Log.Error("StatsD TCP listener failed", zap.Error(err))
+			}
+			return
+		}
+		go func() {
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+			defer conn.Close()
+
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				HandleStatsDPacket(scanner.Text())
+			}
+		}()
+	}
+}
+
+// HandleStatsDPacket aggregates the metrics of one StatsD packet or line.
+func HandleStatsDPacket(packet string) {
+	metrics, errs := statsd.ParsePacket(packet)
+	for _, err := range errs {
+		logger.Log.Debug("Invalid StatsD line", zap.Error(err))
+	}
+
+	statsdState.Lock()
+	defer statsdState.Unlock()
+
+	for _, m := range metrics {
+		labels := make(map[string]string, len(m.Tags))
+		for k, v := range m.Tags {
+			if key := sanitizeLabelName(k); key != "" && v != "" {
+				labels[key] = v
+			}
+		}
+		name := sanitizeMetricName(m.Name)
+		key := string(m.Type) + "|" + SeriesFingerprint(name, labels)
+		statsdState.series[key] = statsdSeries{name: name, labels: labels}
+
+		switch m.Type {
+		case statsd.Counter:
+			statsdState.counters[key] += m.Value / m.SampleRate
+		case statsd.Gauge:
+			if m.Delta {
+				statsdState.gauges[key] += m.Value
+			} else {
+				statsdState.gauges[key] = m.Value
+			}
+		case statsd.Timer, statsd.Histogram, statsd.Distribution:
+			t := statsdState.timers[key]
+			if t == nil {
+				t = &statsdTimer{}
+				statsdState.timers[key] = t
+			}
+			t.values = append(t.values, m.Value)
+			t.count += 1 / m.SampleRate
+		case statsd.Set:
+			set := statsdState.sets[key]
+			if set == nil {
+				set = make(map[string]struct{})
+				statsdState.sets[key] = set
+			}
+			set[m.SetValue] = struct{}{}
+		}
+	}
+}
+
+// StatsDFlusher flushes the aggregated StatsD metrics every collector
+// interval and once more when ctx is cancelled.
+func StatsDFlusher(ctx context.Context, cfg *models.Config, errChan chan error) {
+	ticker := time.NewTicker(time.Duration(cfg.MetricsInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := FlushStatsD(ctx); err != nil {
+				errChan <- err
+			}
+		case <-ctx.Done():
+			logger.Log.Info("Stopping StatsD Flusher...")
+			if err := FlushStatsD(context.Background()); err != nil {
+				logger.Log.Error("Final StatsD flush failed", zap.Error(err))
+			}
+			return
+		}
+	}
+}
+
+// FlushStatsD stores the aggregated StatsD metrics through StoreSamples:
+//
+//   - counters as the running total <name>_total, so rate and increase apply;
+//   - gauges as <name>;
+//   - timers, histograms and distributions as <name>_count, _sum, _min, _max
+//     and <name>{quantile="0.5|0.9|0.95|0.99"} of the interval;
+//   - sets as <name>, the number of unique values seen in the interval.
+//
+// Counters and gauges are stored on every flush until they go
+// StatsDIdleFlushes flushes without an update.
+func FlushStatsD(ctx context.Context) error {
+	now := time.Now().UTC()
+	var samples []models.SeriesSample
+	add := func(name string, labels map[string]string, value float64) {
+		samples = append(samples, models.SeriesSample{Name: name, Labels: labels, Timestamp: now, Value: value})
+	}
+
+	statsdState.Lock()
+	// expired reports whether the counter or gauge key has gone
+	// StatsDIdleFlushes flushes without an update, and forgets it if so.
+	expired := func(key string) bool {
+		s := statsdState.series[key]
+		if s.idle >= StatsDIdleFlushes {
+			delete(statsdState.series, key)
+			delete(statsdState.counters, key)
+			delete(statsdState.gauges, key)
+			return true
+		}
+		s.idle++
+		statsdState.series[key] = s
+		return false
+	}
+	for key, value := range statsdState.counters {
+		if expired(key) {
+			continue
+		}
+		s := statsdState.series[key]
+		name := s.name
+		if !strings.HasSuffix(name, "_total") {
+			name += "_total"
+		}
+		add(name, s.labels, value)
+	}
+	for key, value := range statsdState.gauges {
+		if expired(key) {
+			continue
+		}
+		s := statsdState.series[key]
+		add(s.name, s.labels, value)
+	}
+	for key, t := range statsdState.timers {
+		s := statsdState.series[key]
+		sort.Float64s(t.values)
+		sum := 0.0
+		for _, v := range t.values {
+			sum += v
+		}
+		add(s.name+"_count", s.labels, t.count)
+		add(s.name+"_sum", s.labels, sum)
+		add(s.name+"_min", s.labels, t.values[0])
+		add(s.name+"_max", s.labels, t.values[len(t.values)-1])
+		for _, p := range statsPercentiles {
+			labels := make(map[string]string, len(s.labels)+1)
+			for k, v := range s.labels {
+				labels[k] = v
+			}
+			labels["quantile"] = strconv.FormatFloat(p.fraction, 'g', -1, 64)
+			add(s.name, labels, percentileCont(t.values, p.fraction))
+		}
+		delete(statsdState.timers, key)
+		delete(statsdState.series, key)
+	}
+	for key, set := range statsdState.sets {
+		s := statsdState.series[key]
+		add(s.name, s.labels, float64(len(set)))
+		delete(statsdState.sets, key)
+		delete(statsdState.series, key)
+	}
+	statsdState.Unlock()
+
+	if len(samples) == 0 {
+		return nil
+	}
+	if err := StoreSamples(ctx, samples); err != nil {
+		logger.Log.Error("Failed to store StatsD metrics", zap.Error(err))
+		return err
+	}
+	ingestedSamples.Add(int64(len(samples)))
+	logger.Log.Debug("Flushed StatsD metrics", zap.Int("samples", len(samples)))
+	return nil
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/promql"
+)
+
+// streamQueueSize is the number of samples queued per subscriber. A
+// subscriber that falls further behind misses samples instead of holding up
+// the collector.
+const streamQueueSize = 1024
+
+// StreamFilter selects the samples a subscriber receives. Empty fields match
+// everything.
+type StreamFilter struct {
+	Names    []string
+	Hosts    []string
+	Matchers []*promql.Matcher
+}
+
+func (f StreamFilter) matches(sample models.SeriesSample) bool {
+	if len(f.Names) > 0 && !slices.Contains(f.Names, sample.Name) {
+		return false
+	}
+	if len(f.Hosts) > 0 && !slices.Contains(f.Hosts, sample.Labels[HostLabel]) {
+		return false
+	}
+	for _, m := range f.Matchers {
+		value := sample.Labels[m.Name]
+		if m.Name == promql.MetricNameLabel {
+			value = sample.Name
+		}
+		if !m.Matches(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Subscription receives new samples on C until it is closed.
+type Subscription struct {
+	C <-chan models.SeriesSample
+
+	ch      chan models.SeriesSample
+	filter  StreamFilter
+	dropped int64
+}
+
+var streams struct {
+	sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+// Subscribe returns a subscription to every sample stored from now on that
+// matches filter.
+func Subscribe(filter StreamFilter) *Subscription {
+	ch := make(chan models.SeriesSample, streamQueueSize)
+	sub := &Subscription{C: ch, ch: ch, filter: filter}
+
+	streams.Lock()
+	defer streams.Unlock()
+	if streams.subs == nil {
+		streams.subs = make(map[*Subscription]struct{})
+	}
+	streams.subs[sub] = struct{}{}
+	return sub
+}
+
+// Close unsubscribes and returns the number of samples the subscriber missed
+// because it fell behind.
+func (s *Subscription) Close() int64 {
+	streams.Lock()
+	defer streams.Unlock()
+	delete(streams.subs, s)
+	return s.dropped
+}
+
+// publishSamples hands samples to every matching subscriber without waiting
+// for any of them. The write paths call it once samples are stored, so every
+// stored sample is streamed whichever protocol brought it in.
+func publishSamples(samples []models.SeriesSample) {
+	streams.Lock()
+	defer streams.Unlock()
+	for sub := range streams.subs {
+		for _, sample := range samples {
+			if !sub.filter.matches(sample) {
+				continue
+			}
+			select {
+			case sub.ch <- sample:
+			default:
+				sub.dropped++
+			}
+		}
+	}
+}
+
+const (
+	// maxBackfillPoints bounds the points per series of a subscription backfill.
+	maxBackfillPoints = 1000
+	// maxBackfillWindow bounds how far back a backfill reads, since every raw
+	// sample of the window is loaded before it is averaged.
+	maxBackfillWindow = 24 * time.Hour
+)
+
+var (
+	ErrInvalidSelector = errors.New("invalid series selector")
+	ErrInvalidBackfill = errors.New("invalid backfill")
+)
+
+// ParseSelector parses a PromQL series selector such as
+// cpu_percent{host=~"web-.*"} into its matchers.
+func ParseSelector(selector string) ([]*promql.Matcher, error) {
+	expr, err := promql.ParseExpr(selector)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSelector, err)
+	}
+	vs, ok := expr.(*promql.VectorSelector)
+	if !ok {
+		return nil, fmt.Errorf("%w: expected a selector such as cpu_percent{host=\"web-01\"}", ErrInvalidSelector)
+	}
+	return vs.Matchers, nil
+}
+
+// StreamBackfill returns the samples of the series matching matchers over the
+// last window, averaged into step wide points. Points are stamped with the
+// start of their step.
+func StreamBackfill(ctx context.Context, matchers []*promql.Matcher, window, step time.Duration) ([]models.StreamSeries, error) {
+	if window <= 0 || step <= 0 {
+		return nil, fmt.Errorf("%w: backfill and step must be positive", ErrInvalidBackfill)
+	}
+	if window > maxBackfillWindow {
+		return nil, fmt.Errorf("%w: backfill may reach back at most %s", ErrInvalidBackfill, maxBackfillWindow)
+	}
+	if window/step > maxBackfillPoints {
+		return nil, fmt.Errorf("%w: more than %d points per series, use a larger step", ErrInvalidBackfill, maxBackfillPoints)
+	}
+
+	end := time.Now().UTC()
+	start := end.Add(-window).Truncate(step)
+	series, err := SeriesQuerier{}.Select(ctx, matchers, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]models.StreamSeries, 0, len(series))
+	for _, s := range series {
+		out := models.StreamSeries{Name: s.Labels[promql.MetricNameLabel], Labels: map[string]string{}}
+		for key, value := range s.Labels {
+			if key != promql.MetricNameLabel {
+				out.Labels[key] = value
+			}
+		}
+		var sum float64
+		var count int
+		bucket := int64(-1)
+		for _, p := range s.Points {
+			b := time.UnixMilli(p.T).Sub(start) / step
+			if int64(b) != bucket && count > 0 {
+				out.Points = append(out.Points, models.Point{Time: start.Add(time.Duration(bucket) * step), Value: sum / float64(count)})
+				sum, count = 0, 0
+			}
+			bucket = int64(b)
+			sum += p.V
+			count++
+		}
+		if count > 0 {
+			out.Points = append(out.Points, models.Point{Time: start.Add(time.Duration(bucket) * step), Value: sum / float64(count)})
+		}
+		res = append(res, out)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Name != res[j].Name {
+			return res[i].Name < res[j].Name
+		}
+		return promql.Labels(res[i].Labels).Fingerprint() < promql.Labels(res[j].Labels).Fingerprint()
+	})
+	return res, nil
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/database"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
+	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/models"
+	"go.uber.org/zap"
+)
+
+// getAverageMetricsTimescale answers the whole hours of the range from the
+// continuous aggregate and only scans raw rows for the partial hours at
+// either end.
+func getAverageMetricsTimescale(ctx context.Context, start, end time.Time, filter models.MetricsFilter) (models.AvgMetrics, error) {
+	firstHour := start.Truncate(time.Hour)
+	if firstHour.Before(start) {
+		firstHour = firstHour.Add(time.Hour)
+	}
+	lastHour := end.Truncate(time.Hour)
+	if !firstHour.Before(lastHour) {
+		firstHour, lastHour = end, end
+	}
+
+	where, filterArgs := "", []interface{}{}
+	for _, cond := range filterConditions(filter) {
+		where += " AND " + cond.SQL
+		filterArgs = append(filterArgs, cond.Vars...)
+	}
+	args := append([]interface{}{firstHour, lastHour}, filterArgs...)
+	args = append(args, start, firstHour, lastHour, end)
+	args = append(args, filterArgs...)
+
+	var sums struct {
+		CPUSum      float64
+		MemSum      float64
+		SampleCount int64
+	}
+	if err := database.DB.WithContext(ctx).
+		Raw(fmt.Sprintf(`SELECT
+                COALESCE(SUM(cpu_sum), 0) AS cpu_sum,
+                COALESCE(SUM(mem_sum), 0) AS mem_sum,
+                COALESCE(SUM(sample_count), 0) AS sample_count
+             FROM (
+                SELECT cpu_sum, mem_sum, sample_count FROM %[1]s
+                WHERE bucket >= ? AND bucket < ?%[2]s
+                UNION ALL
+                SELECT cpu_percent, mem_percent, 1 FROM metrics
+                WHERE ((created_at >= ? AND created_at < ?) OR (created_at >= ? AND created_at <= ?))%[2]s
+             ) AS parts`, database.TimescaleCaggHourly, where),
+			args...).
+		Scan(&sums).Error; err != nil {
+		logger.Log.Error("Error fetching average metrics from continuous aggregate:", zap.Error(err))
+		return models.AvgMetrics{}, err
+	}
+
+	if sums.SampleCount == 0 {
+		return models.AvgMetrics{}, nil
+	}
+	return models.AvgMetrics{
+		CPUPercent: sums.CPUSum / float64(sums.SampleCount),
+		MemPercent: sums.MemSum / float64(sums.SampleCount),
+	}, nil
+}
@@ -0,0 +1,105 @@
+// Package statsd parses StatsD metric lines with DogStatsD extensions:
+//
+//	name:value|type[|@sample_rate][|#tag:value,tag2:value2]
+package statsd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type MetricType string
+
+const (
+	Counter      MetricType = "c"
+	Gauge        MetricType = "g"
+	Timer        MetricType = "ms"
+	Histogram    MetricType = "h"
+	Distribution MetricType = "d"
+	Set          MetricType = "s"
+)
+
+type Metric struct {
+	Name string
+	Type MetricType
+	// Value is the numeric value; sets use SetValue instead.
+	Value    float64
+	SetValue string
+	// Delta is set for gauges sent as "+n" or "-n", which adjust the
+	// current value instead of replacing it.
+	Delta      bool
+	SampleRate float64
+	Tags       map[string]string
+}
+
+// ParsePacket parses the newline separated lines of a packet. DogStatsD
+// events and service checks are skipped. Lines that fail to parse are
+// reported in errs and do not stop the others from being parsed.
+func ParsePacket(packet string) (metrics []Metric, errs []error) {
+	for _, line := range strings.Split(packet, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "_e{") || strings.HasPrefix(line, "_sc|") {
+			continue
+		}
+		m, err := ParseLine(line)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, errs
+}
+
+// ParseLine parses a single metric line.
+func ParseLine(line string) (Metric, error) {
+	m := Metric{SampleRate: 1}
+
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return m, fmt.Errorf("invalid line %q: missing name", line)
+	}
+	m.Name = name
+
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 {
+		return m, fmt.Errorf("invalid line %q: missing type", line)
+	}
+	m.Type = MetricType(parts[1])
+
+	value := parts[0]
+	switch m.Type {
+	case Set:
+		m.SetValue = value
+	case Counter, Gauge, Timer, Histogram, Distribution:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return m, fmt.Errorf("invalid line %q: invalid value %q", line, value)
+		}
+		m.Value = v
+		m.Delta = m.Type == Gauge && (value[0] == '+' || value[0] == '-')
+	default:
+		return m, fmt.Errorf("invalid line %q: unknown type %q", line, parts[1])
+	}
+
+	for _, part := range parts[2:] {
+		switch {
+		case strings.HasPrefix(part, "@"):
+			rate, err := strconv.ParseFloat(part[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return m, fmt.Errorf("invalid line %q: invalid sample rate %q", line, part)
+			}
+			m.SampleRate = rate
+		case strings.HasPrefix(part, "#"):
+			m.Tags = make(map[string]string)
+			for _, tag := range strings.Split(part[1:], ",") {
+				k, v, _ := strings.Cut(tag, ":")
+				if k != "" {
+					m.Tags[k] = v
+				}
+			}
+		}
+	}
+	return m, nil
+}
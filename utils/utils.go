@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"fmt"
 	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ROHITHSAKTHIVEL/Metrics-Monitor/logger"
@@ -25,3 +28,62 @@ func ParseTime(req string) (time.Time, error) {
 func RoundToTwoDecimal(num float64) float64 {
 	return math.Round(num*100) / 100
 }
+
+// ParseDuration extends time.ParseDuration with day (d) and week (w) units,
+// e.g. "30d" or "2w", which are the natural units for retention settings.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, found := strings.CutSuffix(s, suffix); found {
+			v, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(v * float64(unit)), nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// ParseDurationMap parses comma separated "name=duration" pairs.
+func ParseDurationMap(s string) (map[string]time.Duration, error) {
+	res := make(map[string]time.Duration)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid entry %q, expected name=duration", pair)
+		}
+		d, err := ParseDuration(value)
+		if err != nil {
+			return nil, err
+		}
+		res[strings.TrimSpace(name)] = d
+	}
+	return res, nil
+}
+
+// ParseLabels parses comma separated "key=value" pairs such as
+// "env=prod,team=payments".
+func ParseLabels(s string) (map[string]string, error) {
+	res := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", pair)
+		}
+		res[key] = strings.TrimSpace(value)
+	}
+	return res, nil
+}